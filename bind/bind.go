@@ -9,26 +9,51 @@
 // (http://godoc.org/golang.org/x/mobile/cmd/gobind)
 package bind // import "golang.org/x/mobile/bind"
 
-// TODO(crawshaw): slice support
-// TODO(crawshaw): channel support
-
 import (
 	"bytes"
 	"go/format"
 	"go/token"
 	"io"
+	"sort"
 
 	"golang.org/x/tools/go/types"
 )
 
 // GenJava generates a Java API from a Go package.
-func GenJava(w io.Writer, fset *token.FileSet, pkg *types.Package) error {
+//
+// The bindings of pkg may refer to the types of the packages allPkg,
+// bound together with pkg. The list may include pkg itself.
+//
+// The class of each bound package p is in the Java package
+// javaPkg.p, such as com.example.p. If javaPkg is empty, it is go.
+func GenJava(w io.Writer, fset *token.FileSet, pkg *types.Package, allPkg []*types.Package, javaPkg string) error {
 	buf := new(bytes.Buffer)
 	g := &javaGen{
 		printer: &printer{buf: buf, indentEach: []byte("    ")},
 		fset:    fset,
 		pkg:     pkg,
+		allPkg:  allPkg,
+		javaPkg: javaPkg,
+	}
+	if err := g.gen(); err != nil {
+		return err
 	}
+	_, err := io.Copy(w, buf)
+	return err
+}
+
+// GenKotlin generates Kotlin declarations wrapping the Java API
+// generated by GenJava, for use from Kotlin.
+// See GenJava for allPkg and javaPkg.
+func GenKotlin(w io.Writer, fset *token.FileSet, pkg *types.Package, allPkg []*types.Package, javaPkg string) error {
+	buf := new(bytes.Buffer)
+	g := &kotlinGen{javaGen: &javaGen{
+		printer: &printer{buf: buf, indentEach: []byte("    ")},
+		fset:    fset,
+		pkg:     pkg,
+		allPkg:  allPkg,
+		javaPkg: javaPkg,
+	}}
 	if err := g.gen(); err != nil {
 		return err
 	}
@@ -37,12 +62,14 @@ func GenJava(w io.Writer, fset *token.FileSet, pkg *types.Package) error {
 }
 
 // GenGo generates a Go stub to support foreign language APIs.
-func GenGo(w io.Writer, fset *token.FileSet, pkg *types.Package) error {
+// See GenJava for allPkg.
+func GenGo(w io.Writer, fset *token.FileSet, pkg *types.Package, allPkg []*types.Package) error {
 	buf := new(bytes.Buffer)
 	g := &goGen{
 		printer: &printer{buf: buf, indentEach: []byte("\t")},
 		fset:    fset,
 		pkg:     pkg,
+		allPkg:  allPkg,
 	}
 	if err := g.gen(); err != nil {
 		return err
@@ -58,12 +85,19 @@ func GenGo(w io.Writer, fset *token.FileSet, pkg *types.Package) error {
 }
 
 // GenObjc generates the Objective-C API from a Go package.
-func GenObjc(w io.Writer, fset *token.FileSet, pkg *types.Package, isHeader bool) error {
+// See GenJava for allPkg.
+//
+// The names of the declarations of each bound package p start with
+// prefix and the capitalized name of p, such as EXSP. If prefix is
+// empty, it is Go.
+func GenObjc(w io.Writer, fset *token.FileSet, pkg *types.Package, allPkg []*types.Package, prefix string, isHeader bool) error {
 	buf := new(bytes.Buffer)
 	g := &objcGen{
 		printer: &printer{buf: buf, indentEach: []byte("\t")},
 		fset:    fset,
 		pkg:     pkg,
+		allPkg:  allPkg,
+		prefix:  prefix,
 	}
 	var err error
 	if isHeader {
@@ -77,3 +111,71 @@ func GenObjc(w io.Writer, fset *token.FileSet, pkg *types.Package, isHeader bool
 	_, err = io.Copy(w, buf)
 	return err
 }
+
+// GenSwift generates a Swift API wrapping the Objective-C API
+// generated by GenObjc.
+// See GenJava for allPkg and GenObjc for prefix.
+func GenSwift(w io.Writer, fset *token.FileSet, pkg *types.Package, allPkg []*types.Package, prefix string) error {
+	buf := new(bytes.Buffer)
+	g := &swiftGen{&objcGen{
+		printer: &printer{buf: buf, indentEach: []byte("    ")},
+		fset:    fset,
+		pkg:     pkg,
+		allPkg:  allPkg,
+		prefix:  prefix,
+	}}
+	if err := g.gen(); err != nil {
+		return err
+	}
+	_, err := io.Copy(w, buf)
+	return err
+}
+
+// GenAPI writes a description of the API of pkg as bound by gobind,
+// one line per declaration. Descriptions of successive versions of
+// the API are compared by DiffAPI.
+func GenAPI(w io.Writer, pkg *types.Package) error {
+	for _, l := range apiLines(pkg) {
+		if _, err := io.WriteString(w, l+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// boundImports returns the packages allPkg bound with pkg that pkg
+// imports, whose types its bindings may refer to, sorted by path.
+func boundImports(pkg *types.Package, allPkg []*types.Package) []*types.Package {
+	var pkgs []*types.Package
+	for _, p := range pkg.Imports() {
+		if isBound(p, pkg, allPkg) {
+			pkgs = append(pkgs, p)
+		}
+	}
+	sort.Sort(pkgsByPath(pkgs))
+	return pkgs
+}
+
+type pkgsByPath []*types.Package
+
+func (a pkgsByPath) Len() int           { return len(a) }
+func (a pkgsByPath) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a pkgsByPath) Less(i, j int) bool { return a[i].Path() < a[j].Path() }
+
+// isBound reports whether pkg is pkg0 or one of the packages allPkg
+// bound with it. Packages are compared by path, as the bound packages
+// may be type-checked separately from the packages importing them.
+func isBound(pkg, pkg0 *types.Package, allPkg []*types.Package) bool {
+	if pkg == nil {
+		return false
+	}
+	if pkg.Path() == pkg0.Path() {
+		return true
+	}
+	for _, p := range allPkg {
+		if pkg.Path() == p.Path() {
+			return true
+		}
+	}
+	return false
+}
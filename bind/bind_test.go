@@ -3,6 +3,7 @@ package bind
 import (
 	"bytes"
 	"flag"
+	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
@@ -30,11 +31,26 @@ var tests = []string{
 	"testdata/structs.go",
 	"testdata/interfaces.go",
 	"testdata/issue10788.go",
+	"testdata/slices.go",
+	"testdata/vars.go",
+	"testdata/results.go",
+	"testdata/errors.go",
+	"testdata/streams.go",
+	"testdata/maps.go",
+	"testdata/casts.go",
+	"testdata/direct.go",
+	"testdata/docs.go",
+}
+
+// multiPkgTests lists the packages bound together, each one
+// importing the ones before it.
+var multiPkgTests = [][]string{
+	{"testdata/geom.go", "testdata/draw.go"},
 }
 
 var fset = token.NewFileSet()
 
-func typeCheck(t *testing.T, filename string) *types.Package {
+func typeCheck(t *testing.T, filename string, imported map[string]*types.Package) *types.Package {
 	f, err := parser.ParseFile(fset, filename, nil, parser.AllErrors)
 	if err != nil {
 		t.Fatalf("%s: %v", filename, err)
@@ -48,6 +64,13 @@ func typeCheck(t *testing.T, filename string) *types.Package {
 	conf.Error = func(err error) {
 		t.Error(err)
 	}
+	conf.Import = func(imports map[string]*types.Package, path string) (*types.Package, error) {
+		if pkg := imported[path]; pkg != nil {
+			imports[path] = pkg
+			return pkg, nil
+		}
+		return types.DefaultImport(imports, path)
+	}
 	pkg, err := conf.Check(pkgName, fset, []*ast.File{f}, nil)
 	if err != nil {
 		t.Fatal(err)
@@ -55,6 +78,46 @@ func typeCheck(t *testing.T, filename string) *types.Package {
 	return pkg
 }
 
+// seqPkg returns a stand-in for package golang.org/x/mobile/bind/seq,
+// declaring the DirectBuffer type used by the test packages.
+func seqPkg() *types.Package {
+	pkg := types.NewPackage("golang.org/x/mobile/bind/seq", "seq")
+	obj := types.NewTypeName(token.NoPos, pkg, "DirectBuffer", nil)
+	types.NewNamed(obj, types.NewStruct(nil, nil), nil)
+	pkg.Scope().Insert(obj)
+	pkg.MarkComplete()
+	return pkg
+}
+
+type bindTest struct {
+	filename string
+	pkg      *types.Package
+	allPkg   []*types.Package // bound together with pkg
+}
+
+func loadTests(t *testing.T) []bindTest {
+	var bts []bindTest
+	for _, filename := range tests {
+		pkg := typeCheck(t, filename, map[string]*types.Package{
+			"golang.org/x/mobile/bind/seq": seqPkg(),
+		})
+		bts = append(bts, bindTest{filename, pkg, []*types.Package{pkg}})
+	}
+	for _, filenames := range multiPkgTests {
+		imported := make(map[string]*types.Package)
+		var allPkg []*types.Package
+		for _, filename := range filenames {
+			pkg := typeCheck(t, filename, imported)
+			imported[pkg.Path()] = pkg
+			allPkg = append(allPkg, pkg)
+		}
+		for i, filename := range filenames {
+			bts = append(bts, bindTest{filename, allPkg[i], allPkg})
+		}
+	}
+	return bts
+}
+
 // diff runs the command "diff a b" and returns its output
 func diff(a, b string) string {
 	var buf bytes.Buffer
@@ -91,12 +154,11 @@ func TestGenObjc(t *testing.T) {
 		false: ".objc.m.golden",
 	}
 
-	for _, filename := range tests {
-		pkg := typeCheck(t, filename)
-
+	for _, bt := range loadTests(t) {
+		filename := bt.filename
 		for isHeader, suffix := range suffixes {
 			var buf bytes.Buffer
-			if err := GenObjc(&buf, fset, pkg, isHeader); err != nil {
+			if err := GenObjc(&buf, fset, bt.pkg, bt.allPkg, "", isHeader); err != nil {
 				t.Errorf("%s: %v", filename, err)
 				continue
 			}
@@ -118,10 +180,10 @@ func TestGenObjc(t *testing.T) {
 }
 
 func TestGenJava(t *testing.T) {
-	for _, filename := range tests {
+	for _, bt := range loadTests(t) {
+		filename := bt.filename
 		var buf bytes.Buffer
-		pkg := typeCheck(t, filename)
-		if err := GenJava(&buf, fset, pkg); err != nil {
+		if err := GenJava(&buf, fset, bt.pkg, bt.allPkg, ""); err != nil {
 			t.Errorf("%s: %v", filename, err)
 			continue
 		}
@@ -142,11 +204,59 @@ func TestGenJava(t *testing.T) {
 	}
 }
 
+func TestGenKotlin(t *testing.T) {
+	for _, bt := range loadTests(t) {
+		filename := bt.filename
+		var buf bytes.Buffer
+		if err := GenKotlin(&buf, fset, bt.pkg, bt.allPkg, ""); err != nil {
+			t.Errorf("%s: %v", filename, err)
+			continue
+		}
+		out := writeTempFile(t, "kotlin", buf.Bytes())
+		defer os.Remove(out)
+		golden := filename[:len(filename)-len(".go")] + ".kt.golden"
+		if diffstr := diff(golden, out); diffstr != "" {
+			t.Errorf("%s: does not match Kotlin golden:\n%s", filename, diffstr)
+
+			if *updateFlag {
+				t.Logf("Updating %s...", golden)
+				if err := exec.Command("/bin/cp", out, golden).Run(); err != nil {
+					t.Errorf("Update failed: %s", err)
+				}
+			}
+		}
+	}
+}
+
+func TestGenSwift(t *testing.T) {
+	for _, bt := range loadTests(t) {
+		filename := bt.filename
+		var buf bytes.Buffer
+		if err := GenSwift(&buf, fset, bt.pkg, bt.allPkg, ""); err != nil {
+			t.Errorf("%s: %v", filename, err)
+			continue
+		}
+		out := writeTempFile(t, "swift", buf.Bytes())
+		defer os.Remove(out)
+		golden := filename[:len(filename)-len(".go")] + ".swift.golden"
+		if diffstr := diff(golden, out); diffstr != "" {
+			t.Errorf("%s: does not match Swift golden:\n%s", filename, diffstr)
+
+			if *updateFlag {
+				t.Logf("Updating %s...", golden)
+				if err := exec.Command("/bin/cp", out, golden).Run(); err != nil {
+					t.Errorf("Update failed: %s", err)
+				}
+			}
+		}
+	}
+}
+
 func TestGenGo(t *testing.T) {
-	for _, filename := range tests {
+	for _, bt := range loadTests(t) {
+		filename := bt.filename
 		var buf bytes.Buffer
-		pkg := typeCheck(t, filename)
-		if err := GenGo(&buf, fset, pkg); err != nil {
+		if err := GenGo(&buf, fset, bt.pkg, bt.allPkg); err != nil {
 			t.Errorf("%s: %v", filename, err)
 			continue
 		}
@@ -165,3 +275,221 @@ func TestGenGo(t *testing.T) {
 		}
 	}
 }
+
+func TestGenAPI(t *testing.T) {
+	for _, bt := range loadTests(t) {
+		filename := bt.filename
+		var buf bytes.Buffer
+		if err := GenAPI(&buf, bt.pkg); err != nil {
+			t.Errorf("%s: %v", filename, err)
+			continue
+		}
+		out := writeTempFile(t, "api", buf.Bytes())
+		defer os.Remove(out)
+		golden := filename[:len(filename)-len(".go")] + ".api.golden"
+		if diffstr := diff(golden, out); diffstr != "" {
+			t.Errorf("%s: does not match API golden:\n%s", filename, diffstr)
+
+			if *updateFlag {
+				t.Logf("Updating %s...", golden)
+				if err := exec.Command("/bin/cp", out, golden).Run(); err != nil {
+					t.Errorf("Update failed: %s", err)
+				}
+			}
+		}
+	}
+}
+
+func TestDiffAPI(t *testing.T) {
+	old := `# v1.0
+pkg p, func F(int) string
+pkg p, type I interface { M }
+pkg p, type I interface, M()
+pkg p, type S struct
+pkg p, type S struct, X int
+`
+	new := `pkg p, func F(int64) string
+pkg p, func G()
+pkg p, type I interface { M, N }
+pkg p, type I interface, M()
+pkg p, type I interface, N()
+
+pkg p, type S struct
+pkg p, type S struct, X int
+pkg p, type S struct, Y int
+`
+	want := []string{
+		"- pkg p, func F(int) string",
+		"+ pkg p, func F(int64) string",
+		"+ pkg p, func G()",
+		"- pkg p, type I interface { M }",
+		"+ pkg p, type I interface { M, N }",
+		"+ pkg p, type I interface, N()",
+		"+ pkg p, type S struct, Y int",
+	}
+	changes, err := DiffAPI(strings.NewReader(old), strings.NewReader(new))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.String())
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("DiffAPI:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	changes, err = DiffAPI(strings.NewReader(old), strings.NewReader(old))
+	if err != nil || len(changes) != 0 {
+		t.Errorf("DiffAPI of the same description = %v, %v; want no changes", changes, err)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	for _, bt := range loadTests(t) {
+		for _, p := range Check(fset, bt.pkg, bt.allPkg) {
+			t.Errorf("%s: unexpected problem: %v", bt.filename, p)
+		}
+	}
+
+	const filename = "testdata/unsupported.go"
+	pkg := typeCheck(t, filename, nil)
+	var buf bytes.Buffer
+	for _, p := range Check(fset, pkg, []*types.Package{pkg}) {
+		fmt.Fprintln(&buf, p)
+	}
+	out := writeTempFile(t, "check", buf.Bytes())
+	defer os.Remove(out)
+	golden := "testdata/unsupported.check.golden"
+	if diffstr := diff(golden, out); diffstr != "" {
+		t.Errorf("%s: does not match check golden:\n%s", filename, diffstr)
+
+		if *updateFlag {
+			t.Logf("Updating %s...", golden)
+			if err := exec.Command("/bin/cp", out, golden).Run(); err != nil {
+				t.Errorf("Update failed: %s", err)
+			}
+		}
+	}
+}
+
+func TestOmit(t *testing.T) {
+	pkg := typeCheck(t, "testdata/unsupported.go", nil)
+	allPkg, probs := Omit(fset, []*types.Package{pkg})
+	omitted := make(map[string]bool)
+	for _, p := range probs {
+		omitted[p.Name] = true
+	}
+	for _, name := range []string{"C", "Overflow", "E", "Celsius", "Temp", "Callback", "Send", "Lookup", "Nested", "Pos", "Errs", "S.P", "T.S", "I.Values", "UseI", "UseHidden", "Callbacks", "Div"} {
+		if !omitted[name] {
+			t.Errorf("%s not omitted", name)
+		}
+	}
+	scope := allPkg[0].Scope()
+	for _, name := range []string{"Good", "Hello", "Sum", "DivResult"} {
+		if scope.Lookup(name) == nil {
+			t.Errorf("%s omitted", name)
+		}
+	}
+	if probs := Check(fset, allPkg[0], allPkg); len(probs) > 0 {
+		t.Errorf("problems after Omit: %v", probs)
+	}
+
+	var buf bytes.Buffer
+	if err := GenGo(&buf, fset, allPkg[0], allPkg); err != nil {
+		t.Errorf("GenGo: %v", err)
+	}
+	if err := GenJava(&buf, fset, allPkg[0], allPkg, ""); err != nil {
+		t.Errorf("GenJava: %v", err)
+	}
+	for _, isHeader := range []bool{true, false} {
+		if err := GenObjc(&buf, fset, allPkg[0], allPkg, "", isHeader); err != nil {
+			t.Errorf("GenObjc: %v", err)
+		}
+	}
+}
+
+func TestGenNames(t *testing.T) {
+	var draw bindTest
+	for _, bt := range loadTests(t) {
+		if bt.filename == "testdata/draw.go" {
+			draw = bt
+		}
+	}
+
+	gens := []struct {
+		name        string
+		gen         func(w *bytes.Buffer) error
+		want, avoid []string
+	}{
+		{
+			name: "Java",
+			gen: func(w *bytes.Buffer) error {
+				return GenJava(w, fset, draw.pkg, draw.allPkg, "com.example.sdk")
+			},
+			want:  []string{"package com.example.sdk.draw;", "com.example.sdk.geom.Geom.Point"},
+			avoid: []string{"package go.", "go.geom."},
+		},
+		{
+			name: "Kotlin",
+			gen: func(w *bytes.Buffer) error {
+				return GenKotlin(w, fset, draw.pkg, draw.allPkg, "com.example.sdk")
+			},
+			want:  []string{"package com.example.sdk.draw\n", "com.example.sdk.geom.Geom.Shape"},
+			avoid: []string{"package go.", "go.geom."},
+		},
+		{
+			name: "Objective-C header",
+			gen: func(w *bytes.Buffer) error {
+				return GenObjc(w, fset, draw.pkg, draw.allPkg, "EXS", true)
+			},
+			want:  []string{"#define __EXSDraw_H__", `#include "EXSGeom.h"`, "@interface EXSDrawCanvas", "EXSGeomPoint*", "EXSDrawNew("},
+			avoid: []string{"GoDraw", "GoGeom"},
+		},
+		{
+			name: "Objective-C",
+			gen: func(w *bytes.Buffer) error {
+				return GenObjc(w, fset, draw.pkg, draw.allPkg, "EXS", false)
+			},
+			want:  []string{`#include "EXSDraw.h"`, "@implementation EXSDrawCanvas", "EXSGeomPoint*"},
+			avoid: []string{"GoDraw", "GoGeom"},
+		},
+		{
+			name: "Swift",
+			gen: func(w *bytes.Buffer) error {
+				return GenSwift(w, fset, draw.pkg, draw.allPkg, "EXS")
+			},
+			want:  []string{"= EXSDrawCanvas", "EXSDrawNew("},
+			avoid: []string{"GoDraw", "GoGeom"},
+		},
+	}
+	for _, g := range gens {
+		var buf bytes.Buffer
+		if err := g.gen(&buf); err != nil {
+			t.Errorf("%s: %v", g.name, err)
+			continue
+		}
+		out := buf.String()
+		for _, s := range g.want {
+			if !strings.Contains(out, s) {
+				t.Errorf("%s: missing %q in:\n%s", g.name, s, out)
+			}
+		}
+		for _, s := range g.avoid {
+			if strings.Contains(out, s) {
+				t.Errorf("%s: unexpected %q in:\n%s", g.name, s, out)
+			}
+		}
+	}
+
+	for _, javaPkg := range []string{"com..example", "com.1example", "com.example."} {
+		if err := GenJava(new(bytes.Buffer), fset, draw.pkg, draw.allPkg, javaPkg); err == nil {
+			t.Errorf("GenJava with Java package %q succeeded, want error", javaPkg)
+		}
+	}
+	for _, prefix := range []string{"1EX", "E-X", "EXé"} {
+		if err := GenObjc(new(bytes.Buffer), fset, draw.pkg, draw.allPkg, prefix, true); err == nil {
+			t.Errorf("GenObjc with prefix %q succeeded, want error", prefix)
+		}
+	}
+}
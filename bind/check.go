@@ -0,0 +1,330 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"fmt"
+	"go/token"
+
+	"golang.org/x/tools/go/types"
+)
+
+// A Problem is an exported declaration that gobind cannot bind.
+type Problem struct {
+	Pos    token.Position
+	Name   string // name of the declaration, such as F, T, T.M or T.F
+	Reason string // why it cannot be bound
+	Hint   string // what would bind, if known
+
+	obj types.Object // package-level declaration holding the problem
+}
+
+func (p *Problem) Error() string {
+	s := fmt.Sprintf("%s: %s: %s", p.Pos, p.Name, p.Reason)
+	if p.Hint != "" {
+		s += " (" + p.Hint + ")"
+	}
+	return s
+}
+
+// Check returns the problems of the exported declarations of pkg,
+// bound together with the packages allPkg, in the order of their
+// names. It reports all the declarations that would make the
+// generators fail.
+func Check(fset *token.FileSet, pkg *types.Package, allPkg []*types.Package) []*Problem {
+	c := &checker{fset: fset, pkg: pkg, allPkg: allPkg}
+	c.check()
+	return c.probs
+}
+
+// Omit returns copies of the packages allPkg, bound together, without
+// the declarations having problems. A struct or interface type having
+// a problem with one of its fields or methods is omitted entirely, as
+// are the declarations using omitted types. The generators bind the
+// rest of the packages. Omit also returns the problems of the omitted
+// declarations.
+func Omit(fset *token.FileSet, allPkg []*types.Package) ([]*types.Package, []*Problem) {
+	omit := make(map[types.Object]bool)
+	var probs []*Problem
+	for {
+		var found []*Problem
+		for _, pkg := range allPkg {
+			c := &checker{fset: fset, pkg: pkg, allPkg: allPkg, omit: omit}
+			c.check()
+			found = append(found, c.probs...)
+		}
+		if len(found) == 0 {
+			break
+		}
+		for _, p := range found {
+			omit[p.obj] = true
+		}
+		probs = append(probs, found...)
+	}
+
+	var pkgs []*types.Package
+	for _, pkg := range allPkg {
+		p := types.NewPackage(pkg.Path(), pkg.Name())
+		scope := pkg.Scope()
+		for _, name := range scope.Names() {
+			if obj := scope.Lookup(name); !omit[obj] {
+				p.Scope().Insert(obj)
+			}
+		}
+		p.SetImports(pkg.Imports())
+		p.MarkComplete()
+		pkgs = append(pkgs, p)
+	}
+	return pkgs, probs
+}
+
+type checker struct {
+	fset   *token.FileSet
+	pkg    *types.Package
+	allPkg []*types.Package
+	omit   map[types.Object]bool // declarations omitted by Omit
+	probs  []*Problem
+}
+
+func (c *checker) errorf(obj types.Object, pos token.Pos, name, hint, format string, args ...interface{}) {
+	c.probs = append(c.probs, &Problem{
+		Pos:    c.fset.Position(pos),
+		Name:   name,
+		Reason: fmt.Sprintf(format, args...),
+		Hint:   hint,
+		obj:    obj,
+	})
+}
+
+func (c *checker) check() {
+	scope := c.pkg.Scope()
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if !obj.Exported() || c.omit[obj] {
+			continue
+		}
+		switch obj := obj.(type) {
+		case *types.Const:
+			t := constType(obj)
+			if t == nil {
+				c.errorf(obj, obj.Pos(), name, "use a boolean, numeric or string constant", "unsupported constant type %s", obj.Type())
+			} else if err := checkConstValue(obj, t); err != nil {
+				c.errorf(obj, obj.Pos(), name, "use a value that fits in 64 bits, or give the constant a type it fits", "%v", err)
+			}
+		case *types.Var:
+			if isErrorType(obj.Type()) {
+				c.errorf(obj, obj.Pos(), name, "use a function returning the error", "variables of type error are not supported")
+				break
+			}
+			c.checkValue(obj, obj.Pos(), name, obj.Type(), ctxValue)
+		case *types.Func:
+			c.checkFunc(obj, obj, name, false)
+		case *types.TypeName:
+			c.checkType(obj)
+		}
+	}
+}
+
+func (c *checker) checkType(obj *types.TypeName) {
+	switch t := obj.Type().Underlying().(type) {
+	case *types.Struct:
+		for _, f := range exportedFields(t) {
+			c.checkValue(obj, f.Pos(), obj.Name()+"."+f.Name(), f.Type(), ctxValue)
+		}
+		for _, m := range exportedMethodSet(types.NewPointer(obj.Type())) {
+			c.checkFunc(obj, m, obj.Name()+"."+m.Name(), false)
+		}
+	case *types.Interface:
+		for i := 0; i < t.NumMethods(); i++ {
+			m := t.Method(i)
+			c.checkFunc(obj, m, obj.Name()+"."+m.Name(), true)
+		}
+	default:
+		if implementsError(obj) {
+			return // bound as an exception
+		}
+		c.errorf(obj, obj.Pos(), obj.Name(), "use a struct holding the value", "only struct and interface types are bound, not %s", t)
+	}
+}
+
+// checkFunc checks the signature of the function or method o, of the
+// declaration obj. Interface methods cannot return channels, and the
+// Java class of the results of o cannot collide with a type.
+func (c *checker) checkFunc(obj types.Object, o *types.Func, name string, isIface bool) {
+	sig := o.Type().(*types.Signature)
+	if err := checkResultClass(o); err != nil {
+		c.errorf(obj, o.Pos(), name, "rename the function or the type", "%v", err)
+		return
+	}
+	params := sig.Params()
+	for i := 0; i < params.Len(); i++ {
+		p := params.At(i)
+		if isErrorType(p.Type()) {
+			continue // passed as its message
+		}
+		if !c.checkValue(obj, o.Pos(), name, p.Type(), ctxParam) {
+			return
+		}
+	}
+	res := sig.Results()
+	for i := 0; i < res.Len(); i++ {
+		t := res.At(i).Type()
+		if isErrorType(t) {
+			if i < res.Len()-1 {
+				c.errorf(obj, o.Pos(), name, "return the error as the last result", "only the last result may be of type error")
+				return
+			}
+			continue
+		}
+		ctx := ctxResult
+		if isIface {
+			ctx = ctxIfaceResult
+		}
+		if !c.checkValue(obj, o.Pos(), name, t, ctx) {
+			return
+		}
+	}
+}
+
+// Contexts of the values checked by checkValue.
+const (
+	ctxValue       = iota // field or variable
+	ctxParam              // function parameter
+	ctxResult             // function result
+	ctxIfaceResult        // interface method result
+	ctxElem               // element of a slice, map or channel
+)
+
+// checkValue checks that values of type t can be passed in ctx, and
+// reports whether they can.
+func (c *checker) checkValue(obj types.Object, pos token.Pos, name string, t types.Type, ctx int) bool {
+	reason, hint := c.valueProblem(t, ctx)
+	if reason == "" {
+		return true
+	}
+	c.errorf(obj, pos, name, hint, "%s", reason)
+	return false
+}
+
+// valueProblem returns why values of type t cannot be passed in ctx,
+// and what would bind, or empty strings if they can.
+func (c *checker) valueProblem(t types.Type, ctx int) (reason, hint string) {
+	if isErrorType(t) {
+		return "unsupported type error", "return the error as the last result of a function"
+	}
+	switch t := t.(type) {
+	case *types.Basic:
+		if !isSupportedBasic(t) {
+			return fmt.Sprintf("unsupported type %s", t), "use a boolean, integer, float or string type"
+		}
+	case *types.Named:
+		switch t.Underlying().(type) {
+		case *types.Struct, *types.Interface:
+		default:
+			return fmt.Sprintf("unsupported type %s, only struct and interface types are bound", t), "use its underlying type, or a struct holding the value"
+		}
+		obj := t.Obj()
+		if !obj.Exported() {
+			return fmt.Sprintf("type %s is not exported", t), "export " + obj.Name() + ", or use an exported type"
+		}
+		if !isBound(obj.Pkg(), c.pkg, c.allPkg) {
+			return fmt.Sprintf("type %s is not defined in a bound package", t), fmt.Sprintf("bind package %s too, or use a type of package %s", obj.Pkg().Path(), c.pkg.Name())
+		}
+		if c.omit[obj] {
+			return fmt.Sprintf("type %s is omitted", t), "fix the problems of " + obj.Name()
+		}
+	case *types.Pointer:
+		if isDirectBuffer(t) {
+			if ctx == ctxElem {
+				return "*seq.DirectBuffer values cannot be elements", "pass the buffers separately"
+			}
+			return "", ""
+		}
+		if n, ok := t.Elem().(*types.Named); ok {
+			if _, ok := n.Underlying().(*types.Struct); ok {
+				return c.valueProblem(n, ctx)
+			}
+		}
+		return fmt.Sprintf("unsupported type %s, only pointers to structs are bound", t), "pass the value, or a pointer to a struct"
+	case *types.Slice, *types.Array:
+		if ctx == ctxElem {
+			return fmt.Sprintf("unsupported element type %s", t), "use a struct holding the slice"
+		}
+		elem := arrayElem(t)
+		if b, ok := elem.(*types.Basic); ok && b.Kind() == types.Uint8 {
+			return "", "" // byte slice
+		}
+		if !isArrayElem(elem) {
+			return fmt.Sprintf("unsupported slice element type %s", elem), "use a slice of booleans, integers, floats, strings, structs or interfaces"
+		}
+		return c.valueProblem(elem, ctxElem)
+	case *types.Map:
+		if ctx == ctxElem {
+			return fmt.Sprintf("unsupported element type %s", t), "use a struct holding the map"
+		}
+		if k, ok := t.Key().(*types.Basic); !ok || !isSupportedBasic(k) {
+			return fmt.Sprintf("unsupported map key type %s", t.Key()), "use boolean, numeric or string keys"
+		}
+		if b, ok := t.Elem().(*types.Slice); ok && isByte(b.Elem()) {
+			return "", ""
+		}
+		return c.valueProblem(t.Elem(), ctxElem)
+	case *types.Chan:
+		switch ctx {
+		case ctxResult:
+		case ctxIfaceResult:
+			return "channels cannot be returned by interface methods", "return the channel from a function or a struct method"
+		case ctxParam:
+			return "channels are only supported as results of functions and struct methods", "return the channel from a function, or pass an interface called for each value"
+		default:
+			return "channels are only supported as results of functions and struct methods", "use a method returning the channel"
+		}
+		if t.Dir() != types.RecvOnly {
+			return fmt.Sprintf("unsupported channel type %s, only receive-only channels are supported", t), "return a <-chan " + types.TypeString(t.Elem(), types.RelativeTo(c.pkg))
+		}
+		return c.valueProblem(t.Elem(), ctxElem)
+	case *types.Signature:
+		return fmt.Sprintf("unsupported function type %s", t), "use an interface with a single method"
+	default:
+		return fmt.Sprintf("unsupported type %s", t), "declare a named struct or interface type"
+	}
+	return "", ""
+}
+
+// isSupportedBasic reports whether the values of the basic type t are
+// bound.
+func isSupportedBasic(t *types.Basic) bool {
+	switch t.Kind() {
+	case types.Bool, types.String, types.Float32, types.Float64,
+		types.Int, types.Int8, types.Int16, types.Int32, types.Int64,
+		types.Uint, types.Uint8, types.Uint16, types.Uint32, types.Uint64:
+		return true
+	}
+	return false
+}
+
+// isArrayElem reports whether slices of elements of type t, other
+// than byte slices, are bound.
+func isArrayElem(t types.Type) bool {
+	switch t := t.(type) {
+	case *types.Basic:
+		switch t.Kind() {
+		case types.Bool, types.String, types.Float32, types.Float64,
+			types.Int, types.Int8, types.Int16, types.Int32, types.Int64,
+			types.Uint, types.Uint16, types.Uint32, types.Uint64:
+			return true
+		}
+	case *types.Named:
+		return true
+	case *types.Pointer:
+		return !isDirectBuffer(t)
+	}
+	return false
+}
+
+func isByte(t types.Type) bool {
+	b, ok := t.(*types.Basic)
+	return ok && b.Kind() == types.Uint8
+}
@@ -0,0 +1,185 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/go/types"
+)
+
+// pkgDocs holds the doc comments of the declarations of a package,
+// keyed by name, or by type and member name for fields and methods,
+// such as "T.M". The package doc is keyed by "".
+type pkgDocs map[string]string
+
+// loadDocs reads the doc comments of pkg from its source files, found
+// through the positions of its declarations in fset. The declarations
+// of files that cannot be parsed have no doc.
+func loadDocs(fset *token.FileSet, pkg *types.Package) pkgDocs {
+	docs := make(pkgDocs)
+	parsed := make(map[string]bool)
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		pos := scope.Lookup(name).Pos()
+		if !pos.IsValid() {
+			continue
+		}
+		filename := fset.Position(pos).Filename
+		if parsed[filename] {
+			continue
+		}
+		parsed[filename] = true
+		f, err := parser.ParseFile(token.NewFileSet(), filename, nil, parser.ParseComments)
+		if err != nil {
+			continue
+		}
+		docs.addFile(f)
+	}
+	return docs
+}
+
+func (d pkgDocs) addFile(f *ast.File) {
+	d.add("", f.Doc)
+	for _, decl := range f.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			name := decl.Name.Name
+			if decl.Recv != nil && len(decl.Recv.List) == 1 {
+				typ := decl.Recv.List[0].Type
+				if star, ok := typ.(*ast.StarExpr); ok {
+					typ = star.X
+				}
+				if id, ok := typ.(*ast.Ident); ok {
+					name = id.Name + "." + name
+				}
+			}
+			d.add(name, decl.Doc)
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					d.add(spec.Name.Name, specDoc(decl, spec.Doc, spec.Comment))
+					d.addMembers(spec)
+				case *ast.ValueSpec:
+					for _, n := range spec.Names {
+						d.add(n.Name, specDoc(decl, spec.Doc, spec.Comment))
+					}
+				}
+			}
+		}
+	}
+}
+
+// addMembers adds the docs of the fields of a struct type, or of
+// the methods of an interface type.
+func (d pkgDocs) addMembers(spec *ast.TypeSpec) {
+	var fields *ast.FieldList
+	switch t := spec.Type.(type) {
+	case *ast.StructType:
+		fields = t.Fields
+	case *ast.InterfaceType:
+		fields = t.Methods
+	default:
+		return
+	}
+	for _, f := range fields.List {
+		doc := f.Doc
+		if doc == nil {
+			doc = f.Comment
+		}
+		for _, n := range f.Names {
+			d.add(spec.Name.Name+"."+n.Name, doc)
+		}
+	}
+}
+
+// specDoc returns the doc of a spec of decl: its own doc or line
+// comment, or the doc of decl if it declares a single spec.
+func specDoc(decl *ast.GenDecl, doc, comment *ast.CommentGroup) *ast.CommentGroup {
+	switch {
+	case doc != nil:
+		return doc
+	case comment != nil:
+		return comment
+	case len(decl.Specs) == 1:
+		return decl.Doc
+	}
+	return nil
+}
+
+func (d pkgDocs) add(key string, doc *ast.CommentGroup) {
+	if text := strings.TrimSpace(doc.Text()); text != "" {
+		d[key] = text
+	}
+}
+
+// docKey returns the key of the doc of the function or method o.
+func docKey(o *types.Func) string {
+	recv := o.Type().(*types.Signature).Recv()
+	if recv == nil {
+		return o.Name()
+	}
+	typ := recv.Type()
+	if p, ok := typ.(*types.Pointer); ok {
+		typ = p.Elem()
+	}
+	if n, ok := typ.(*types.Named); ok {
+		return n.Obj().Name() + "." + o.Name()
+	}
+	return o.Name()
+}
+
+var docIdentRE = regexp.MustCompile(`\b(?:[a-z][a-z0-9_]*\.)?[A-Z][A-Za-z0-9_]*\b`)
+
+// lines returns the lines of the doc of key, with the Go identifiers
+// in names, possibly qualified by the name of pkg, replaced by their
+// generated names.
+func (d pkgDocs) lines(key string, pkg *types.Package, names map[string]string) []string {
+	text, ok := d[key]
+	if !ok {
+		return nil
+	}
+	text = docIdentRE.ReplaceAllStringFunc(text, func(id string) string {
+		name := id
+		if i := strings.IndexByte(id, '.'); i >= 0 {
+			if id[:i] != pkg.Name() {
+				return id
+			}
+			name = id[i+1:]
+		}
+		if n, ok := names[name]; ok {
+			return n
+		}
+		if name != id {
+			return name
+		}
+		return id
+	})
+	// The doc must not end the comment holding it.
+	text = strings.Replace(text, "*/", "* /", -1)
+	return strings.Split(text, "\n")
+}
+
+// genDoc prints the doc of key as a /** */ comment. See lines for names.
+func (d pkgDocs) genDoc(p *printer, key string, pkg *types.Package, names map[string]string) {
+	lines := d.lines(key, pkg, names)
+	if lines == nil {
+		return
+	}
+	p.Printf("/**\n")
+	for _, l := range lines {
+		if l == "" {
+			p.Printf(" *\n")
+		} else {
+			p.Printf(" * %s\n", l)
+		}
+	}
+	p.Printf(" */\n")
+}
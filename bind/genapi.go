@@ -0,0 +1,193 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/types"
+)
+
+// apiLines returns the lines describing the API of pkg as bound by
+// gobind, sorted. The lines have the format of the API files of the
+// Go distribution, one per declaration:
+//
+//	pkg example.com/p, const Max int
+//	pkg example.com/p, func New(int) *T
+//	pkg example.com/p, method (*T) Div(int) (q int, r int, error)
+//	pkg example.com/p, type I interface { Close, Read }
+//	pkg example.com/p, type I interface, Close() error
+//	pkg example.com/p, type T struct
+//	pkg example.com/p, type T struct, Name string
+//	pkg example.com/p, var Debug bool
+//
+// The types of other packages are qualified by their name. The
+// methods of structs are listed with a pointer receiver, as gobind
+// binds the methods of both. The names of results are kept if there
+// are several, as they name the fields of the Java result classes.
+// An interface is also described by the names of its methods, so that
+// adding a method changes the description.
+func apiLines(pkg *types.Package) []string {
+	qf := func(p *types.Package) string {
+		if p.Path() == pkg.Path() {
+			return ""
+		}
+		return p.Name()
+	}
+	typ := func(t types.Type) string {
+		return types.TypeString(t, qf)
+	}
+
+	var lines []string
+	add := func(format string, args ...interface{}) {
+		lines = append(lines, "pkg "+pkg.Path()+", "+fmt.Sprintf(format, args...))
+	}
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+		switch obj := obj.(type) {
+		case *types.Const:
+			if t := constType(obj); t != nil && checkConstValue(obj, t) == nil {
+				add("const %s %s", name, typ(t))
+			}
+		case *types.Var:
+			add("var %s %s", name, typ(obj.Type()))
+		case *types.Func:
+			add("func %s%s", name, apiSignature(obj, typ))
+		case *types.TypeName:
+			switch t := obj.Type().Underlying().(type) {
+			case *types.Struct:
+				add("type %s struct", name)
+				for _, f := range exportedFields(t) {
+					add("type %s struct, %s %s", name, f.Name(), typ(f.Type()))
+				}
+				for _, m := range exportedMethodSet(types.NewPointer(obj.Type())) {
+					add("method (*%s) %s%s", name, m.Name(), apiSignature(m, typ))
+				}
+			case *types.Interface:
+				var names []string
+				for i := 0; i < t.NumMethods(); i++ {
+					m := t.Method(i)
+					if !m.Exported() {
+						continue
+					}
+					names = append(names, m.Name())
+					add("type %s interface, %s%s", name, m.Name(), apiSignature(m, typ))
+				}
+				sort.Strings(names)
+				add("type %s interface { %s }", name, strings.Join(names, ", "))
+			}
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+// apiSignature returns the parameter and result types of the function
+// or method o, formatted by typ.
+func apiSignature(o *types.Func, typ func(types.Type) string) string {
+	sig := o.Type().(*types.Signature)
+	params := sig.Params()
+	var ps []string
+	for i := 0; i < params.Len(); i++ {
+		t := typ(params.At(i).Type())
+		if sig.Variadic() && i == params.Len()-1 {
+			t = "..." + strings.TrimPrefix(t, "[]")
+		}
+		ps = append(ps, t)
+	}
+	s := "(" + strings.Join(ps, ", ") + ")"
+
+	res := sig.Results()
+	named := numValues(sig) > 1
+	var rs []string
+	for i := 0; i < res.Len(); i++ {
+		t := typ(res.At(i).Type())
+		if named && !isErrorType(res.At(i).Type()) {
+			t = resultName(res, i) + " " + t
+		}
+		rs = append(rs, t)
+	}
+	switch len(rs) {
+	case 0:
+	case 1:
+		s += " " + rs[0]
+	default:
+		s += " (" + strings.Join(rs, ", ") + ")"
+	}
+	return s
+}
+
+// An APIChange is a line added to or removed from the description of
+// an API, as written by GenAPI.
+type APIChange struct {
+	Line string
+
+	// Breaking reports whether Line was removed. A removed
+	// declaration, or one whose type changed, breaks the programs
+	// using it, while added declarations do not.
+	Breaking bool
+}
+
+func (c APIChange) String() string {
+	if c.Breaking {
+		return "- " + c.Line
+	}
+	return "+ " + c.Line
+}
+
+// DiffAPI returns the changes from the API description old to the API
+// description new, sorted by line. Blank lines and lines starting with
+// # are ignored.
+func DiffAPI(old, new io.Reader) ([]APIChange, error) {
+	oldLines, err := readAPI(old)
+	if err != nil {
+		return nil, err
+	}
+	newLines, err := readAPI(new)
+	if err != nil {
+		return nil, err
+	}
+	var changes []APIChange
+	for l := range oldLines {
+		if !newLines[l] {
+			changes = append(changes, APIChange{Line: l, Breaking: true})
+		}
+	}
+	for l := range newLines {
+		if !oldLines[l] {
+			changes = append(changes, APIChange{Line: l})
+		}
+	}
+	sort.Sort(apiChangesByLine(changes))
+	return changes, nil
+}
+
+func readAPI(r io.Reader) (map[string]bool, error) {
+	lines := make(map[string]bool)
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		l := string(bytes.TrimSpace(s.Bytes()))
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		lines[l] = true
+	}
+	return lines, s.Err()
+}
+
+type apiChangesByLine []APIChange
+
+func (a apiChangesByLine) Len() int           { return len(a) }
+func (a apiChangesByLine) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a apiChangesByLine) Less(i, j int) bool { return a[i].Line < a[j].Line }
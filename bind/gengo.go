@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"go/token"
 	"log"
+	"sort"
 	"strings"
 
 	"golang.org/x/tools/go/types"
@@ -15,9 +16,14 @@ import (
 
 type goGen struct {
 	*printer
-	fset *token.FileSet
-	pkg  *types.Package
-	err  ErrorList
+	fset   *token.FileSet
+	pkg    *types.Package
+	allPkg []*types.Package
+	err    ErrorList
+
+	// fields set by gen.
+	imports map[string]*types.Package // other bound packages used, by path
+	proxies []*types.TypeName         // interfaces of other bound packages used
 }
 
 func (g *goGen) errorf(format string, args ...interface{}) {
@@ -33,13 +39,56 @@ package go_%s
 import (
 	"golang.org/x/mobile/bind/seq"
 	%q
-)
-
 `
 
 func (g *goGen) genPreamble() {
 	n := g.pkg.Name()
 	g.Printf(goPreamble, n, n, g.pkg.Path(), n, g.pkg.Path())
+	var paths []string
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		g.Printf("\t%q\n", path)
+	}
+	g.Printf(")\n\n")
+}
+
+// checkBound reports whether the named type obj can be referred to
+// by the bindings, as it is defined in a bound package.
+func (g *goGen) checkBound(obj *types.TypeName) bool {
+	if !isBound(obj.Pkg(), g.pkg, g.allPkg) {
+		g.errorf("type %s not defined in package %s or a package bound with it", obj.Type(), g.pkg.Path())
+		return false
+	}
+	return true
+}
+
+// qualifiedName returns the name of the type obj qualified by its
+// package name, and records the package as used by the stub.
+func (g *goGen) qualifiedName(obj *types.TypeName) string {
+	if pkg := obj.Pkg(); pkg.Path() != g.pkg.Path() {
+		g.imports[pkg.Path()] = pkg
+	}
+	return obj.Pkg().Name() + "." + obj.Name()
+}
+
+// proxyName returns the name of the type implementing the interface
+// obj by calling a foreign object. The stub of each bound package
+// defines its own proxies for the interfaces of the other packages.
+func (g *goGen) proxyName(obj *types.TypeName) string {
+	if obj.Pkg().Path() == g.pkg.Path() {
+		return "proxy" + obj.Name()
+	}
+	name := "proxy" + obj.Pkg().Name() + "_" + obj.Name()
+	for _, p := range g.proxies {
+		if p == obj {
+			return name
+		}
+	}
+	g.proxies = append(g.proxies, obj)
+	return name
 }
 
 func (g *goGen) genFuncBody(o *types.Func, selectorLHS string) {
@@ -50,25 +99,24 @@ func (g *goGen) genFuncBody(o *types.Func, selectorLHS string) {
 		g.genRead("param_"+paramName(params, i), "in", p.Type())
 	}
 
-	res := sig.Results()
-	if res.Len() > 2 || res.Len() == 2 && !isErrorType(res.At(1).Type()) {
-		g.errorf("functions and methods must return either zero or one values, and optionally an error")
+	if err := checkResults(o); err != nil {
+		g.errorf("%v", err)
 		return
 	}
-	returnsValue := false
-	returnsError := false
-	if res.Len() == 1 {
-		if isErrorType(res.At(0).Type()) {
-			returnsError = true
-			g.Printf("err := ")
-		} else {
-			returnsValue = true
-			g.Printf("res := ")
+	res := sig.Results()
+	var resNames []string
+	for i := 0; i < res.Len(); i++ {
+		switch {
+		case isErrorType(res.At(i).Type()):
+			resNames = append(resNames, "err")
+		case numValues(sig) == 1:
+			resNames = append(resNames, "res")
+		default:
+			resNames = append(resNames, fmt.Sprintf("res_%d", i))
 		}
-	} else if res.Len() == 2 {
-		returnsValue = true
-		returnsError = true
-		g.Printf("res, err := ")
+	}
+	if len(resNames) > 0 {
+		g.Printf("%s := ", strings.Join(resNames, ", "))
 	}
 
 	g.Printf("%s.%s(", selectorLHS, o.Name())
@@ -78,34 +126,32 @@ func (g *goGen) genFuncBody(o *types.Func, selectorLHS string) {
 		}
 		g.Printf("param_%s", paramName(params, i))
 	}
+	if sig.Variadic() {
+		g.Printf("...")
+	}
 	g.Printf(")\n")
 
-	if returnsValue {
-		g.genWrite("res", "out", res.At(0).Type())
-	}
-	if returnsError {
-		g.genWrite("err", "out", res.At(res.Len()-1).Type())
+	for i, name := range resNames {
+		g.genWrite(name, "out", res.At(i).Type())
 	}
 }
 
 func (g *goGen) genWrite(valName, seqName string, T types.Type) {
 	if isErrorType(T) {
-		g.Printf("if %s == nil {\n", valName)
-		g.Printf("    %s.WriteString(\"\");\n", seqName)
-		g.Printf("} else {\n")
-		g.Printf("    %s.WriteString(%s.Error());\n", seqName, valName)
-		g.Printf("}\n")
+		g.Printf("%s.WriteError(%s)\n", seqName, valName)
 		return
 	}
 	switch T := T.(type) {
 	case *types.Pointer:
+		if isDirectBuffer(T) {
+			g.Printf("%s.WriteDirectBuffer(%s)\n", seqName, valName)
+			return
+		}
 		// TODO(crawshaw): test *int
 		// TODO(crawshaw): test **Generator
 		switch T := T.Elem().(type) {
 		case *types.Named:
-			obj := T.Obj()
-			if obj.Pkg() != g.pkg {
-				g.errorf("type %s not defined in package %s", T, g.pkg)
+			if !g.checkBound(T.Obj()) {
 				return
 			}
 			g.Printf("%s.WriteGoRef(%s)\n", seqName, valName)
@@ -116,14 +162,80 @@ func (g *goGen) genWrite(valName, seqName string, T types.Type) {
 		switch u := T.Underlying().(type) {
 		case *types.Interface, *types.Pointer:
 			g.Printf("%s.WriteGoRef(%s)\n", seqName, valName)
+		case *types.Struct:
+			// Struct values are passed as a reference to a copy.
+			g.Printf("%s_copy := %s\n", valName, valName)
+			g.Printf("%s.WriteGoRef(&%s_copy)\n", seqName, valName)
 		default:
 			g.errorf("unsupported, direct named type %s: %s", T, u)
 		}
+	case *types.Slice, *types.Array:
+		g.genWriteArray(valName, seqName, T)
+	case *types.Map:
+		g.Printf("%s.WriteArrayLen(len(%s))\n", seqName, valName)
+		g.Printf("for %s_key, %s_val := range %s {\n", valName, valName, valName)
+		g.Indent()
+		g.genWrite(valName+"_key", seqName, T.Key())
+		g.genWrite(valName+"_val", seqName, T.Elem())
+		g.Outdent()
+		g.Printf("}\n")
+	case *types.Chan:
+		g.genWriteStream(valName, seqName, T)
 	default:
 		g.Printf("%s.Write%s(%s);\n", seqName, seqType(T), valName)
 	}
 }
 
+// genWriteStream writes a receive-only channel as a reference to a
+// seq.Stream, which receives and writes its values on request.
+func (g *goGen) genWriteStream(valName, seqName string, T *types.Chan) {
+	if T.Dir() != types.RecvOnly {
+		g.errorf("unsupported channel type %s, only receive-only channels are supported", T)
+		return
+	}
+	elemName := valName + "_elem"
+	g.Printf("%s.WriteGoRef(seq.NewStream(func(%s *seq.Buffer, done <-chan struct{}) {\n", seqName, seqName)
+	g.Indent()
+	g.Printf("select {\n")
+	g.Printf("case %s, ok := <-%s:\n", elemName, valName)
+	g.Indent()
+	g.Printf("%s.WriteBool(ok)\n", seqName)
+	g.Printf("if ok {\n")
+	g.Indent()
+	g.genWrite(elemName, seqName, T.Elem())
+	g.Outdent()
+	g.Printf("}\n")
+	g.Outdent()
+	g.Printf("case <-done:\n")
+	g.Indent()
+	g.Printf("%s.WriteBool(false)\n", seqName)
+	g.Outdent()
+	g.Printf("}\n")
+	g.Outdent()
+	g.Printf("}))\n")
+}
+
+// genWriteArray writes a slice or array. Arrays of basic types are
+// written with a single seq.Buffer call, arrays of references are
+// written element by element.
+func (g *goGen) genWriteArray(valName, seqName string, T types.Type) {
+	seqTyp := seqType(T)
+	if seqTyp != "RefArray" {
+		if _, ok := T.(*types.Array); ok {
+			valName += "[:]"
+		}
+		g.Printf("%s.Write%s(%s)\n", seqName, seqTyp, valName)
+		return
+	}
+	elemName := valName + "_elem"
+	g.Printf("%s.WriteArrayLen(len(%s))\n", seqName, valName)
+	g.Printf("for _, %s := range %s {\n", elemName, valName)
+	g.Indent()
+	g.genWrite(elemName, seqName, arrayElem(T))
+	g.Outdent()
+	g.Printf("}\n")
+}
+
 func (g *goGen) genFunc(o *types.Func) {
 	g.Printf("func proxy_%s(out, in *seq.Buffer) {\n", o.Name())
 	g.Indent()
@@ -150,11 +262,16 @@ func exportedMethodSet(T types.Type) []*types.Func {
 	return methods
 }
 
+// exportedFields returns the exported fields of T that can be bound.
+// Fields of type error and channel fields are not bound.
 func exportedFields(T *types.Struct) []*types.Var {
 	var fields []*types.Var
 	for i := 0; i < T.NumFields(); i++ {
 		f := T.Field(i)
-		if !f.Exported() {
+		if !f.Exported() || isErrorType(f.Type()) {
+			continue
+		}
+		if _, ok := f.Type().(*types.Chan); ok {
 			continue
 		}
 		fields = append(fields, f)
@@ -168,7 +285,8 @@ func (g *goGen) genStruct(obj *types.TypeName, T *types.Struct) {
 
 	g.Printf("const (\n")
 	g.Indent()
-	g.Printf("proxy%s_Descriptor = \"go.%s.%s\"\n", obj.Name(), g.pkg.Name(), obj.Name())
+	g.Printf("proxy%s_Descriptor = %q\n", obj.Name(), seqDesc(g.pkg, obj.Name()))
+	g.Printf("proxy%s_cast_Code = 0x00e\n", obj.Name())
 	for i, f := range fields {
 		g.Printf("proxy%s_%s_Get_Code = 0x%x0f\n", obj.Name(), f.Name(), i)
 		g.Printf("proxy%s_%s_Set_Code = 0x%x1f\n", obj.Name(), f.Name(), i)
@@ -181,19 +299,14 @@ func (g *goGen) genStruct(obj *types.TypeName, T *types.Struct) {
 
 	g.Printf("type proxy%s seq.Ref\n\n", obj.Name())
 
-	for _, f := range fields {
-		seqTyp := seqType(f.Type())
+	g.genCast(obj, "*"+g.qualifiedName(obj))
 
+	for _, f := range fields {
 		g.Printf("func proxy%s_%s_Set(out, in *seq.Buffer) {\n", obj.Name(), f.Name())
 		g.Indent()
 		g.Printf("ref := in.ReadRef()\n")
-		g.Printf("v := in.Read%s()\n", seqTyp)
-		if seqTyp == "Ref" {
-			g.Printf("ref.Get().(*%s.%s).%s = v.Get().(%s)\n", g.pkg.Name(), obj.Name(), f.Name(), g.typeString(f.Type()))
-		} else {
-			// TODO(crawshaw): other kinds of non-ptr types.
-			g.Printf("ref.Get().(*%s.%s).%s = v\n", g.pkg.Name(), obj.Name(), f.Name())
-		}
+		g.genRead("v", "in", f.Type())
+		g.Printf("ref.Get().(*%s.%s).%s = v\n", g.pkg.Name(), obj.Name(), f.Name())
 		g.Outdent()
 		g.Printf("}\n\n")
 
@@ -201,11 +314,7 @@ func (g *goGen) genStruct(obj *types.TypeName, T *types.Struct) {
 		g.Indent()
 		g.Printf("ref := in.ReadRef()\n")
 		g.Printf("v := ref.Get().(*%s.%s).%s\n", g.pkg.Name(), obj.Name(), f.Name())
-		if seqTyp == "Ref" {
-			g.Printf("out.WriteGoRef(v)\n")
-		} else {
-			g.Printf("out.Write%s(v)\n", seqTyp)
-		}
+		g.genWrite("v", "out", f.Type())
 		g.Outdent()
 		g.Printf("}\n\n")
 	}
@@ -222,6 +331,7 @@ func (g *goGen) genStruct(obj *types.TypeName, T *types.Struct) {
 
 	g.Printf("func init() {\n")
 	g.Indent()
+	g.Printf("seq.Register(proxy%s_Descriptor, proxy%s_cast_Code, proxy%s_cast)\n", obj.Name(), obj.Name(), obj.Name())
 	for _, f := range fields {
 		n := f.Name()
 		g.Printf("seq.Register(proxy%s_Descriptor, proxy%s_%s_Set_Code, proxy%s_%s_Set)\n", obj.Name(), obj.Name(), n, obj.Name(), n)
@@ -235,13 +345,69 @@ func (g *goGen) genStruct(obj *types.TypeName, T *types.Struct) {
 	g.Printf("}\n\n")
 }
 
+// genCast generates the function reporting whether a reference is
+// to a Go object of type typ, the Go type of the struct or interface
+// obj. It implements the checked casts of the foreign languages.
+func (g *goGen) genCast(obj *types.TypeName, typ string) {
+	g.Printf("func proxy%s_cast(out, in *seq.Buffer) {\n", obj.Name())
+	g.Indent()
+	g.Printf("ref := in.ReadRef()\n")
+	g.Printf("ok := false\n")
+	g.Printf("if ref.Num < 0 { // go object\n")
+	g.Printf("	_, ok = ref.Get().(%s)\n", typ)
+	g.Printf("}\n")
+	g.Printf("out.WriteBool(ok)\n")
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
+// genVar generates a getter and setter for a package variable,
+// with the descriptor and codes used for struct fields.
+func (g *goGen) genVar(o *types.Var) {
+	if isErrorType(o.Type()) {
+		g.errorf("%s: variables of type error are not supported", o.Name())
+		return
+	}
+	v := fmt.Sprintf("%s.%s", g.pkg.Name(), o.Name())
+
+	g.Printf("const (\n")
+	g.Indent()
+	g.Printf("var_%s_Descriptor = %q\n", o.Name(), seqDesc(g.pkg, o.Name()))
+	g.Printf("var_%s_Get_Code = 0x00f\n", o.Name())
+	g.Printf("var_%s_Set_Code = 0x01f\n", o.Name())
+	g.Outdent()
+	g.Printf(")\n\n")
+
+	g.Printf("func var_%s_Set(out, in *seq.Buffer) {\n", o.Name())
+	g.Indent()
+	g.genRead("v", "in", o.Type())
+	g.Printf("%s = v\n", v)
+	g.Outdent()
+	g.Printf("}\n\n")
+
+	g.Printf("func var_%s_Get(out, in *seq.Buffer) {\n", o.Name())
+	g.Indent()
+	g.Printf("v := %s\n", v)
+	g.genWrite("v", "out", o.Type())
+	g.Outdent()
+	g.Printf("}\n\n")
+
+	g.Printf("func init() {\n")
+	g.Indent()
+	g.Printf("seq.Register(var_%s_Descriptor, var_%s_Set_Code, var_%s_Set)\n", o.Name(), o.Name(), o.Name())
+	g.Printf("seq.Register(var_%s_Descriptor, var_%s_Get_Code, var_%s_Get)\n", o.Name(), o.Name(), o.Name())
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
 func (g *goGen) genInterface(obj *types.TypeName) {
 	iface := obj.Type().(*types.Named).Underlying().(*types.Interface)
 
 	// Descriptor and code for interface methods.
 	g.Printf("const (\n")
 	g.Indent()
-	g.Printf("proxy%s_Descriptor = \"go.%s.%s\"\n", obj.Name(), g.pkg.Name(), obj.Name())
+	g.Printf("proxy%s_Descriptor = %q\n", obj.Name(), seqDesc(g.pkg, obj.Name()))
+	g.Printf("proxy%s_cast_Code = 0x00e\n", obj.Name())
 	for i := 0; i < iface.NumMethods(); i++ {
 		g.Printf("proxy%s_%s_Code = 0x%x0a\n", obj.Name(), iface.Method(i).Name(), i+1)
 	}
@@ -249,6 +415,7 @@ func (g *goGen) genInterface(obj *types.TypeName) {
 	g.Printf(")\n\n")
 
 	// Define the entry points.
+	g.genCast(obj, g.qualifiedName(obj))
 	for i := 0; i < iface.NumMethods(); i++ {
 		m := iface.Method(i)
 		g.Printf("func proxy%s_%s(out, in *seq.Buffer) {\n", obj.Name(), m.Name())
@@ -263,6 +430,7 @@ func (g *goGen) genInterface(obj *types.TypeName) {
 	// Register the method entry points.
 	g.Printf("func init() {\n")
 	g.Indent()
+	g.Printf("seq.Register(proxy%s_Descriptor, proxy%s_cast_Code, proxy%s_cast)\n", obj.Name(), obj.Name(), obj.Name())
 	for i := 0; i < iface.NumMethods(); i++ {
 		g.Printf("seq.Register(proxy%s_Descriptor, proxy%s_%s_Code, proxy%s_%s)\n",
 			obj.Name(), obj.Name(), iface.Method(i).Name(), obj.Name(), iface.Method(i).Name())
@@ -270,34 +438,59 @@ func (g *goGen) genInterface(obj *types.TypeName) {
 	g.Outdent()
 	g.Printf("}\n\n")
 
-	// Define a proxy interface.
-	g.Printf("type proxy%s seq.Ref\n\n", obj.Name())
+	g.genInterfaceProxy(obj)
+}
+
+// genInterfaceProxy defines the proxy type implementing the interface
+// obj by calling a foreign object. The method codes of the interfaces
+// of other bound packages are defined along with their proxies.
+func (g *goGen) genInterfaceProxy(obj *types.TypeName) {
+	iface := obj.Type().(*types.Named).Underlying().(*types.Interface)
+	proxy := g.proxyName(obj)
 
+	if obj.Pkg().Path() != g.pkg.Path() {
+		g.Printf("const (\n")
+		g.Indent()
+		for i := 0; i < iface.NumMethods(); i++ {
+			g.Printf("%s_%s_Code = 0x%x0a\n", proxy, iface.Method(i).Name(), i+1)
+		}
+		g.Outdent()
+		g.Printf(")\n\n")
+	}
+
+	g.Printf("type %s seq.Ref\n\n", proxy)
 	for i := 0; i < iface.NumMethods(); i++ {
 		m := iface.Method(i)
 		sig := m.Type().(*types.Signature)
 		params := sig.Params()
 		res := sig.Results()
 
-		if res.Len() > 2 ||
-			(res.Len() == 2 && !isErrorType(res.At(1).Type())) {
-			g.errorf("functions and methods must return either zero or one value, and optionally an error: %s.%s", obj.Name(), m.Name())
+		if err := checkResults(m); err != nil {
+			g.errorf("%v", err)
 			continue
 		}
 
-		g.Printf("func (p *proxy%s) %s(", obj.Name(), m.Name())
+		g.Printf("func (p *%s) %s(", proxy, m.Name())
 		for i := 0; i < params.Len(); i++ {
 			if i > 0 {
 				g.Printf(", ")
 			}
-			g.Printf("%s %s", paramName(params, i), g.typeString(params.At(i).Type()))
+			g.Printf("%s %s", paramName(params, i), g.paramType(sig, i))
 		}
 		g.Printf(") ")
 
-		if res.Len() == 1 {
-			g.Printf(g.typeString(res.At(0).Type()))
-		} else if res.Len() == 2 {
-			g.Printf("(%s, error)", g.typeString(res.At(0).Type()))
+		var resTypes []string
+		for i := 0; i < res.Len(); i++ {
+			if t := res.At(i).Type(); isErrorType(t) {
+				resTypes = append(resTypes, "error")
+			} else {
+				resTypes = append(resTypes, g.typeString(t))
+			}
+		}
+		if len(resTypes) == 1 {
+			g.Printf("%s", resTypes[0])
+		} else if len(resTypes) > 1 {
+			g.Printf("(%s)", strings.Join(resTypes, ", "))
 		}
 		g.Printf(" {\n")
 		g.Indent()
@@ -308,9 +501,9 @@ func (g *goGen) genInterface(obj *types.TypeName) {
 		}
 
 		if res.Len() == 0 {
-			g.Printf("seq.Transact((*seq.Ref)(p), proxy%s_%s_Code, in)\n", obj.Name(), m.Name())
+			g.Printf("seq.Transact((*seq.Ref)(p), %s_%s_Code, in)\n", proxy, m.Name())
 		} else {
-			g.Printf("out := seq.Transact((*seq.Ref)(p), proxy%s_%s_Code, in)\n", obj.Name(), m.Name())
+			g.Printf("out := seq.Transact((*seq.Ref)(p), %s_%s_Code, in)\n", proxy, m.Name())
 			var rvs []string
 			for i := 0; i < res.Len(); i++ {
 				rv := fmt.Sprintf("res_%d", i)
@@ -322,7 +515,46 @@ func (g *goGen) genInterface(obj *types.TypeName) {
 
 		g.Outdent()
 		g.Printf("}\n\n")
+
+		g.genAsyncMethod(proxy, iface, m)
+	}
+}
+
+// genAsyncMethod generates the MAsync method of the proxy for the
+// method M of iface, if M has no results other than an error. MAsync
+// calls M without waiting for it to complete, and passes the error it
+// returns to the handler set by seq.SetAsyncErrorHandler. Go callers
+// reach it through an interface holding the MAsync method.
+func (g *goGen) genAsyncMethod(proxy string, iface *types.Interface, m *types.Func) {
+	sig := m.Type().(*types.Signature)
+	params := sig.Params()
+	res := sig.Results()
+	if res.Len() > 1 || res.Len() == 1 && !isErrorType(res.At(0).Type()) {
+		return
+	}
+	name := m.Name() + "Async"
+	for i := 0; i < iface.NumMethods(); i++ {
+		if iface.Method(i).Name() == name {
+			return // M and MAsync are both methods of iface
+		}
+	}
+
+	g.Printf("func (p *%s) %s(", proxy, name)
+	for i := 0; i < params.Len(); i++ {
+		if i > 0 {
+			g.Printf(", ")
+		}
+		g.Printf("%s %s", paramName(params, i), g.paramType(sig, i))
 	}
+	g.Printf(") {\n")
+	g.Indent()
+	g.Printf("in := new(seq.Buffer)\n")
+	for i := 0; i < params.Len(); i++ {
+		g.genWrite(paramName(params, i), "in", params.At(i).Type())
+	}
+	g.Printf("seq.TransactAsync((*seq.Ref)(p), %s_%s_Code, in, %v)\n", proxy, m.Name(), res.Len() == 1)
+	g.Outdent()
+	g.Printf("}\n\n")
 }
 
 func (g *goGen) genRead(valName, seqName string, typ types.Type) {
@@ -332,16 +564,22 @@ func (g *goGen) genRead(valName, seqName string, typ types.Type) {
 	}
 	switch t := typ.(type) {
 	case *types.Pointer:
+		if isDirectBuffer(t) {
+			g.Printf("%s := %s.ReadDirectBuffer()\n", valName, seqName)
+			return
+		}
 		switch u := t.Elem().(type) {
 		case *types.Named:
 			o := u.Obj()
-			if o.Pkg() != g.pkg {
-				g.errorf("type %s not defined in package %s", u, g.pkg)
+			if !g.checkBound(o) {
 				return
 			}
-			g.Printf("// Must be a Go object\n")
+			g.Printf("// Must be a Go object, or null\n")
 			g.Printf("%s_ref := %s.ReadRef()\n", valName, seqName)
-			g.Printf("%s := %s_ref.Get().(*%s.%s)\n", valName, valName, g.pkg.Name(), o.Name())
+			g.Printf("var %s *%s\n", valName, g.qualifiedName(o))
+			g.Printf("if %s_ref.Num != 0 {\n", valName)
+			g.Printf("	%s = %s_ref.Get().(*%s)\n", valName, valName, g.qualifiedName(o))
+			g.Printf("}\n")
 		default:
 			g.errorf("unsupported type %s", t)
 		}
@@ -349,23 +587,82 @@ func (g *goGen) genRead(valName, seqName string, typ types.Type) {
 		switch t.Underlying().(type) {
 		case *types.Interface, *types.Pointer:
 			o := t.Obj()
-			if o.Pkg() != g.pkg {
-				g.errorf("type %s not defined in package %s", t, g.pkg)
+			if !g.checkBound(o) {
 				return
 			}
 			g.Printf("var %s %s\n", valName, g.typeString(t))
 			g.Printf("%s_ref := %s.ReadRef()\n", valName, seqName)
 			g.Printf("if %s_ref.Num < 0 { // go object \n", valName)
-			g.Printf("   %s = %s_ref.Get().(%s.%s)\n", valName, valName, g.pkg.Name(), o.Name())
-			g.Printf("} else {  // foreign object \n")
-			g.Printf("   %s = (*proxy%s)(%s_ref)\n", valName, o.Name(), valName)
+			g.Printf("   %s = %s_ref.Get().(%s)\n", valName, valName, g.qualifiedName(o))
+			g.Printf("} else if %s_ref.Num > 0 { // foreign object, not null\n", valName)
+			g.Printf("   %s = (*%s)(%s_ref)\n", valName, g.proxyName(o), valName)
+			g.Printf("}\n")
+		case *types.Struct:
+			o := t.Obj()
+			if !g.checkBound(o) {
+				return
+			}
+			g.Printf("// Must be a Go object, copied, or null for the zero value\n")
+			g.Printf("%s_ref := %s.ReadRef()\n", valName, seqName)
+			g.Printf("var %s %s\n", valName, g.qualifiedName(o))
+			g.Printf("if %s_ref.Num != 0 {\n", valName)
+			g.Printf("	%s = *%s_ref.Get().(*%s)\n", valName, valName, g.qualifiedName(o))
 			g.Printf("}\n")
 		}
+	case *types.Slice:
+		g.genReadSlice(valName, seqName, t)
+	case *types.Map:
+		g.Printf("%s_len := %s.ReadArrayLen()\n", valName, seqName)
+		g.Printf("%s := make(%s, %s_len)\n", valName, g.typeString(t), valName)
+		g.Printf("for i := 0; i < %s_len; i++ {\n", valName)
+		g.Indent()
+		g.genRead(valName+"_key", seqName, t.Key())
+		g.genRead(valName+"_val", seqName, t.Elem())
+		g.Printf("%s[%s_key] = %s_val\n", valName, valName, valName)
+		g.Outdent()
+		g.Printf("}\n")
+	case *types.Chan:
+		g.errorf("channels are only supported as results of Go functions and methods: %s", t)
+	case *types.Array:
+		// Read as a slice of the length of the array, then copy
+		// into the array.
+		sliceName := valName + "_slice"
+		g.genReadSlice(sliceName, seqName, types.NewSlice(t.Elem()))
+		g.Printf("seq.CheckArrayLen(len(%s), %d)\n", sliceName, t.Len())
+		g.Printf("var %s %s\n", valName, g.typeString(t))
+		g.Printf("copy(%s[:], %s)\n", valName, sliceName)
 	default:
 		g.Printf("%s := %s.Read%s()\n", valName, seqName, seqType(t))
 	}
 }
 
+func (g *goGen) genReadSlice(valName, seqName string, t *types.Slice) {
+	seqTyp := seqType(t)
+	if seqTyp != "RefArray" {
+		g.Printf("%s := %s.Read%s()\n", valName, seqName, seqTyp)
+		return
+	}
+	elemName := valName + "_elem"
+	g.Printf("%s := make(%s, %s.ReadArrayLen())\n", valName, g.typeString(t), seqName)
+	g.Printf("for i := range %s {\n", valName)
+	g.Indent()
+	g.genRead(elemName, seqName, t.Elem())
+	g.Printf("%s[i] = %s\n", valName, elemName)
+	g.Outdent()
+	g.Printf("}\n")
+}
+
+// paramType returns the type of the i-th parameter of sig as it is
+// written in a parameter list: the last parameter of a variadic
+// function has the type ...T.
+func (g *goGen) paramType(sig *types.Signature, i int) string {
+	t := sig.Params().At(i).Type()
+	if sig.Variadic() && i == sig.Params().Len()-1 {
+		return "..." + g.typeString(t.(*types.Slice).Elem())
+	}
+	return g.typeString(t)
+}
+
 func (g *goGen) typeString(typ types.Type) string {
 	pkg := g.pkg
 
@@ -375,23 +672,34 @@ func (g *goGen) typeString(typ types.Type) string {
 		if obj.Pkg() == nil { // e.g. error type is *types.Named.
 			return types.TypeString(typ, types.RelativeTo(pkg))
 		}
-		if obj.Pkg() != g.pkg {
-			g.errorf("type %s not defined in package %s", t, g.pkg)
+		if !g.checkBound(obj) {
+			return "TODO"
 		}
 
 		switch t.Underlying().(type) {
 		case *types.Interface, *types.Struct:
-			return fmt.Sprintf("%s.%s", pkg.Name(), types.TypeString(typ, types.RelativeTo(pkg)))
+			return g.qualifiedName(obj)
 		default:
 			g.errorf("unsupported named type %s / %T", t, t)
 		}
 	case *types.Pointer:
+		if isDirectBuffer(t) {
+			return "*seq.DirectBuffer"
+		}
 		switch t := t.Elem().(type) {
 		case *types.Named:
 			return fmt.Sprintf("*%s", g.typeString(t))
 		default:
 			g.errorf("not yet supported, pointer type %s / %T", t, t)
 		}
+	case *types.Slice:
+		return "[]" + g.typeString(t.Elem())
+	case *types.Array:
+		return fmt.Sprintf("[%d]%s", t.Len(), g.typeString(t.Elem()))
+	case *types.Map:
+		return fmt.Sprintf("map[%s]%s", g.typeString(t.Key()), g.typeString(t.Elem()))
+	case *types.Chan:
+		return "<-chan " + g.typeString(t.Elem())
 	default:
 		return types.TypeString(typ, types.RelativeTo(pkg))
 	}
@@ -399,7 +707,8 @@ func (g *goGen) typeString(typ types.Type) string {
 }
 
 func (g *goGen) gen() error {
-	g.genPreamble()
+	g.imports = make(map[string]*types.Package)
+	g.proxies = nil
 
 	var funcs []string
 
@@ -412,8 +721,10 @@ func (g *goGen) gen() error {
 		}
 
 		switch obj := obj.(type) {
-		// TODO(crawshaw): case *types.Const:
-		// TODO(crawshaw): case *types.Var:
+		case *types.Const:
+			// Constant values are generated in the target language.
+		case *types.Var:
+			g.genVar(obj)
 		case *types.Func:
 			g.genFunc(obj)
 			funcs = append(funcs, obj.Name())
@@ -435,11 +746,23 @@ func (g *goGen) gen() error {
 	g.Printf("func init() {\n")
 	g.Indent()
 	for i, name := range funcs {
-		g.Printf("seq.Register(%q, %d, proxy_%s)\n", g.pkg.Name(), i+1, name)
+		g.Printf("seq.Register(%q, %d, proxy_%s)\n", seqDesc(g.pkg, ""), i+1, name)
 	}
 	g.Outdent()
 	g.Printf("}\n")
 
+	// Proxies may use the interfaces of more packages.
+	for i := 0; i < len(g.proxies); i++ {
+		g.Printf("\n")
+		g.genInterfaceProxy(g.proxies[i])
+	}
+
+	// The preamble imports the packages used by the stub.
+	body := append([]byte(nil), g.buf.Bytes()...)
+	g.buf.Reset()
+	g.genPreamble()
+	g.buf.Write(body)
+
 	if len(g.err) > 0 {
 		return g.err
 	}
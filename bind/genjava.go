@@ -9,16 +9,18 @@ import (
 	"fmt"
 	"go/token"
 	"io"
+	"math"
 	"regexp"
+	"strconv"
+	"strings"
 	"unicode"
-	"unicode/utf8"
+	"unicode/utf16"
 
+	"golang.org/x/tools/go/exact"
 	"golang.org/x/tools/go/types"
 )
 
 // TODO(crawshaw): disallow basic android java type names in exported symbols.
-// TODO(crawshaw): generate all relevant "implements" relationships for interfaces.
-// TODO(crawshaw): consider introducing Java functions for casting to and from interfaces at runtime.
 
 type ErrorList []error
 
@@ -35,18 +37,35 @@ func (list ErrorList) Error() string {
 
 type javaGen struct {
 	*printer
-	fset *token.FileSet
-	pkg  *types.Package
-	err  ErrorList
+	fset    *token.FileSet
+	pkg     *types.Package
+	allPkg  []*types.Package
+	javaPkg string // prefix of the Java packages of bound packages
+	err     ErrorList
+
+	errTypes []*types.TypeName // exported types implementing error
+	ifaces   []*types.TypeName // interfaces Java classes can implement
+	docs     pkgDocs
+}
+
+// genDoc generates the Javadoc of the declaration key of pkgDocs.
+// Java keeps the Go names.
+func (g *javaGen) genDoc(key string) {
+	g.docs.genDoc(g.printer, key, g.pkg, nil)
 }
 
 func (g *javaGen) genStruct(obj *types.TypeName, T *types.Struct) {
 	fields := exportedFields(T)
 	methods := exportedMethodSet(types.NewPointer(obj.Type()))
 
-	g.Printf("public static final class %s implements go.Seq.Object {\n", obj.Name())
+	g.genDoc(obj.Name())
+	g.Printf("public static final class %s implements go.Seq.Object", obj.Name())
+	for _, iface := range implementedIfaces(obj, g.ifaces) {
+		g.Printf(", %s", g.javaType(iface.Type()))
+	}
+	g.Printf(" {\n")
 	g.Indent()
-	g.Printf("private static final String DESCRIPTOR = \"go.%s.%s\";\n", g.pkg.Name(), obj.Name())
+	g.Printf("private static final String DESCRIPTOR = %q;\n", seqDesc(g.pkg, obj.Name()))
 	for i, f := range fields {
 		g.Printf("private static final int FIELD_%s_GET = 0x%x0f;\n", f.Name(), i)
 		g.Printf("private static final int FIELD_%s_SET = 0x%x1f;\n", f.Name(), i)
@@ -59,7 +78,7 @@ func (g *javaGen) genStruct(obj *types.TypeName, T *types.Struct) {
 	g.Printf("private go.Seq.Ref ref;\n\n")
 
 	n := obj.Name()
-	g.Printf("private %s(go.Seq.Ref ref) { this.ref = ref; }\n\n", n)
+	g.Printf("%s(go.Seq.Ref ref) { this.ref = ref; }\n\n", n)
 	g.Printf(`public go.Seq.Ref ref() { return ref; }
 
 public void call(int code, go.Seq in, go.Seq out) {
@@ -69,32 +88,39 @@ public void call(int code, go.Seq in, go.Seq out) {
 `)
 
 	for _, f := range fields {
+		g.genDoc(obj.Name() + "." + f.Name())
 		g.Printf("public %s get%s() {\n", g.javaType(f.Type()), f.Name())
 		g.Indent()
 		g.Printf("Seq in = new Seq();\n")
 		g.Printf("Seq out = new Seq();\n")
 		g.Printf("in.writeRef(ref);\n")
 		g.Printf("Seq.send(DESCRIPTOR, FIELD_%s_GET, in, out);\n", f.Name())
-		if seqType(f.Type()) == "Ref" {
-			g.Printf("return new %s(out.read%s);\n", g.javaType(f.Type()), seqRead(f.Type()))
-		} else {
+		switch seqType(f.Type()) {
+		case "Ref", "RefArray", "Map":
+			g.Printf("%s v;\n", g.javaType(f.Type()))
+			g.genRead("v", "out", f.Type())
+			g.Printf("return v;\n")
+		default:
 			g.Printf("return out.read%s;\n", seqRead(f.Type()))
 		}
 		g.Outdent()
 		g.Printf("}\n\n")
 
+		g.genDoc(obj.Name() + "." + f.Name())
 		g.Printf("public void set%s(%s v) {\n", f.Name(), g.javaType(f.Type()))
 		g.Indent()
 		g.Printf("Seq in = new Seq();\n")
 		g.Printf("Seq out = new Seq();\n")
 		g.Printf("in.writeRef(ref);\n")
-		g.Printf("in.write%s;\n", seqWrite(f.Type(), "v"))
+		g.genWrite("v", "in", f.Type())
 		g.Printf("Seq.send(DESCRIPTOR, FIELD_%s_SET, in, out);\n", f.Name())
 		g.Outdent()
 		g.Printf("}\n\n")
 	}
 
 	for _, m := range methods {
+		g.genResultClass(m)
+		g.genDoc(docKey(m))
 		g.genFunc(m, true)
 	}
 
@@ -108,6 +134,8 @@ public void call(int code, go.Seq in, go.Seq out) {
 		g.Printf("%s that%s = that.get%s();\n", g.javaType(f.Type()), nf, nf)
 		if isJavaPrimitive(f.Type()) {
 			g.Printf("if (this%s != that%s) {\n    return false;\n}\n", nf, nf)
+		} else if arrayElem(f.Type()) != nil {
+			g.Printf("if (!java.util.Arrays.equals(this%s, that%s)) {\n    return false;\n}\n", nf, nf)
 		} else {
 			g.Printf("if (this%s == null) {\n", nf)
 			g.Indent()
@@ -121,7 +149,7 @@ public void call(int code, go.Seq in, go.Seq out) {
 	g.Printf("}\n\n")
 
 	g.Printf("@Override public int hashCode() {\n")
-	g.Printf("    return java.util.Arrays.hashCode(new Object[] {")
+	g.Printf("    return java.util.Arrays.deepHashCode(new Object[] {")
 	for i, f := range fields {
 		if i > 0 {
 			g.Printf(", ")
@@ -155,7 +183,7 @@ func (g *javaGen) genInterfaceStub(o *types.TypeName, m *types.Interface) {
 	g.Printf("public static abstract class Stub implements %s {\n", o.Name())
 	g.Indent()
 
-	g.Printf("static final String DESCRIPTOR = \"go.%s.%s\";\n\n", g.pkg.Name(), o.Name())
+	g.Printf("static final String DESCRIPTOR = %q;\n\n", seqDesc(g.pkg, o.Name()))
 	g.Printf("private final go.Seq.Ref ref;\n")
 	g.Printf("public Stub() {\n    ref = go.Seq.createRef(this);\n}\n\n")
 	g.Printf("public go.Seq.Ref ref() { return ref; }\n\n")
@@ -179,21 +207,19 @@ func (g *javaGen) genInterfaceStub(o *types.TypeName, m *types.Interface) {
 		}
 
 		res := sig.Results()
-		var returnsError bool
-		var numRes = res.Len()
-		if (res.Len() == 1 && isErrorType(res.At(0).Type())) ||
-			(res.Len() == 2 && isErrorType(res.At(1).Type())) {
-			numRes -= 1
-			returnsError = true
-		}
+		numRes := numValues(sig)
+		returnsErr := returnsError(sig)
 
-		if returnsError {
+		if returnsErr {
 			g.Printf("try {\n")
 			g.Indent()
 		}
 
-		if numRes > 0 {
+		switch {
+		case numRes == 1:
 			g.Printf("%s result = ", g.javaType(res.At(0).Type()))
+		case numRes > 1:
+			g.Printf("%sResult result = ", f.Name())
 		}
 
 		g.Printf("this.%s(", f.Name())
@@ -205,20 +231,29 @@ func (g *javaGen) genInterfaceStub(o *types.TypeName, m *types.Interface) {
 		}
 		g.Printf(");\n")
 
-		if numRes > 0 {
-			g.Printf("out.write%s;\n", seqWrite(res.At(0).Type(), "result"))
+		switch {
+		case numRes == 1:
+			g.genWrite("result", "out", res.At(0).Type())
+		case numRes > 1:
+			for i := 0; i < numRes; i++ {
+				g.genWrite("result."+resultName(res, i), "out", res.At(i).Type())
+			}
 		}
-		if returnsError {
-			g.Printf("out.writeString(null);\n")
+		if returnsErr {
+			g.Printf("out.writeError(null);\n")
 			g.Outdent()
 			g.Printf("} catch (Exception e) {\n")
 			g.Indent()
-			if numRes > 0 {
-				resTyp := res.At(0).Type()
-				g.Printf("%s result = %s;\n", g.javaType(resTyp), g.javaTypeDefault(resTyp))
-				g.Printf("out.write%s;\n", seqWrite(resTyp, "result"))
+			for i := 0; i < numRes; i++ {
+				resTyp := res.At(i).Type()
+				name := "result"
+				if numRes > 1 {
+					name = "result_" + resultName(res, i)
+				}
+				g.Printf("%s %s = %s;\n", g.javaType(resTyp), name, g.javaTypeDefault(resTyp))
+				g.genWrite(name, "out", resTyp)
 			}
-			g.Printf("out.writeString(e.getMessage());\n")
+			g.Printf("out.writeError(e);\n")
 			g.Outdent()
 			g.Printf("}\n")
 		}
@@ -251,22 +286,33 @@ const javaProxyPreamble = `static final class Proxy implements %s {
 
 `
 
-func (g *javaGen) genInterface(o *types.TypeName) {
+// genInterface generates the Java interface of o, and reports
+// whether its Stub and Proxy classes could be generated.
+func (g *javaGen) genInterface(o *types.TypeName) bool {
 	iface := o.Type().(*types.Named).Underlying().(*types.Interface)
 
+	g.genDoc(o.Name())
 	g.Printf("public interface %s extends go.Seq.Object {\n", o.Name())
 	g.Indent()
 
 	methodSigErr := false
 	for i := 0; i < iface.NumMethods(); i++ {
-		if err := g.funcSignature(iface.Method(i), false); err != nil {
+		m := iface.Method(i)
+		if returnsChan(m) {
+			methodSigErr = true
+			g.errorf("channels cannot be returned by interface methods: %s", m)
+			continue
+		}
+		g.genResultClass(m)
+		g.genDoc(o.Name() + "." + m.Name())
+		if err := g.funcSignature(m, false); err != nil {
 			methodSigErr = true
 			g.errorf("%v", err)
 		}
 		g.Printf(";\n\n")
 	}
 	if methodSigErr {
-		return // skip stub generation, more of the same errors
+		return false // skip stub generation, more of the same errors
 	}
 
 	g.genInterfaceStub(o, iface)
@@ -286,12 +332,171 @@ func (g *javaGen) genInterface(o *types.TypeName) {
 
 	g.Outdent()
 	g.Printf("}\n\n")
+	return true
+}
+
+// javaImplementable reports whether the generated Java classes of the
+// structs implementing the interface o can implement its Java interface.
+// The methods of both must have the same Java signatures, which is not
+// the case of methods returning several values, each returning their
+// own result class.
+func javaImplementable(o *types.TypeName) bool {
+	iface := o.Type().Underlying().(*types.Interface)
+	for i := 0; i < iface.NumMethods(); i++ {
+		m := iface.Method(i)
+		if returnsChan(m) || numValues(m.Type().(*types.Signature)) > 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// genCast generates the checked cast of a go.Seq.Object to the class
+// or interface of o, returning null if the object has another type.
+// It asks Go whether a Go object has the type of o.
+func (g *javaGen) genCast(o *types.TypeName, desc, newProxy string) {
+	g.Printf("public static %s cast%s(go.Seq.Object o) {\n", o.Name(), o.Name())
+	g.Indent()
+	g.Printf("if (o == null || o instanceof %s) {\n", o.Name())
+	g.Printf("    return (%s)o;\n", o.Name())
+	g.Printf("}\n")
+	g.Printf("if (!go.Seq.canCast(%s, o)) {\n", desc)
+	g.Printf("    return null;\n")
+	g.Printf("}\n")
+	g.Printf("return %s(o.ref());\n", newProxy)
+	g.Outdent()
+	g.Printf("}\n\n")
 }
 
 func isErrorType(T types.Type) bool {
 	return T == types.Universe.Lookup("error").Type()
 }
 
+// boundIfaces returns the exported interface types that classes of the
+// foreign languages can implement, those with methods, all of them
+// exported, of pkg and of the packages allPkg bound with it that pkg
+// imports. The Objective-C headers of the other packages may include
+// that of pkg, so Java and Objective-C both leave them out.
+func boundIfaces(pkg *types.Package, allPkg []*types.Package) []*types.TypeName {
+	pkgs := append([]*types.Package{pkg}, boundImports(pkg, allPkg)...)
+	var ifaces []*types.TypeName
+	for _, p := range pkgs {
+		scope := p.Scope()
+	names:
+		for _, name := range scope.Names() {
+			o, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || !o.Exported() {
+				continue
+			}
+			iface, ok := o.Type().Underlying().(*types.Interface)
+			if !ok || iface.NumMethods() == 0 {
+				continue
+			}
+			for i := 0; i < iface.NumMethods(); i++ {
+				if !iface.Method(i).Exported() {
+					continue names
+				}
+			}
+			ifaces = append(ifaces, o)
+		}
+	}
+	return ifaces
+}
+
+// implementedIfaces returns the interfaces among ifaces implemented
+// by a pointer to the named type of obj.
+func implementedIfaces(obj *types.TypeName, ifaces []*types.TypeName) []*types.TypeName {
+	var impl []*types.TypeName
+	T := types.NewPointer(obj.Type())
+	for _, o := range ifaces {
+		if types.Implements(T, o.Type().Underlying().(*types.Interface)) {
+			impl = append(impl, o)
+		}
+	}
+	return impl
+}
+
+// implementsError reports whether the named type of obj, or a
+// pointer to it, implements error. Interface types do not.
+func implementsError(obj *types.TypeName) bool {
+	if _, ok := obj.Type().Underlying().(*types.Interface); ok {
+		return false
+	}
+	errIface := types.Universe.Lookup("error").Type().Underlying().(*types.Interface)
+	return types.Implements(obj.Type(), errIface) ||
+		types.Implements(types.NewPointer(obj.Type()), errIface)
+}
+
+// errorTypeName returns the name of the error type of obj on the
+// wire, as written by seq.Buffer.WriteError.
+func errorTypeName(obj *types.TypeName) string {
+	return obj.Pkg().Path() + "." + obj.Name()
+}
+
+// checkResults reports an error if a result of o other than
+// the last has type error.
+func checkResults(o *types.Func) error {
+	res := o.Type().(*types.Signature).Results()
+	for i := 0; i < res.Len()-1; i++ {
+		if isErrorType(res.At(i).Type()) {
+			return fmt.Errorf("only the last result value may be of type error: %s", o)
+		}
+	}
+	return nil
+}
+
+// checkResultClass reports an error if the Java class of the results
+// of o, a function with more than one result value, has the name of
+// an exported type of its package, whose class it would collide with.
+func checkResultClass(o *types.Func) error {
+	if numValues(o.Type().(*types.Signature)) < 2 {
+		return nil
+	}
+	name := o.Name() + "Result"
+	if t, ok := o.Pkg().Scope().Lookup(name).(*types.TypeName); ok && t.Exported() {
+		return fmt.Errorf("the Java class %s of the results of %s has the name of a type", name, o.Name())
+	}
+	return nil
+}
+
+// checkParams reports an error if a parameter of o is a channel.
+func checkParams(o *types.Func) error {
+	params := o.Type().(*types.Signature).Params()
+	for i := 0; i < params.Len(); i++ {
+		if _, ok := params.At(i).Type().(*types.Chan); ok {
+			return fmt.Errorf("channels are only supported as results of Go functions and methods: %s", o)
+		}
+	}
+	return nil
+}
+
+// returnsChan reports whether a result of o is a channel.
+func returnsChan(o *types.Func) bool {
+	res := o.Type().(*types.Signature).Results()
+	for i := 0; i < res.Len(); i++ {
+		if _, ok := res.At(i).Type().(*types.Chan); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// returnsError reports whether the last result of sig is an error.
+func returnsError(sig *types.Signature) bool {
+	res := sig.Results()
+	return res.Len() > 0 && isErrorType(res.At(res.Len()-1).Type())
+}
+
+// numValues returns the number of results of sig that are not
+// the trailing error.
+func numValues(sig *types.Signature) int {
+	n := sig.Results().Len()
+	if returnsError(sig) {
+		n--
+	}
+	return n
+}
+
 func isJavaPrimitive(T types.Type) bool {
 	b, ok := T.(*types.Basic)
 	if !ok {
@@ -299,7 +504,8 @@ func isJavaPrimitive(T types.Type) bool {
 	}
 	switch b.Kind() {
 	case types.Bool, types.Uint8, types.Float32, types.Float64,
-		types.Int, types.Int8, types.Int16, types.Int32, types.Int64:
+		types.Int, types.Int8, types.Int16, types.Int32, types.Int64,
+		types.Uint, types.Uint16, types.Uint32, types.Uint64:
 		return true
 	}
 	return false
@@ -326,7 +532,14 @@ func (g *javaGen) javaType(T types.Type) string {
 			// TODO(crawshaw): Java bytes are signed, so this is
 			// questionable, but vital.
 			return "byte"
-		// TODO(crawshaw): case types.Uint, types.Uint16, types.Uint32, types.Uint64:
+		case types.Uint16:
+			// Widened, Java has no unsigned types.
+			return "int"
+		case types.Uint32:
+			return "long"
+		case types.Uint64, types.Uint:
+			// Values above 1<<63-1 appear negative in Java.
+			return "long"
 		case types.Float32:
 			return "float"
 		case types.Float64:
@@ -340,16 +553,32 @@ func (g *javaGen) javaType(T types.Type) string {
 	case *types.Slice:
 		elem := g.javaType(T.Elem())
 		return elem + "[]"
+	case *types.Array:
+		elem := g.javaType(T.Elem())
+		return elem + "[]"
+	case *types.Map:
+		return "java.util.Map<" + g.javaBoxedType(T.Key()) + ", " + g.javaBoxedType(T.Elem()) + ">"
+	case *types.Chan:
+		return "go.Seq.Stream<" + g.javaBoxedType(T.Elem()) + ">"
 
 	case *types.Pointer:
+		if isDirectBuffer(T) {
+			return "java.nio.ByteBuffer"
+		}
 		if _, ok := T.Elem().(*types.Named); ok {
 			return g.javaType(T.Elem())
 		}
 		panic(fmt.Sprintf("unsupporter pointer to type: %s", T))
 	case *types.Named:
 		n := T.Obj()
-		if n.Pkg() != g.pkg {
-			panic(fmt.Sprintf("type %s is in package %s, must be defined in package %s", n.Name(), n.Pkg().Name(), g.pkg.Name()))
+		if !isBound(n.Pkg(), g.pkg, g.allPkg) {
+			g.errorf("type %s not defined in package %s or a package bound with it", T, g.pkg.Path())
+			return "TODO"
+		}
+		if n.Pkg().Path() != g.pkg.Path() {
+			// Types of other bound packages are nested in
+			// the class of their package.
+			return g.javaPkgName(n.Pkg()) + "." + capitalize(n.Pkg().Name()) + "." + n.Name()
 		}
 		// TODO(crawshaw): more checking here
 		return n.Name()
@@ -359,6 +588,28 @@ func (g *javaGen) javaType(T types.Type) string {
 	}
 }
 
+// javaBoxedType returns the Java type of T usable as a type argument.
+func (g *javaGen) javaBoxedType(T types.Type) string {
+	switch t := g.javaType(T); t {
+	case "boolean":
+		return "Boolean"
+	case "byte":
+		return "Byte"
+	case "short":
+		return "Short"
+	case "int":
+		return "Integer"
+	case "long":
+		return "Long"
+	case "float":
+		return "Float"
+	case "double":
+		return "Double"
+	default:
+		return t
+	}
+}
+
 // javaTypeDefault returns a string that represents the default value of the mapped java type.
 // TODO(hyangah): Combine javaType and javaTypeDefault?
 func (g *javaGen) javaTypeDefault(T types.Type) string {
@@ -368,7 +619,8 @@ func (g *javaGen) javaTypeDefault(T types.Type) string {
 		case types.Bool:
 			return "false"
 		case types.Int, types.Int8, types.Int16, types.Int32,
-			types.Int64, types.Uint8, types.Float32, types.Float64:
+			types.Int64, types.Uint8, types.Float32, types.Float64,
+			types.Uint, types.Uint16, types.Uint32, types.Uint64:
 			return "0"
 		case types.String:
 			return "null"
@@ -376,7 +628,7 @@ func (g *javaGen) javaTypeDefault(T types.Type) string {
 			g.errorf("unsupported return type: %s", T)
 			return "TODO"
 		}
-	case *types.Slice, *types.Pointer, *types.Named:
+	case *types.Slice, *types.Array, *types.Pointer, *types.Named, *types.Chan, *types.Map:
 		return "null"
 
 	default:
@@ -398,30 +650,71 @@ func paramName(params *types.Tuple, pos int) string {
 	return name
 }
 
+// resultName returns the name of the field holding the i-th result
+// value in a result class.
+func resultName(res *types.Tuple, pos int) string {
+	name := res.At(pos).Name()
+	if name == "" || name == "_" || paramRE.MatchString(name) {
+		name = fmt.Sprintf("r%d", pos)
+	}
+	return name
+}
+
+// genResultClass generates the class returned in Java by a function
+// with more than one result value, if o is such a function.
+func (g *javaGen) genResultClass(o *types.Func) {
+	sig := o.Type().(*types.Signature)
+	if numValues(sig) < 2 || checkResults(o) != nil || checkResultClass(o) != nil {
+		return
+	}
+	res := sig.Results()
+	n := numValues(sig)
+
+	g.Printf("public static final class %sResult {\n", o.Name())
+	g.Indent()
+	for i := 0; i < n; i++ {
+		g.Printf("public final %s %s;\n", g.javaType(res.At(i).Type()), resultName(res, i))
+	}
+	g.Printf("\n")
+	g.Printf("public %sResult(", o.Name())
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			g.Printf(", ")
+		}
+		g.Printf("%s %s", g.javaType(res.At(i).Type()), resultName(res, i))
+	}
+	g.Printf(") {\n")
+	g.Indent()
+	for i := 0; i < n; i++ {
+		g.Printf("this.%s = %s;\n", resultName(res, i), resultName(res, i))
+	}
+	g.Outdent()
+	g.Printf("}\n")
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
 func (g *javaGen) funcSignature(o *types.Func, static bool) error {
+	if err := checkResults(o); err != nil {
+		return err
+	}
+	if err := checkResultClass(o); err != nil {
+		return err
+	}
+	if err := checkParams(o); err != nil {
+		return err
+	}
 	sig := o.Type().(*types.Signature)
 	res := sig.Results()
 
-	var returnsError bool
 	var ret string
-	switch res.Len() {
-	case 2:
-		if !isErrorType(res.At(1).Type()) {
-			return fmt.Errorf("second result value must be of type error: %s", o)
-		}
-		returnsError = true
-		ret = g.javaType(res.At(0).Type())
-	case 1:
-		if isErrorType(res.At(0).Type()) {
-			returnsError = true
-			ret = "void"
-		} else {
-			ret = g.javaType(res.At(0).Type())
-		}
+	switch numValues(sig) {
 	case 0:
 		ret = "void"
+	case 1:
+		ret = g.javaType(res.At(0).Type())
 	default:
-		return fmt.Errorf("too many result values: %s", o)
+		ret = o.Name() + "Result"
 	}
 
 	g.Printf("public ")
@@ -437,10 +730,14 @@ func (g *javaGen) funcSignature(o *types.Func, static bool) error {
 		v := sig.Params().At(i)
 		name := paramName(params, i)
 		jt := g.javaType(v.Type())
+		if sig.Variadic() && i == params.Len()-1 {
+			// Variadic Go functions are variadic in Java.
+			jt = strings.TrimSuffix(jt, "[]") + "..."
+		}
 		g.Printf("%s %s", jt, name)
 	}
 	g.Printf(")")
-	if returnsError {
+	if returnsError(sig) {
 		g.Printf(" throws Exception")
 	}
 	return nil
@@ -453,25 +750,20 @@ func (g *javaGen) genFunc(o *types.Func, method bool) {
 	}
 	sig := o.Type().(*types.Signature)
 	res := sig.Results()
+	nvals := numValues(sig)
 
 	g.Printf(" {\n")
 	g.Indent()
 	g.Printf("go.Seq _in = new go.Seq();\n")
 	g.Printf("go.Seq _out = new go.Seq();\n")
 
-	returnsError := false
-	var resultType types.Type
-	if res.Len() > 0 {
-		if !isErrorType(res.At(0).Type()) {
-			resultType = res.At(0).Type()
-		}
-		if res.Len() > 1 || isErrorType(res.At(0).Type()) {
-			returnsError = true
-		}
+	if nvals == 1 {
+		g.Printf("%s _result;\n", g.javaType(res.At(0).Type()))
 	}
-	if resultType != nil {
-		t := g.javaType(resultType)
-		g.Printf("%s _result;\n", t)
+	if nvals > 1 {
+		for i := 0; i < nvals; i++ {
+			g.Printf("%s _%s;\n", g.javaType(res.At(i).Type()), resultName(res, i))
+		}
 	}
 
 	if method {
@@ -480,85 +772,456 @@ func (g *javaGen) genFunc(o *types.Func, method bool) {
 	params := sig.Params()
 	for i := 0; i < params.Len(); i++ {
 		p := params.At(i)
-		g.Printf("_in.write%s;\n", seqWrite(p.Type(), paramName(params, i)))
+		g.genWrite(paramName(params, i), "_in", p.Type())
 	}
 	g.Printf("Seq.send(DESCRIPTOR, CALL_%s, _in, _out);\n", o.Name())
-	if resultType != nil {
-		g.genRead("_result", "_out", resultType)
+	if nvals == 1 {
+		g.genRead("_result", "_out", res.At(0).Type())
 	}
-	if returnsError {
-		g.Printf(`String _err = _out.readString();
-if (_err != null) {
-    throw new Exception(_err);
-}
-`)
+	if nvals > 1 {
+		for i := 0; i < nvals; i++ {
+			g.genRead("_"+resultName(res, i), "_out", res.At(i).Type())
+		}
+	}
+	if returnsError(sig) {
+		g.Printf("go.Seq.GoException _err = _out.readError();\n")
+		g.Printf("if (_err != null) {\n")
+		if len(g.errTypes) > 0 {
+			g.Printf("    throw typedError(_err);\n")
+		} else {
+			g.Printf("    throw _err;\n")
+		}
+		g.Printf("}\n")
 	}
-	if resultType != nil {
+	if nvals == 1 {
 		g.Printf("return _result;\n")
 	}
+	if nvals > 1 {
+		g.Printf("return new %sResult(", o.Name())
+		for i := 0; i < nvals; i++ {
+			if i > 0 {
+				g.Printf(", ")
+			}
+			g.Printf("_%s", resultName(res, i))
+		}
+		g.Printf(");\n")
+	}
 	g.Outdent()
 	g.Printf("}\n\n")
 }
 
+func (g *javaGen) genWrite(valName, seqName string, T types.Type) {
+	if a, ok := T.(*types.Array); ok {
+		// Go arrays have a fixed length, checked before the call.
+		g.Printf("if (%s == null || %s.length != %d) {\n", valName, valName, a.Len())
+		g.Printf("    throw new IllegalArgumentException(\"array length must be %d\");\n", a.Len())
+		g.Printf("}\n")
+	}
+	m, ok := T.(*types.Map)
+	if !ok {
+		g.Printf("%s.write%s;\n", seqName, seqWrite(T, valName))
+		return
+	}
+	// A null map is written as an empty map.
+	e := javaIdent(valName) + "_e"
+	g.Printf("%s.writeArrayLen(%s == null ? 0 : %s.size());\n", seqName, valName, valName)
+	g.Printf("if (%s != null) {\n", valName)
+	g.Indent()
+	g.Printf("for (java.util.Map.Entry<%s, %s> %s : %s.entrySet()) {\n",
+		g.javaBoxedType(m.Key()), g.javaBoxedType(m.Elem()), e, valName)
+	g.Indent()
+	g.genWrite(e+".getKey()", seqName, m.Key())
+	g.genWrite(e+".getValue()", seqName, m.Elem())
+	g.Outdent()
+	g.Printf("}\n")
+	g.Outdent()
+	g.Printf("}\n")
+}
+
+// javaIdent returns name with the characters that cannot appear
+// in a Java identifier replaced by underscores.
+func javaIdent(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
 func (g *javaGen) genRead(resName, seqName string, T types.Type) {
 	switch T := T.(type) {
 	case *types.Pointer:
+		if isDirectBuffer(T) {
+			g.Printf("%s = %s.readDirectBuffer();\n", resName, seqName)
+			return
+		}
 		// TODO(crawshaw): test *int
 		// TODO(crawshaw): test **Generator
 		switch T := T.Elem().(type) {
 		case *types.Named:
-			o := T.Obj()
-			if o.Pkg() != g.pkg {
-				g.errorf("type %s not defined in package %s", T, g.pkg)
-				return
-			}
-			g.Printf("%s = new %s(%s.readRef());\n", resName, o.Name(), seqName)
+			g.genNew(resName, seqName, T, "")
 		default:
 			g.errorf("unsupported type %s", T)
 		}
 	case *types.Named:
 		switch T.Underlying().(type) {
 		case *types.Interface, *types.Pointer:
-			o := T.Obj()
-			if o.Pkg() != g.pkg {
-				g.errorf("type %s not defined in package %s", T, g.pkg)
-				return
-			}
-			g.Printf("%s = new %s.Proxy(%s.readRef());\n", resName, o.Name(), seqName)
+			g.genNew(resName, seqName, T, "Proxy")
+		case *types.Struct:
+			g.genNew(resName, seqName, T, "")
 		default:
 			g.errorf("unsupported, direct named type %s", T)
 		}
+	case *types.Slice, *types.Array:
+		if seqType(T) != "RefArray" {
+			g.Printf("%s = %s.read%s();\n", resName, seqName, seqType(T))
+			return
+		}
+		elem := arrayElem(T)
+		g.Printf("int %s_len = %s.readArrayLen();\n", resName, seqName)
+		g.Printf("%s = new %s[%s_len];\n", resName, g.javaType(elem), resName)
+		g.Printf("for (int %s_i = 0; %s_i < %s_len; %s_i++) {\n", resName, resName, resName, resName)
+		g.Indent()
+		g.genRead(resName+"["+resName+"_i]", seqName, elem)
+		g.Outdent()
+		g.Printf("}\n")
+	case *types.Map:
+		// Maps are never read as null.
+		n := javaIdent(resName)
+		g.Printf("int %s_len = %s.readArrayLen();\n", n, seqName)
+		g.Printf("%s = new java.util.HashMap<%s, %s>(%s_len);\n",
+			resName, g.javaBoxedType(T.Key()), g.javaBoxedType(T.Elem()), n)
+		g.Printf("for (int %s_i = 0; %s_i < %s_len; %s_i++) {\n", n, n, n, n)
+		g.Indent()
+		g.Printf("%s %s_key;\n", g.javaType(T.Key()), n)
+		g.genRead(n+"_key", seqName, T.Key())
+		g.Printf("%s %s_val;\n", g.javaType(T.Elem()), n)
+		g.genRead(n+"_val", seqName, T.Elem())
+		g.Printf("%s.put(%s_key, %s_val);\n", resName, n, n)
+		g.Outdent()
+		g.Printf("}\n")
+	case *types.Chan:
+		elem := g.javaBoxedType(T.Elem())
+		g.Printf("%s = new go.Seq.Stream<%s>(%s.readRef()) {\n", resName, elem, seqName)
+		g.Indent()
+		g.Printf("protected %s read(go.Seq in) {\n", elem)
+		g.Indent()
+		g.Printf("%s v;\n", g.javaType(T.Elem()))
+		g.genRead("v", "in", T.Elem())
+		g.Printf("return v;\n")
+		g.Outdent()
+		g.Printf("}\n")
+		g.Outdent()
+		g.Printf("};\n")
 	default:
 		g.Printf("%s = %s.read%s();\n", resName, seqName, seqType(T))
 	}
 }
 
+// genNew generates the creation of the object of the struct class of T,
+// or of its nested class, such as Proxy, from the Ref read from seqName.
+// The constructors are not public, so the classes of the other bound
+// packages are created by go.Seq.newObject.
+func (g *javaGen) genNew(resName, seqName string, T *types.Named, nested string) {
+	cls := g.javaType(T)
+	if nested != "" {
+		cls += "." + nested
+	}
+	pkg := T.Obj().Pkg()
+	if pkg.Path() == g.pkg.Path() {
+		g.Printf("%s = new %s(%s.readRef());\n", resName, cls, seqName)
+		return
+	}
+	name := g.javaPkgName(pkg) + "." + capitalize(pkg.Name()) + "$" + T.Obj().Name()
+	if nested != "" {
+		name += "$" + nested
+	}
+	g.Printf("%s = (%s) go.Seq.newObject(%q, %s.readRef());\n", resName, g.javaType(T), name, seqName)
+}
+
+// genErrorClass generates the exception class standing for an
+// error type in Java.
+func (g *javaGen) genErrorClass(o *types.TypeName) {
+	n := o.Name() + "Exception"
+	g.genDoc(o.Name())
+	g.Printf("public static final class %s extends go.Seq.GoException {\n", n)
+	g.Indent()
+	g.Printf("public %s(String message) {\n    this(message, 0);\n}\n\n", n)
+	g.Printf("public %s(String message, long code) {\n    super(message, code, %q);\n}\n", n, errorTypeName(o))
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
+// genTypedError generates the method replacing exceptions read
+// from Go by the exception class of their error type.
+func (g *javaGen) genTypedError() {
+	g.Printf("private static go.Seq.GoException typedError(go.Seq.GoException e) {\n")
+	g.Indent()
+	g.Printf("String type = e.getType();\n")
+	for _, o := range g.errTypes {
+		g.Printf("if (type.equals(%q)) {\n", errorTypeName(o))
+		g.Printf("    return new %sException(e.getMessage(), e.getCode());\n", o.Name())
+		g.Printf("}\n")
+	}
+	g.Printf("return e;\n")
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
+func (g *javaGen) genConst(o *types.Const) {
+	t := constType(o)
+	if t == nil {
+		g.errorf("%s: unsupported constant type %s", o.Name(), o.Type())
+		return
+	}
+	if err := checkConstValue(o, t); err != nil {
+		g.errorf("%s: %v", o.Name(), err)
+		return
+	}
+	var val string
+	switch t.Kind() {
+	case types.Bool:
+		val = fmt.Sprint(exact.BoolVal(o.Val()))
+	case types.String:
+		val = javaString(exact.StringVal(o.Val()))
+	case types.Float32:
+		f, _ := exact.Float32Val(o.Val())
+		val = strconv.FormatFloat(float64(f), 'g', -1, 32) + "f"
+	case types.Float64:
+		f, _ := exact.Float64Val(o.Val())
+		val = strconv.FormatFloat(f, 'g', -1, 64)
+	default:
+		// Integers keep their bits: unsigned values that do
+		// not fit the Java type appear negative.
+		var v int64
+		if i, ok := exact.Int64Val(o.Val()); ok {
+			v = i
+		} else {
+			u, _ := exact.Uint64Val(o.Val())
+			v = int64(u)
+		}
+		switch jt := g.javaType(t); jt {
+		case "byte":
+			val = fmt.Sprint(int8(v))
+		case "short":
+			val = fmt.Sprint(int16(v))
+		case "int":
+			val = fmt.Sprint(int32(v))
+		default:
+			val = fmt.Sprint(v) + "L"
+		}
+	}
+	g.genDoc(o.Name())
+	g.Printf("public static final %s %s = %s;\n\n", g.javaType(t), o.Name(), val)
+}
+
+// genVar generates a static getter and setter for a package variable.
+func (g *javaGen) genVar(o *types.Var) {
+	if isErrorType(o.Type()) {
+		g.errorf("%s: variables of type error are not supported", o.Name())
+		return
+	}
+	jt := g.javaType(o.Type())
+	desc := seqDesc(g.pkg, o.Name())
+
+	g.genDoc(o.Name())
+	g.Printf("public static %s get%s() {\n", jt, o.Name())
+	g.Indent()
+	g.Printf("Seq in = new Seq();\n")
+	g.Printf("Seq out = new Seq();\n")
+	g.Printf("%s _result;\n", jt)
+	g.Printf("Seq.send(%q, VAR_GET, in, out);\n", desc)
+	g.genRead("_result", "out", o.Type())
+	g.Printf("return _result;\n")
+	g.Outdent()
+	g.Printf("}\n\n")
+
+	g.genDoc(o.Name())
+	g.Printf("public static void set%s(%s v) {\n", o.Name(), jt)
+	g.Indent()
+	g.Printf("Seq in = new Seq();\n")
+	g.Printf("Seq out = new Seq();\n")
+	g.genWrite("v", "in", o.Type())
+	g.Printf("Seq.send(%q, VAR_SET, in, out);\n", desc)
+	g.Outdent()
+	g.Printf("}\n\n")
+}
+
+// constType returns the basic type used for the value of a constant,
+// or nil if the constant cannot be bound. The int constants that do
+// not fit in 32 bits are int64, as int is 32 bits in Objective-C.
+func constType(o *types.Const) *types.Basic {
+	t, ok := o.Type().Underlying().(*types.Basic)
+	if !ok {
+		return nil
+	}
+	// Untyped constants have the type they would default to in Go.
+	switch t.Kind() {
+	case types.UntypedBool:
+		t = types.Typ[types.Bool]
+	case types.UntypedInt:
+		t = types.Typ[types.Int]
+	case types.UntypedRune:
+		t = types.Typ[types.Int32]
+	case types.UntypedFloat:
+		t = types.Typ[types.Float64]
+	case types.UntypedString:
+		t = types.Typ[types.String]
+	}
+	switch t.Kind() {
+	case types.Int:
+		if i, ok := exact.Int64Val(o.Val()); !ok || i != int64(int32(i)) {
+			return types.Typ[types.Int64]
+		}
+		return t
+	case types.Bool, types.String, types.Float32, types.Float64,
+		types.Int8, types.Int16, types.Int32, types.Int64,
+		types.Uint, types.Uint8, types.Uint16, types.Uint32, types.Uint64:
+		return t
+	}
+	return nil
+}
+
+// checkConstValue reports an error if the value of the constant o does
+// not fit its type t, as an untyped constant may not. Go int and uint
+// are 64 bits.
+func checkConstValue(o *types.Const, t *types.Basic) error {
+	v := o.Val()
+	ok := true
+	switch t.Kind() {
+	case types.Int, types.Int64, types.Int32, types.Int16, types.Int8:
+		bits := constBits[t.Kind()]
+		i, exactOK := exact.Int64Val(v)
+		ok = exactOK && i>>(bits-1) == i>>63
+	case types.Uint, types.Uint64, types.Uint32, types.Uint16, types.Uint8:
+		bits := constBits[t.Kind()]
+		u, exactOK := exact.Uint64Val(v)
+		ok = exactOK && (bits == 64 || u>>bits == 0)
+	case types.Float32:
+		f, _ := exact.Float32Val(v)
+		ok = !math.IsInf(float64(f), 0)
+	case types.Float64:
+		f, _ := exact.Float64Val(v)
+		ok = !math.IsInf(f, 0)
+	}
+	if !ok {
+		typ := t.String()
+		if b, isBasic := o.Type().(*types.Basic); isBasic && b.Kind() == types.UntypedInt {
+			typ = "int" // the default type, before constType widens it
+		}
+		return fmt.Errorf("constant %s overflows %s", v, typ)
+	}
+	return nil
+}
+
+// constBits are the sizes in bits of the integer types of constants.
+var constBits = map[types.BasicKind]uint{
+	types.Int: 64, types.Int64: 64, types.Int32: 32, types.Int16: 16, types.Int8: 8,
+	types.Uint: 64, types.Uint64: 64, types.Uint32: 32, types.Uint16: 16, types.Uint8: 8,
+}
+
+// javaString returns s as a Java string literal.
+func javaString(s string) string {
+	var b bytes.Buffer
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r >= 0x20 && r < 0x7f {
+				b.WriteRune(r)
+				continue
+			}
+			for _, c := range utf16.Encode([]rune{r}) {
+				fmt.Fprintf(&b, `\u%04x`, c)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
 func (g *javaGen) errorf(format string, args ...interface{}) {
 	g.err = append(g.err, fmt.Errorf(format, args...))
 }
 
+// javaPkgName returns the Java package holding the class of the bound
+// package pkg.
+func (g *javaGen) javaPkgName(pkg *types.Package) string {
+	prefix := g.javaPkg
+	if prefix == "" {
+		prefix = "go"
+	}
+	return prefix + "." + pkg.Name()
+}
+
+// checkJavaPkg returns an error if javaPkg is not empty or a Java
+// package name.
+func checkJavaPkg(javaPkg string) error {
+	if javaPkg == "" {
+		return nil
+	}
+	for _, id := range strings.Split(javaPkg, ".") {
+		if id == "" {
+			return fmt.Errorf("invalid Java package name: %q", javaPkg)
+		}
+		for i, r := range id {
+			if !unicode.IsLetter(r) && r != '_' && r != '$' && (i == 0 || !unicode.IsDigit(r)) {
+				return fmt.Errorf("invalid Java package name: %q", javaPkg)
+			}
+		}
+	}
+	return nil
+}
+
 const javaPreamble = `// Java Package %s is a proxy for talking to a Go program.
 //   gobind -lang=java %s
 //
 // File is generated by gobind. Do not edit.
-package go.%s;
+package %s;
 
 import go.Seq;
 
 `
 
 func (g *javaGen) gen() error {
-	g.Printf(javaPreamble, g.pkg.Name(), g.pkg.Path(), g.pkg.Name())
+	if err := checkJavaPkg(g.javaPkg); err != nil {
+		return err
+	}
+	g.Printf(javaPreamble, g.pkg.Name(), g.pkg.Path(), g.javaPkgName(g.pkg))
 
-	firstRune, size := utf8.DecodeRuneInString(g.pkg.Name())
-	className := string(unicode.ToUpper(firstRune)) + g.pkg.Name()[size:]
+	className := capitalize(g.pkg.Name())
+	g.docs = loadDocs(g.fset, g.pkg)
 
+	g.genDoc("")
 	g.Printf("public abstract class %s {\n", className)
 	g.Indent()
 	g.Printf("private %s() {} // uninstantiable\n\n", className)
 	scope := g.pkg.Scope()
 	names := scope.Names()
+	for _, name := range names {
+		if o, ok := scope.Lookup(name).(*types.TypeName); ok && o.Exported() && implementsError(o) {
+			g.errTypes = append(g.errTypes, o)
+		}
+	}
+	for _, o := range boundIfaces(g.pkg, g.allPkg) {
+		if javaImplementable(o) {
+			g.ifaces = append(g.ifaces, o)
+		}
+	}
+
 	var funcs []string
+	hasVars := false
 	for _, name := range names {
 		obj := scope.Lookup(name)
 		if !obj.Exported() {
@@ -566,19 +1229,33 @@ func (g *javaGen) gen() error {
 		}
 
 		switch o := obj.(type) {
-		// TODO(crawshaw): case *types.Const:
-		// TODO(crawshaw): case *types.Var:
+		case *types.Const:
+			g.genConst(o)
+		case *types.Var:
+			g.genVar(o)
+			hasVars = true
 		case *types.Func:
+			g.genResultClass(o)
+			g.genDoc(o.Name())
 			g.genFunc(o, false)
 			funcs = append(funcs, o.Name())
 		case *types.TypeName:
+			if implementsError(o) {
+				g.genErrorClass(o)
+			}
 			named := o.Type().(*types.Named)
 			switch t := named.Underlying().(type) {
 			case *types.Struct:
 				g.genStruct(o, t)
+				g.genCast(o, o.Name()+".DESCRIPTOR", "new "+o.Name())
 			case *types.Interface:
-				g.genInterface(o)
+				if g.genInterface(o) {
+					g.genCast(o, o.Name()+".Stub.DESCRIPTOR", "new "+o.Name()+".Proxy")
+				}
 			default:
+				if implementsError(o) {
+					continue // only bound as an exception
+				}
 				g.errorf("%s: cannot generate binding for %s: %T", g.fset.Position(o.Pos()), o.Name(), t)
 				continue
 			}
@@ -587,11 +1264,18 @@ func (g *javaGen) gen() error {
 		}
 	}
 
+	if len(g.errTypes) > 0 {
+		g.genTypedError()
+	}
 	for i, name := range funcs {
 		g.Printf("private static final int CALL_%s = %d;\n", name, i+1)
 	}
+	if hasVars {
+		g.Printf("private static final int VAR_GET = 0x00f;\n")
+		g.Printf("private static final int VAR_SET = 0x01f;\n")
+	}
 
-	g.Printf("private static final String DESCRIPTOR = %q;\n", g.pkg.Name())
+	g.Printf("private static final String DESCRIPTOR = %q;\n", seqDesc(g.pkg, ""))
 	g.Outdent()
 	g.Printf("}\n")
 
@@ -0,0 +1,293 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"fmt"
+	"unicode"
+
+	"golang.org/x/tools/go/types"
+)
+
+// kotlinGen generates Kotlin declarations wrapping the Java classes
+// generated by javaGen for the same package:
+//
+//   - a top-level function for each function, with Kotlin types
+//     telling which values may be null;
+//   - a top-level property for each variable;
+//   - functions returning a Result in place of throwing the error
+//     returned by Go, for functions and methods;
+//   - component functions destructuring the result classes of
+//     functions returning several values.
+//
+// Kotlin already accesses the fields of structs as properties, through
+// their Java getters and setters.
+type kotlinGen struct {
+	*javaGen
+
+	docNames map[string]string // Kotlin names of functions and variables
+}
+
+// genDoc generates the KDoc of the declaration key of pkgDocs.
+func (g *kotlinGen) genDoc(key string) {
+	g.docs.genDoc(g.printer, key, g.pkg, g.docNames)
+}
+
+func (g *kotlinGen) gen() error {
+	if err := checkJavaPkg(g.javaPkg); err != nil {
+		return err
+	}
+	g.Printf(kotlinPreamble, g.pkg.Name(), g.pkg.Path(), capitalize(g.pkg.Name()), g.javaPkgName(g.pkg))
+
+	g.docs = loadDocs(g.fset, g.pkg)
+	g.docNames = make(map[string]string)
+	scope := g.pkg.Scope()
+	for _, name := range scope.Names() {
+		switch scope.Lookup(name).(type) {
+		case *types.Func, *types.Var:
+			g.docNames[name] = kotlinName(name)
+		}
+	}
+
+	for _, name := range scope.Names() {
+		obj := scope.Lookup(name)
+		if !obj.Exported() {
+			continue
+		}
+		switch o := obj.(type) {
+		case *types.Var:
+			g.genVar(o)
+		case *types.Func:
+			g.genFunc(o, nil)
+		case *types.TypeName:
+			switch t := o.Type().Underlying().(type) {
+			case *types.Struct:
+				for _, m := range exportedMethodSet(types.NewPointer(o.Type())) {
+					g.genFunc(m, o)
+				}
+			case *types.Interface:
+				g.genInterface(o, t)
+			}
+		}
+	}
+
+	if len(g.err) > 0 {
+		return g.err
+	}
+	return nil
+}
+
+// genVar generates a top-level property for a package variable.
+func (g *kotlinGen) genVar(o *types.Var) {
+	if isErrorType(o.Type()) {
+		g.errorf("%s: variables of type error are not supported", o.Name())
+		return
+	}
+	cls := capitalize(g.pkg.Name())
+	g.genDoc(o.Name())
+	g.Printf("var %s: %s\n", kotlinName(o.Name()), g.kotlinType(o.Type()))
+	g.Indent()
+	g.Printf("get() = %s.get%s()\n", cls, o.Name())
+	g.Printf("set(v) = %s.set%s(v)\n", cls, o.Name())
+	g.Outdent()
+	g.Printf("\n")
+}
+
+// genInterface generates the wrappers of the methods of the interface
+// o, unless Java cannot bind it.
+func (g *kotlinGen) genInterface(o *types.TypeName, iface *types.Interface) {
+	for i := 0; i < iface.NumMethods(); i++ {
+		m := iface.Method(i)
+		if returnsChan(m) {
+			g.errorf("channels cannot be returned by interface methods: %s", m)
+			return
+		}
+	}
+	for i := 0; i < iface.NumMethods(); i++ {
+		g.genFunc(iface.Method(i), o)
+	}
+}
+
+// genFunc generates the wrappers of the function o, or of the method o
+// of the type recv if recv is not nil. Functions are wrapped in a
+// function with a Kotlin name, methods only if they return an error.
+func (g *kotlinGen) genFunc(o *types.Func, recv *types.TypeName) {
+	if err := checkResults(o); err != nil {
+		g.errorf("%v", err)
+		return
+	}
+	if err := checkParams(o); err != nil {
+		g.errorf("%v", err)
+		return
+	}
+	if err := checkResultClass(o); err != nil {
+		g.errorf("%v", err)
+		return
+	}
+	sig := o.Type().(*types.Signature)
+	res := sig.Results()
+
+	cls := capitalize(g.pkg.Name())
+	if recv != nil {
+		cls = g.kotlinClass(recv.Type().(*types.Named))
+	}
+	var ret string
+	switch numValues(sig) {
+	case 0:
+		ret = "Unit"
+	case 1:
+		ret = g.kotlinType(res.At(0).Type())
+	default:
+		ret = cls + "." + o.Name() + "Result"
+	}
+
+	if recv == nil || returnsError(sig) {
+		if recv != nil {
+			g.genDoc(recv.Name() + "." + o.Name())
+		} else {
+			g.genDoc(o.Name())
+		}
+		g.Printf("fun ")
+		if recv != nil {
+			g.Printf("%s.", cls)
+		}
+		g.Printf("%s(", kotlinName(o.Name()))
+		params := sig.Params()
+		for i := 0; i < params.Len(); i++ {
+			if i > 0 {
+				g.Printf(", ")
+			}
+			if sig.Variadic() && i == params.Len()-1 {
+				g.Printf("vararg %s: %s", kotlinIdent(paramName(params, i)), g.kotlinType(arrayElem(params.At(i).Type())))
+			} else {
+				g.Printf("%s: %s", kotlinIdent(paramName(params, i)), g.kotlinType(params.At(i).Type()))
+			}
+		}
+		call := o.Name() + "("
+		if recv == nil {
+			call = cls + "." + call
+		}
+		for i := 0; i < params.Len(); i++ {
+			if i > 0 {
+				call += ", "
+			}
+			if sig.Variadic() && i == params.Len()-1 {
+				call += "*" // spread the array to the Java varargs
+			}
+			call += kotlinIdent(paramName(params, i))
+		}
+		call += ")"
+		if returnsError(sig) {
+			g.Printf("): Result<%s> = runCatching { %s }\n\n", ret, call)
+		} else {
+			g.Printf("): %s = %s\n\n", ret, call)
+		}
+	}
+
+	if n := numValues(sig); n > 1 {
+		for i := 0; i < n; i++ {
+			g.Printf("operator fun %s.component%d(): %s = %s\n", ret, i+1, g.kotlinType(res.At(i).Type()), kotlinIdent(resultName(res, i)))
+		}
+		g.Printf("\n")
+	}
+}
+
+// kotlinType returns the Kotlin type of the values of type T read from
+// or written to the Java classes. The values that are never null in
+// Java have a non-null type.
+func (g *kotlinGen) kotlinType(T types.Type) string {
+	switch T := T.(type) {
+	case *types.Basic:
+		if T.Kind() == types.String {
+			return "String"
+		}
+		// Kotlin capitalizes the names of the Java primitive types.
+		return capitalize(g.javaType(T))
+	case *types.Slice, *types.Array:
+		// Slices may be null.
+		elem := arrayElem(T)
+		if isJavaPrimitive(elem) {
+			return capitalize(g.javaType(elem)) + "Array?"
+		}
+		return "Array<" + g.kotlinType(elem) + ">?"
+	case *types.Map:
+		return "Map<" + g.kotlinType(T.Key()) + ", " + g.kotlinType(T.Elem()) + ">"
+	case *types.Chan:
+		return "go.Seq.Stream<" + g.kotlinType(T.Elem()) + ">"
+	case *types.Pointer:
+		if isDirectBuffer(T) {
+			return "java.nio.ByteBuffer?"
+		}
+		return g.kotlinType(T.Elem())
+	case *types.Named:
+		// Go nil pointers and interfaces are null.
+		return g.kotlinClass(T) + "?"
+	default:
+		return g.javaType(T)
+	}
+}
+
+// kotlinClass returns the Kotlin name of the Java class of the struct
+// or interface type T.
+func (g *kotlinGen) kotlinClass(T *types.Named) string {
+	jt := g.javaType(T)
+	if T.Obj().Pkg() != nil && T.Obj().Pkg().Path() == g.pkg.Path() {
+		// Types of the package are nested in its class.
+		return capitalize(g.pkg.Name()) + "." + jt
+	}
+	return jt
+}
+
+// kotlinName returns the name of the Kotlin declaration wrapping the Go
+// declaration name.
+func kotlinName(name string) string {
+	return kotlinIdent(lowerCamel(name))
+}
+
+// lowerCamel returns name with its leading upper case letters lowered,
+// as Kotlin and Swift do for the names of Java getters and Objective-C
+// methods: Hello is hello, URL is url and HTTPServer is httpServer.
+func lowerCamel(name string) string {
+	r := []rune(name)
+	n := 0
+	for n < len(r) && unicode.IsUpper(r[n]) {
+		n++
+	}
+	if n > 1 && n < len(r) && unicode.IsLower(r[n]) {
+		n-- // the last upper case letter starts a word
+	}
+	for i := 0; i < n; i++ {
+		r[i] = unicode.ToLower(r[i])
+	}
+	return string(r)
+}
+
+// kotlinKeywords are the Kotlin keywords that cannot be identifiers.
+var kotlinKeywords = map[string]bool{
+	"as": true, "break": true, "class": true, "continue": true, "do": true,
+	"else": true, "false": true, "for": true, "fun": true, "if": true,
+	"in": true, "interface": true, "is": true, "null": true, "object": true,
+	"package": true, "return": true, "super": true, "this": true, "throw": true,
+	"true": true, "try": true, "typealias": true, "typeof": true, "val": true,
+	"var": true, "when": true, "while": true,
+}
+
+// kotlinIdent returns name as a Kotlin identifier, quoting keywords.
+func kotlinIdent(name string) string {
+	if kotlinKeywords[name] {
+		return fmt.Sprintf("`%s`", name)
+	}
+	return name
+}
+
+const kotlinPreamble = `// Kotlin Package %s is a proxy for talking to a Go program.
+//   gobind -lang=kotlin %s
+//
+// File is generated by gobind. Do not edit.
+@file:JvmName("%sKt")
+
+package %s
+
+`
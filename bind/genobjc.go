@@ -5,26 +5,43 @@
 package bind
 
 import (
+	"bytes"
 	"fmt"
 	"go/token"
-	"golang.org/x/tools/go/types"
-	"log"
+	"math"
+	"strconv"
 	"strings"
 	"unicode"
 	"unicode/utf8"
+
+	"golang.org/x/tools/go/exact"
+	"golang.org/x/tools/go/types"
 )
 
 type objcGen struct {
 	*printer
-	fset *token.FileSet
-	pkg  *types.Package
-	err  ErrorList
+	fset   *token.FileSet
+	pkg    *types.Package
+	allPkg []*types.Package
+	prefix string // prefix of the names of bound packages
+	err    ErrorList
 
 	// fields set by init.
 	pkgName    string
 	namePrefix string
 	funcs      []*types.Func
 	names      []*types.TypeName
+	consts     []*types.Const
+	vars       []*types.Var
+	errTypes   []*types.TypeName // exported types implementing error
+	ifaces     []*types.TypeName // protocols the classes can conform to
+	docs       pkgDocs
+	docNames   map[string]string // Objective-C names of the declarations
+}
+
+// genDoc generates the header doc of the declaration key of pkgDocs.
+func (g *objcGen) genDoc(key string) {
+	g.docs.genDoc(g.printer, key, g.pkg, g.docNames)
 }
 
 func capitalize(n string) string {
@@ -34,9 +51,15 @@ func capitalize(n string) string {
 
 func (g *objcGen) init() {
 	g.pkgName = g.pkg.Name()
-	g.namePrefix = "Go" + capitalize(g.pkgName)
+	g.namePrefix = g.pkgPrefix(g.pkg)
 	g.funcs = nil
 	g.names = nil
+	g.consts = nil
+	g.vars = nil
+	g.errTypes = nil
+	g.ifaces = boundIfaces(g.pkg, g.allPkg)
+	g.docs = loadDocs(g.fset, g.pkg)
+	g.docNames = make(map[string]string)
 
 	scope := g.pkg.Scope()
 	for _, name := range scope.Names() {
@@ -44,16 +67,44 @@ func (g *objcGen) init() {
 		if !obj.Exported() {
 			continue
 		}
+		g.docNames[name] = g.namePrefix + name
 		switch obj := obj.(type) {
 		case *types.Func:
 			g.funcs = append(g.funcs, obj)
 		case *types.TypeName:
 			g.names = append(g.names, obj)
-			// TODO(hyangah): *types.Const, *types.Var
+			if implementsError(obj) {
+				g.errTypes = append(g.errTypes, obj)
+			}
+		case *types.Const:
+			g.consts = append(g.consts, obj)
+		case *types.Var:
+			g.vars = append(g.vars, obj)
 		}
 	}
 }
 
+// pkgPrefix returns the prefix of the names of the declarations of the
+// bound package pkg, and of its header.
+func (g *objcGen) pkgPrefix(pkg *types.Package) string {
+	prefix := g.prefix
+	if prefix == "" {
+		prefix = "Go"
+	}
+	return prefix + capitalize(pkg.Name())
+}
+
+// checkPrefix returns an error if prefix is not empty or an
+// Objective-C identifier.
+func checkPrefix(prefix string) error {
+	for i, r := range prefix {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || r == '_' || i > 0 && '0' <= r && r <= '9') {
+			return fmt.Errorf("invalid Objective-C name prefix: %q", prefix)
+		}
+	}
+	return nil
+}
+
 const objcPreamble = `// Objective-C API for talking to %s Go package.
 //   gobind -lang=objc %s
 //
@@ -62,34 +113,79 @@ const objcPreamble = `// Objective-C API for talking to %s Go package.
 `
 
 func (g *objcGen) genH() error {
+	if err := checkPrefix(g.prefix); err != nil {
+		return err
+	}
 	g.init()
 
 	g.Printf(objcPreamble, g.pkg.Path(), g.pkg.Path())
-	g.Printf("#ifndef __Go%s_H__\n", capitalize(g.pkgName))
-	g.Printf("#define __Go%s_H__\n", capitalize(g.pkgName))
+	g.Printf("#ifndef __%s_H__\n", g.namePrefix)
+	g.Printf("#define __%s_H__\n", g.namePrefix)
 	g.Printf("\n")
 	g.Printf(`#include <Foundation/Foundation.h>`)
-	g.Printf("\n\n")
+	g.Printf("\n")
+	for _, pkg := range boundImports(g.pkg, g.allPkg) {
+		g.Printf("#include \"%s.h\"\n", g.pkgPrefix(pkg))
+	}
+	g.Printf("\n")
+
+	if _, ok := g.docs[""]; ok {
+		g.genDoc("")
+		g.Printf("\n")
+	}
+
+	if g.usesStreams() {
+		g.Printf("@class GoSeqStream;\n\n")
+	}
 
-	// @class names
+	// @class and @protocol names
 	for _, obj := range g.names {
 		named := obj.Type().(*types.Named)
 		switch named.Underlying().(type) {
-		case *types.Struct, *types.Interface:
+		case *types.Struct:
+			g.Printf("@class %s%s;\n", g.namePrefix, obj.Name())
+		case *types.Interface:
+			g.Printf("@protocol %s%s;\n", g.namePrefix, obj.Name())
 			g.Printf("@class %s%s;\n", g.namePrefix, obj.Name())
 		}
 		g.Printf("\n")
 	}
 
+	// @protocols, defined before the @interfaces conforming to them.
+	for _, obj := range g.names {
+		if t, ok := obj.Type().Underlying().(*types.Interface); ok {
+			g.genInterfaceH(obj, t)
+			g.Printf("\n")
+		}
+	}
+
 	// @interfaces
 	for _, obj := range g.names {
-		named := obj.Type().(*types.Named)
-		switch t := named.Underlying().(type) {
-		case *types.Struct:
+		if t, ok := obj.Type().Underlying().(*types.Struct); ok {
 			g.genStructH(obj, t)
-		case *types.Interface:
-			g.genInterfaceH(obj, t)
+			g.Printf("\n")
 		}
+	}
+
+	// constants.
+	for _, obj := range g.consts {
+		g.genConstH(obj)
+	}
+	if len(g.consts) > 0 {
+		g.Printf("\n")
+	}
+
+	// error domains.
+	for _, obj := range g.errTypes {
+		g.Printf("FOUNDATION_EXPORT NSString* const %s%sDomain;\n", g.namePrefix, obj.Name())
+	}
+	if len(g.errTypes) > 0 {
+		g.Printf("\n")
+	}
+
+	// variable accessors.
+	for _, obj := range g.vars {
+		g.genVarH(obj)
 		g.Printf("\n")
 	}
 
@@ -99,6 +195,15 @@ func (g *objcGen) genH() error {
 		g.Printf("\n")
 	}
 
+	// checked casts.
+	for _, obj := range g.names {
+		switch obj.Type().Underlying().(type) {
+		case *types.Struct, *types.Interface:
+			g.Printf("FOUNDATION_EXPORT %s %sCast%s(id o);\n", g.objcType(obj.Type()), g.namePrefix, obj.Name())
+			g.Printf("\n")
+		}
+	}
+
 	// declare all named types first.
 	g.Printf("#endif\n")
 
@@ -109,6 +214,9 @@ func (g *objcGen) genH() error {
 }
 
 func (g *objcGen) genM() error {
+	if err := checkPrefix(g.prefix); err != nil {
+		return err
+	}
 	g.init()
 
 	g.Printf(objcPreamble, g.pkg.Path(), g.pkg.Path())
@@ -118,8 +226,14 @@ func (g *objcGen) genM() error {
 	g.Printf("\n")
 	g.Printf("static NSString *errDomain = @\"go.%s\";\n", g.pkg.Path())
 	g.Printf("\n")
+	for _, obj := range g.errTypes {
+		g.Printf("NSString* const %s%sDomain = @%q;\n", g.namePrefix, obj.Name(), errorTypeName(obj))
+	}
+	if len(g.errTypes) > 0 {
+		g.Printf("\n")
+	}
 
-	g.Printf("#define _DESCRIPTOR_ %q\n\n", g.pkgName)
+	g.Printf("#define _DESCRIPTOR_ %q\n\n", seqDesc(g.pkg, ""))
 	for i, obj := range g.funcs {
 		g.Printf("#define _CALL_%s_ %d\n", obj.Name(), i+1)
 	}
@@ -137,12 +251,27 @@ func (g *objcGen) genM() error {
 		g.Printf("\n")
 	}
 
+	// variable accessors.
+	for _, obj := range g.vars {
+		g.genVarM(obj)
+		g.Printf("\n")
+	}
+
 	// global functions.
 	for _, obj := range g.funcs {
 		g.genFuncM(obj)
 		g.Printf("\n")
 	}
 
+	// checked casts.
+	for _, obj := range g.names {
+		switch obj.Type().Underlying().(type) {
+		case *types.Struct, *types.Interface:
+			g.genCastM(obj)
+			g.Printf("\n")
+		}
+	}
+
 	if len(g.err) > 0 {
 		return g.err
 	}
@@ -167,6 +296,10 @@ func (g *objcGen) funcSummary(obj *types.Func) *funcSummary {
 	params := sig.Params()
 	for i := 0; i < params.Len(); i++ {
 		p := params.At(i)
+		if _, ok := p.Type().(*types.Chan); ok {
+			g.errorf("channels are only supported as results of Go functions and methods: %s", obj)
+			return nil
+		}
 		v := paramInfo{
 			typ:  p.Type(),
 			name: paramName(params, i),
@@ -195,29 +328,25 @@ func (g *objcGen) funcSummary(obj *types.Func) *funcSummary {
 			s.retParams = append(s.retParams, paramInfo{typ: typ, name: name})
 			s.ret = g.objcType(typ)
 		}
-	case 2:
-		name := res.At(0).Name()
-		if name == "" || paramRE.MatchString(name) {
-			name = "ret0_"
-		}
-		s.retParams = append(s.retParams, paramInfo{
-			typ:  res.At(0).Type(),
-			name: name,
-		})
-
-		if !isErrorType(res.At(1).Type()) {
-			g.errorf("second result value must be of type error: %s", obj)
+	default:
+		if err := checkResults(obj); err != nil {
+			g.errorf("%v", err)
 			return nil
 		}
-		s.retParams = append(s.retParams, paramInfo{
-			typ:  res.At(1).Type(),
-			name: "error", // TODO(hyangah): name collision check.
-		})
-		s.ret = "BOOL"
-	default:
-		// TODO(hyangah): relax the constraint on multiple return params.
-		g.errorf("too many result values: %s", obj)
-		return nil
+		for i := 0; i < res.Len(); i++ {
+			p := res.At(i)
+			name := p.Name()
+			if isErrorType(p.Type()) {
+				name = "error" // TODO(hyangah): name collision check.
+			} else if name == "" || name == "_" || paramRE.MatchString(name) {
+				name = fmt.Sprintf("ret%d_", i)
+			}
+			s.retParams = append(s.retParams, paramInfo{typ: p.Type(), name: name})
+		}
+		s.ret = "void"
+		if returnsError(sig) {
+			s.ret = "BOOL"
+		}
 	}
 
 	return s
@@ -263,15 +392,146 @@ func (s *funcSummary) returnsVal() bool {
 
 func (g *objcGen) genFuncH(obj *types.Func) {
 	if s := g.funcSummary(obj); s != nil {
+		g.genDoc(obj.Name())
 		g.Printf("FOUNDATION_EXPORT %s;\n", s.asFunc(g))
 	}
 }
 
+func (g *objcGen) genConstH(o *types.Const) {
+	t := constType(o)
+	if t == nil {
+		g.errorf("%s: unsupported constant type %s", o.Name(), o.Type())
+		return
+	}
+	if err := checkConstValue(o, t); err != nil {
+		g.errorf("%s: %v", o.Name(), err)
+		return
+	}
+	var val string
+	switch t.Kind() {
+	case types.Bool:
+		if exact.BoolVal(o.Val()) {
+			val = "YES"
+		} else {
+			val = "NO"
+		}
+	case types.String:
+		val = "@" + objcString(exact.StringVal(o.Val()))
+	case types.Float32, types.Float64:
+		f, _ := exact.Float64Val(o.Val())
+		val = strconv.FormatFloat(f, 'g', -1, 64)
+	case types.Uint, types.Uint64:
+		u, _ := exact.Uint64Val(o.Val())
+		val = fmt.Sprintf("%dULL", u)
+	case types.Uint8, types.Uint16, types.Uint32:
+		u, _ := exact.Uint64Val(o.Val())
+		val = fmt.Sprintf("%dU", u)
+	default:
+		i, _ := exact.Int64Val(o.Val())
+		switch {
+		case i == math.MinInt64:
+			// -9223372036854775808LL overflows before it is negated.
+			val = "(-9223372036854775807LL - 1)"
+		case t.Kind() == types.Int || t.Kind() == types.Int64:
+			val = fmt.Sprintf("%dLL", i)
+		default:
+			val = fmt.Sprintf("%d", i)
+		}
+	}
+	g.genDoc(o.Name())
+	g.Printf("static %s const %s%s = %s;\n", g.objcType(t), g.namePrefix, o.Name(), val)
+}
+
+// varSummaries returns the summaries of the getter and setter
+// of a package variable.
+func (g *objcGen) varSummaries(o *types.Var) (get, set *funcSummary) {
+	get = &funcSummary{
+		name: o.Name(),
+		ret:  g.objcType(o.Type()),
+	}
+	get.retParams = append(get.retParams, paramInfo{typ: o.Type(), name: "ret_"})
+	set = &funcSummary{
+		name: "Set" + o.Name(),
+		ret:  "void",
+	}
+	set.params = append(set.params, paramInfo{typ: o.Type(), name: "v"})
+	return get, set
+}
+
+func (g *objcGen) genVarH(o *types.Var) {
+	if isErrorType(o.Type()) {
+		g.errorf("%s: variables of type error are not supported", o.Name())
+		return
+	}
+	get, set := g.varSummaries(o)
+	g.genDoc(o.Name())
+	g.Printf("FOUNDATION_EXPORT %s;\n", get.asFunc(g))
+	g.genDoc(o.Name())
+	g.Printf("FOUNDATION_EXPORT %s;\n", set.asFunc(g))
+}
+
+func (g *objcGen) genVarM(o *types.Var) {
+	if isErrorType(o.Type()) {
+		return // reported by genVarH
+	}
+	desc := fmt.Sprintf("_GO_%s_%s", g.pkgName, o.Name())
+	g.Printf("#define %s_DESCRIPTOR_ %q\n", desc, seqDesc(g.pkg, o.Name()))
+	g.Printf("#define %s_GET_ (0x00f)\n", desc)
+	g.Printf("#define %s_SET_ (0x01f)\n", desc)
+	g.Printf("\n")
+
+	get, set := g.varSummaries(o)
+	g.Printf("%s {\n", get.asFunc(g))
+	g.Indent()
+	g.genFunc(desc+"_DESCRIPTOR_", desc+"_GET_", get, false)
+	g.Outdent()
+	g.Printf("}\n\n")
+
+	g.Printf("%s {\n", set.asFunc(g))
+	g.Indent()
+	g.genFunc(desc+"_DESCRIPTOR_", desc+"_SET_", set, false)
+	g.Outdent()
+	g.Printf("}\n")
+}
+
+// objcString returns s as a C string literal.
+func objcString(s string) string {
+	var b bytes.Buffer
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if c < 0x20 || c == 0x7f {
+				// Octal escapes, unlike hex escapes, do not
+				// consume the digits that follow.
+				fmt.Fprintf(&b, `\%03o`, c)
+			} else {
+				b.WriteByte(c)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
 func (g *objcGen) seqType(typ types.Type) string {
 	s := seqType(typ)
-	if s == "String" {
+	switch s {
+	case "String":
 		// TODO(hyangah): non utf-8 strings.
 		s = "UTF8"
+	case "StringArray":
+		s = "UTF8Array"
 	}
 	return s
 }
@@ -295,10 +555,27 @@ func (g *objcGen) genFunc(pkgDesc, callDesc string, s *funcSummary, isMethod boo
 		g.Printf("go_seq_writeRef(&in_, self.ref);\n")
 	}
 	for _, p := range s.params {
-		st := g.seqType(p.typ)
-		if st == "Ref" {
+		switch st := g.seqType(p.typ); st {
+		case "Ref":
 			g.Printf("go_seq_write%s(&in_, %s.ref);\n", st, p.name)
-		} else {
+		case "RefArray":
+			g.Printf("go_seq_writeArrayLen(&in_, (int32_t)[%s count]);\n", p.name)
+			g.Printf("for (%s %s_elem in %s) {\n", g.objcType(arrayElem(p.typ)), p.name, p.name)
+			g.Indent()
+			g.Printf("go_seq_writeRef(&in_, %s_elem.ref);\n", p.name)
+			g.Outdent()
+			g.Printf("}\n")
+		case "Map":
+			m := p.typ.(*types.Map)
+			g.Printf("go_seq_writeArrayLen(&in_, (int32_t)[%s count]);\n", p.name)
+			g.Printf("for (id %s_key in %s) {\n", p.name, p.name)
+			g.Indent()
+			g.Printf("id %s_val = [%s objectForKey:%s_key];\n", p.name, p.name, p.name)
+			g.genWriteBoxed(p.name+"_key", m.Key())
+			g.genWriteBoxed(p.name+"_val", m.Elem())
+			g.Outdent()
+			g.Printf("}\n")
+		default:
 			g.Printf("go_seq_write%s(&in_, %s);\n", st, p.name)
 		}
 	}
@@ -306,7 +583,13 @@ func (g *objcGen) genFunc(pkgDesc, callDesc string, s *funcSummary, isMethod boo
 
 	if s.returnsVal() {
 		p := s.retParams[0]
-		if seqTyp := g.seqType(p.typ); seqTyp != "Ref" {
+		if seqTyp := g.seqType(p.typ); seqTyp == "RefArray" {
+			g.genReadRefArray(p.name, p.typ)
+		} else if seqTyp == "Stream" {
+			g.genReadStream(p.name, p.typ)
+		} else if seqTyp == "Map" {
+			g.genReadMap(p.name, p.typ)
+		} else if seqTyp != "Ref" {
 			g.Printf("%s %s = go_seq_read%s(&out_);\n", g.objcType(p.typ), p.name, g.seqType(p.typ))
 		} else {
 			ptype := g.objcType(p.typ)
@@ -314,7 +597,7 @@ func (g *objcGen) genFunc(pkgDesc, callDesc string, s *funcSummary, isMethod boo
 			g.Printf("%s %s = %s_ref.obj;\n", ptype, p.name, p.name)
 			g.Printf("if (%s == NULL) {\n", p.name)
 			g.Indent()
-			g.Printf("%s = [[%s alloc] initWithRef:%s_ref];\n", p.name, ptype[:len(ptype)-1], p.name)
+			g.Printf("%s = [[%s alloc] initWithRef:%s_ref];\n", p.name, g.refClass(p.typ), p.name)
 			g.Outdent()
 			g.Printf("}\n")
 		}
@@ -322,29 +605,47 @@ func (g *objcGen) genFunc(pkgDesc, callDesc string, s *funcSummary, isMethod boo
 		for _, p := range s.retParams {
 			if isErrorType(p.typ) {
 				g.Printf("NSString* _%s = go_seq_readUTF8(&out_);\n", p.name)
-				g.Printf("if ([_%s length] != 0 && %s != nil) {\n", p.name, p.name)
+				g.Printf("if ([_%s length] != 0) {\n", p.name)
+				g.Indent()
+				g.Printf("NSString* _%s_domain = go_seq_readUTF8(&out_);\n", p.name)
+				g.Printf("int64_t _%s_code = go_seq_readInt64(&out_);\n", p.name)
+				g.Printf("if ([_%s_domain length] == 0) {\n", p.name)
+				g.Indent()
+				g.Printf("_%s_domain = errDomain;\n", p.name)
+				g.Outdent()
+				g.Printf("}\n")
+				g.Printf("if (%s != nil) {\n", p.name)
 				g.Indent()
 				g.Printf("NSMutableDictionary *details = [NSMutableDictionary dictionary];\n")
 				g.Printf("[details setValue:_%s forKey:NSLocalizedDescriptionKey];\n", p.name)
-				g.Printf("*%s = [NSError errorWithDomain:errDomain code:1 userInfo:details];\n", p.name)
+				g.Printf("*%s = [NSError errorWithDomain:_%s_domain code:(NSInteger)_%s_code userInfo:details];\n", p.name, p.name, p.name)
+				g.Outdent()
+				g.Printf("}\n")
 				g.Outdent()
 				g.Printf("}\n")
 			} else if seqTyp := g.seqType(p.typ); seqTyp != "Ref" {
-				g.Printf("%s %s_val = go_seq_read%s(&out_);\n", g.objcType(p.typ), p.name, g.seqType(p.typ))
+				if seqTyp == "RefArray" {
+					g.genReadRefArray(p.name+"_val", p.typ)
+				} else if seqTyp == "Stream" {
+					g.genReadStream(p.name+"_val", p.typ)
+				} else if seqTyp == "Map" {
+					g.genReadMap(p.name+"_val", p.typ)
+				} else {
+					g.Printf("%s %s_val = go_seq_read%s(&out_);\n", g.objcType(p.typ), p.name, g.seqType(p.typ))
+				}
 				g.Printf("if (%s != NULL) {\n", p.name)
 				g.Indent()
 				g.Printf("*%s = %s_val;\n", p.name, p.name)
 				g.Outdent()
 				g.Printf("}\n")
 			} else {
-				ptype := g.objcType(p.typ)
 				g.Printf("GoSeqRef* %s_ref = go_seq_readRef(&out_);\n", p.name)
 				g.Printf("if (%s != NULL) {\n", p.name)
 				g.Indent()
 				g.Printf("*%s = %s_ref.obj;\n", p.name, p.name)
 				g.Printf("if (*%s == NULL) {\n", p.name)
 				g.Indent()
-				g.Printf("*%s = [[%s alloc] initWithRef:%s_ref];\n", p.name, ptype[:len(ptype)-1], p.name)
+				g.Printf("*%s = [[%s alloc] initWithRef:%s_ref];\n", p.name, g.refClass(p.typ), p.name)
 				g.Outdent()
 				g.Printf("}\n")
 				g.Outdent()
@@ -359,21 +660,273 @@ func (g *objcGen) genFunc(pkgDesc, callDesc string, s *funcSummary, isMethod boo
 		p := s.retParams[n-1]
 		if isErrorType(p.typ) {
 			g.Printf("return ([_%s length] == 0);\n", p.name)
-		} else {
+		} else if s.returnsVal() {
 			g.Printf("return %s;\n", p.name)
 		}
 	}
 }
 
+// genReadRefArray declares name as an array holding the objects
+// of the array of references typ read from out_.
+func (g *objcGen) genReadRefArray(name string, typ types.Type) {
+	etype := g.objcType(arrayElem(typ))
+	g.Printf("int32_t %s_len = go_seq_readArrayLen(&out_);\n", name)
+	g.Printf("NSMutableArray* %s = [NSMutableArray arrayWithCapacity:%s_len];\n", name, name)
+	g.Printf("for (int32_t i = 0; i < %s_len; i++) {\n", name)
+	g.Indent()
+	g.Printf("GoSeqRef* %s_ref = go_seq_readRef(&out_);\n", name)
+	g.Printf("%s %s_elem = %s_ref.obj;\n", etype, name, name)
+	g.Printf("if (%s_elem == NULL) {\n", name)
+	g.Indent()
+	g.Printf("%s_elem = [[%s alloc] initWithRef:%s_ref];\n", name, g.refClass(arrayElem(typ)), name)
+	g.Outdent()
+	g.Printf("}\n")
+	g.Printf("[%s addObject:%s_elem];\n", name, name)
+	g.Outdent()
+	g.Printf("}\n")
+}
+
+// usesStreams reports whether a function or method of the package
+// returns a channel, bound as a GoSeqStream declared in seq.h.
+func (g *objcGen) usesStreams() bool {
+	for _, obj := range g.funcs {
+		if returnsChan(obj) {
+			return true
+		}
+	}
+	for _, obj := range g.names {
+		if _, ok := obj.Type().Underlying().(*types.Struct); !ok {
+			continue
+		}
+		for _, m := range exportedMethodSet(types.NewPointer(obj.Type())) {
+			if returnsChan(m) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// genReadStream reads a receive-only channel into a GoSeqStream,
+// with a block boxing each of its values.
+func (g *objcGen) genReadStream(name string, typ types.Type) {
+	elem := typ.(*types.Chan).Elem()
+	g.Printf("GoSeqStream* %s = [[GoSeqStream alloc] initWithRef:go_seq_readRef(&out_) read:^id(GoSeq *in) {\n", name)
+	g.Indent()
+	if !g.genReadBoxed("v", "in", elem) {
+		g.errorf("unsupported channel element type: %s", typ)
+		g.Printf("return nil;\n")
+	} else {
+		g.Printf("return v;\n")
+	}
+	g.Outdent()
+	g.Printf("}];\n")
+}
+
+// genReadMap declares name as a dictionary holding the boxed
+// entries of the map typ read from out_.
+func (g *objcGen) genReadMap(name string, typ types.Type) {
+	m := typ.(*types.Map)
+	g.Printf("int32_t %s_len = go_seq_readArrayLen(&out_);\n", name)
+	g.Printf("NSMutableDictionary* %s = [NSMutableDictionary dictionaryWithCapacity:%s_len];\n", name, name)
+	g.Printf("for (int32_t i = 0; i < %s_len; i++) {\n", name)
+	g.Indent()
+	if !g.genReadBoxed(name+"_key", "&out_", m.Key()) || !g.genReadBoxed(name+"_val", "&out_", m.Elem()) {
+		g.errorf("unsupported map type: %s", typ)
+	}
+	g.Printf("[%s setObject:%s_val forKey:%s_key];\n", name, name, name)
+	g.Outdent()
+	g.Printf("}\n")
+}
+
+// genReadBoxed declares name as an object holding a value of type
+// typ read from seq, boxing numbers in NSNumbers. It reports false
+// if values of typ cannot be boxed.
+func (g *objcGen) genReadBoxed(name, seq string, typ types.Type) bool {
+	switch seqTyp := g.seqType(typ); seqTyp {
+	case "Ref":
+		g.Printf("GoSeqRef* %s_ref = go_seq_readRef(%s);\n", name, seq)
+		g.Printf("id %s = %s_ref.obj;\n", name, name)
+		g.Printf("if (%s == NULL) {\n", name)
+		g.Indent()
+		g.Printf("%s = [[%s alloc] initWithRef:%s_ref];\n", name, g.refClass(typ), name)
+		g.Outdent()
+		g.Printf("}\n")
+	case "UTF8", "ByteArray", "BoolArray", "IntArray", "Int8Array", "Int16Array",
+		"Int32Array", "Int64Array", "UintArray", "Uint16Array", "Uint32Array",
+		"Uint64Array", "Float32Array", "Float64Array", "UTF8Array":
+		g.Printf("id %s = go_seq_read%s(%s);\n", name, seqTyp, seq)
+	case "Bool", "Int", "Int8", "Int16", "Int32", "Int64", "Uint", "Uint16",
+		"Uint32", "Uint64", "Float32", "Float64":
+		g.Printf("id %s = @(go_seq_read%s(%s));\n", name, seqTyp, seq)
+	default:
+		return false
+	}
+	return true
+}
+
+// nsNumberValue maps seq types to the NSNumber methods
+// unboxing their values.
+var nsNumberValue = map[string]string{
+	"Bool":    "boolValue",
+	"Int":     "intValue",
+	"Int8":    "charValue",
+	"Int16":   "shortValue",
+	"Int32":   "intValue",
+	"Int64":   "longLongValue",
+	"Uint":    "unsignedIntegerValue",
+	"Uint16":  "unsignedShortValue",
+	"Uint32":  "unsignedIntValue",
+	"Uint64":  "unsignedLongLongValue",
+	"Float32": "floatValue",
+	"Float64": "doubleValue",
+}
+
+// genWriteBoxed writes the object name, holding a value of type
+// typ boxed as by genReadBoxed, to in_.
+func (g *objcGen) genWriteBoxed(name string, typ types.Type) {
+	switch seqTyp := g.seqType(typ); seqTyp {
+	case "Ref":
+		g.Printf("go_seq_writeRef(&in_, ((%s)%s).ref);\n", g.objcType(typ), name)
+	case "UTF8", "ByteArray", "BoolArray", "IntArray", "Int8Array", "Int16Array",
+		"Int32Array", "Int64Array", "UintArray", "Uint16Array", "Uint32Array",
+		"Uint64Array", "Float32Array", "Float64Array", "UTF8Array":
+		g.Printf("go_seq_write%s(&in_, %s);\n", seqTyp, name)
+	default:
+		m, ok := nsNumberValue[seqTyp]
+		if !ok {
+			g.errorf("unsupported map element type: %s", typ)
+			return
+		}
+		g.Printf("go_seq_write%s(&in_, [%s %s]);\n", seqTyp, name, m)
+	}
+}
+
+// refClass returns the name of the class of the objects holding
+// references to Go values of the struct or interface type typ.
+func (g *objcGen) refClass(typ types.Type) string {
+	if p, ok := typ.(*types.Pointer); ok {
+		typ = p.Elem()
+	}
+	n := typ.(*types.Named).Obj()
+	return g.pkgPrefix(n.Pkg()) + n.Name()
+}
+
+// genInterfaceH declares the protocol of the interface obj, which
+// the classes of the structs implementing it conform to, and the
+// class of the Go objects of other types implementing it.
 func (g *objcGen) genInterfaceH(obj *types.TypeName, t *types.Interface) {
-	log.Printf("TODO: %s", obj.Name())
+	var methods []*funcSummary
+	for i := 0; i < t.NumMethods(); i++ {
+		if s := g.funcSummary(t.Method(i)); s != nil {
+			methods = append(methods, s)
+		}
+	}
+
+	g.genDoc(obj.Name())
+	g.Printf("@protocol %s%s <NSObject>\n", g.namePrefix, obj.Name())
+	g.Printf("@property(strong, readonly) id ref;\n")
+	g.Printf("\n")
+	for _, s := range methods {
+		g.genDoc(obj.Name() + "." + s.name)
+		g.Printf("- %s;\n", s.asMethod(g))
+	}
+	g.Printf("@end\n")
+	g.Printf("\n")
+
+	g.Printf("@interface %s%s : NSObject <%s%s> {\n", g.namePrefix, obj.Name(), g.namePrefix, obj.Name())
+	g.Printf("}\n")
+	g.Printf("@property(strong, readonly) id ref;\n")
+	g.Printf("\n")
+	g.Printf("- (id)initWithRef:(id)ref;\n")
+	for _, s := range methods {
+		g.Printf("- %s;\n", s.asMethod(g))
+	}
+	g.Printf("@end\n")
 }
+
+// genInterfaceM implements the class of genInterfaceH by calling
+// the methods of the Go object.
 func (g *objcGen) genInterfaceM(obj *types.TypeName, t *types.Interface) {
-	log.Printf("TODO: %s", obj.Name())
+	desc := fmt.Sprintf("_GO_%s_%s", g.pkgName, obj.Name())
+	g.Printf("#define %s_DESCRIPTOR_ %q\n", desc, seqDesc(g.pkg, obj.Name()))
+	g.Printf("#define %s_CAST_ (0x00e)\n", desc)
+	for i := 0; i < t.NumMethods(); i++ {
+		g.Printf("#define %s_%s_ (0x%x0a)\n", desc, t.Method(i).Name(), i+1)
+	}
+
+	g.Printf("\n")
+	g.Printf("@implementation %s%s {\n", g.namePrefix, obj.Name())
+	g.Printf("}\n\n")
+	g.Printf("- (id)initWithRef:(id)ref {\n")
+	g.Indent()
+	g.Printf("self = [super init];\n")
+	g.Printf("if (self) { _ref = ref; }\n")
+	g.Printf("return self;\n")
+	g.Outdent()
+	g.Printf("}\n\n")
+
+	for i := 0; i < t.NumMethods(); i++ {
+		m := t.Method(i)
+		s := g.funcSummary(m)
+		if s == nil {
+			continue
+		}
+		g.Printf("- %s {\n", s.asMethod(g))
+		g.Indent()
+		g.genFunc(desc+"_DESCRIPTOR_", desc+"_"+m.Name()+"_", s, true)
+		g.Outdent()
+		g.Printf("}\n\n")
+	}
+	g.Printf("@end\n")
+}
+
+// genCastM implements the checked cast of an object to the class or
+// protocol of the struct or interface obj, returning nil if the
+// object is not a reference to a Go value of that type.
+func (g *objcGen) genCastM(obj *types.TypeName) {
+	name := g.namePrefix + obj.Name()
+	desc := fmt.Sprintf("_GO_%s_%s", g.pkgName, obj.Name())
+	typ := g.objcType(obj.Type())
+	g.Printf("%s %sCast%s(id o) {\n", typ, g.namePrefix, obj.Name())
+	g.Indent()
+	if _, ok := obj.Type().Underlying().(*types.Interface); ok {
+		g.Printf("if (o == nil || [o conformsToProtocol:@protocol(%s)]) {\n", name)
+	} else {
+		g.Printf("if (o == nil || [o isKindOfClass:[%s class]]) {\n", name)
+	}
+	g.Printf("	return o;\n")
+	g.Printf("}\n")
+	g.Printf("if (![o respondsToSelector:@selector(ref)]) {\n")
+	g.Printf("	return nil;\n")
+	g.Printf("}\n")
+	g.Printf("GoSeqRef* ref = [(%s)o ref];\n", typ)
+	g.Printf("GoSeq in_ = {};\n")
+	g.Printf("GoSeq out_ = {};\n")
+	g.Printf("go_seq_writeRef(&in_, ref);\n")
+	g.Printf("go_seq_send(%s_DESCRIPTOR_, %s_CAST_, &in_, &out_);\n", desc, desc)
+	g.Printf("BOOL ok = go_seq_readBool(&out_);\n")
+	g.Printf("go_seq_free(&in_);\n")
+	g.Printf("go_seq_free(&out_);\n")
+	g.Printf("if (!ok) {\n")
+	g.Printf("	return nil;\n")
+	g.Printf("}\n")
+	g.Printf("return [[%s alloc] initWithRef:ref];\n", name)
+	g.Outdent()
+	g.Printf("}\n")
 }
 
 func (g *objcGen) genStructH(obj *types.TypeName, t *types.Struct) {
-	g.Printf("@interface %s%s : NSObject {\n", g.namePrefix, obj.Name())
+	g.genDoc(obj.Name())
+	g.Printf("@interface %s%s : NSObject", g.namePrefix, obj.Name())
+	if ifaces := implementedIfaces(obj, g.ifaces); len(ifaces) > 0 {
+		var protos []string
+		for _, iface := range ifaces {
+			protos = append(protos, g.refClass(iface.Type()))
+		}
+		g.Printf(" <%s>", strings.Join(protos, ", "))
+	}
+	g.Printf(" {\n")
 	g.Printf("}\n")
 	g.Printf("@property(strong, readonly) id ref;\n")
 	g.Printf("\n")
@@ -381,15 +934,17 @@ func (g *objcGen) genStructH(obj *types.TypeName, t *types.Struct) {
 
 	// accessors to exported fields.
 	for _, f := range exportedFields(t) {
-		// TODO(hyangah): error type field?
 		name, typ := f.Name(), g.objcType(f.Type())
+		g.genDoc(obj.Name() + "." + name)
 		g.Printf("- (%s)%s;\n", typ, name)
+		g.genDoc(obj.Name() + "." + name)
 		g.Printf("- (void)set%s:(%s)v;\n", name, typ)
 	}
 
 	// exported methods
 	for _, m := range exportedMethodSet(types.NewPointer(obj.Type())) {
 		s := g.funcSummary(m)
+		g.genDoc(docKey(m))
 		g.Printf("- %s;\n", s.asMethod(g))
 	}
 	g.Printf("@end\n")
@@ -400,7 +955,8 @@ func (g *objcGen) genStructM(obj *types.TypeName, t *types.Struct) {
 	methods := exportedMethodSet(types.NewPointer(obj.Type()))
 
 	desc := fmt.Sprintf("_GO_%s_%s", g.pkgName, obj.Name())
-	g.Printf("#define %s_DESCRIPTOR_ \"go.%s.%s\"\n", desc, g.pkgName, obj.Name())
+	g.Printf("#define %s_DESCRIPTOR_ %q\n", desc, seqDesc(g.pkg, obj.Name()))
+	g.Printf("#define %s_CAST_ (0x00e)\n", desc)
 	for i, f := range fields {
 		g.Printf("#define %s_FIELD_%s_GET_ (0x%x0f)\n", desc, f.Name(), i)
 		g.Printf("#define %s_FIELD_%s_SET_ (0x%x1f)\n", desc, f.Name(), i)
@@ -422,7 +978,6 @@ func (g *objcGen) genStructM(obj *types.TypeName, t *types.Struct) {
 
 	for _, f := range fields {
 		// getter
-		// TODO(hyangah): support error type fields?
 		s := &funcSummary{
 			name: f.Name(),
 			ret:  g.objcType(f.Type()),
@@ -487,6 +1042,8 @@ func (g *objcGen) objcType(typ types.Type) string {
 		case types.Uint8:
 			// byte is an alias of uint8, and the alias is lost.
 			return "byte"
+		case types.Uint:
+			return "NSUInteger"
 		case types.Uint16:
 			return "uint16_t"
 		case types.Uint32:
@@ -503,33 +1060,48 @@ func (g *objcGen) objcType(typ types.Type) string {
 			g.errorf("unsupported type: %s", typ)
 			return "TODO"
 		}
-	case *types.Slice:
-		elem := g.objcType(typ.Elem())
+	case *types.Slice, *types.Array:
+		elem := g.objcType(arrayElem(typ))
 		// Special case: NSData seems to be a better option for byte slice.
 		if elem == "byte" {
 			return "NSData*"
 		}
-		// TODO(hyangah): support other slice types: NSArray or CFArrayRef.
-		// Investigate the performance implication.
+		// TODO(hyangah): Investigate the performance implication
+		// of boxing numbers. CFArrayRef or NSData may be better.
+		switch g.seqType(typ) {
+		case "BoolArray", "IntArray", "Int8Array", "Int16Array", "Int32Array",
+			"Int64Array", "UintArray", "Uint16Array", "Uint32Array", "Uint64Array",
+			"Float32Array", "Float64Array", "UTF8Array", "RefArray":
+			return "NSArray*"
+		}
 		g.errorf("unsupported type: %s", typ)
 		return "TODO"
+	case *types.Chan:
+		return "GoSeqStream*"
+	case *types.Map:
+		return "NSDictionary*"
 	case *types.Pointer:
+		if isDirectBuffer(typ) {
+			return "NSMutableData*"
+		}
 		if _, ok := typ.Elem().(*types.Named); ok {
-			return g.objcType(typ.Elem()) + "*"
+			// Structs are referred to by pointer, whether
+			// they are passed by value or by pointer in Go.
+			return g.objcType(typ.Elem())
 		}
 		g.errorf("unsupported pointer to type: %s", typ)
 		return "TODO"
 	case *types.Named:
 		n := typ.Obj()
-		if n.Pkg() != g.pkg {
-			g.errorf("type %s is in package %s; only types defined in package %s is supported", n.Name(), n.Pkg().Name(), g.pkg.Name())
+		if !isBound(n.Pkg(), g.pkg, g.allPkg) {
+			g.errorf("type %s is in package %s; only types defined in package %s or a package bound with it are supported", n.Name(), n.Pkg().Name(), g.pkg.Name())
 			return "TODO"
 		}
 		switch typ.Underlying().(type) {
 		case *types.Interface:
-			return g.namePrefix + n.Name() + "*"
+			return "id<" + g.refClass(typ) + ">"
 		case *types.Struct:
-			return g.namePrefix + n.Name()
+			return g.refClass(typ) + "*"
 		}
 		g.errorf("unsupported, named type %s", typ)
 		return "TODO"
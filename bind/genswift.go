@@ -0,0 +1,390 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bind
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/tools/go/types"
+)
+
+// swiftGen generates a Swift API wrapping the Objective-C API
+// generated by objcGen for the same package. The declarations of the
+// package are nested in an enum named after it, without the prefix of
+// their Objective-C names:
+//
+//   - type aliases of the classes of structs and of the protocols of
+//     interfaces;
+//   - static functions, throwing the errors returned by Go and
+//     returning several values as a tuple;
+//   - static properties for variables and constants;
+//   - static checked casts.
+//
+// The values that may be nil, slices, *seq.DirectBuffer, structs and
+// interfaces, have an optional type. Methods are called on the
+// Objective-C classes, which Swift imports as throwing if they return
+// an error.
+//
+// Structs stay classes rather than Swift structs: a Go struct can only
+// be created by Go, and its value is shared with Go by reference.
+type swiftGen struct {
+	*objcGen
+}
+
+const swiftPreamble = `// Swift API for talking to %s Go package.
+//   gobind -lang=swift %s
+//
+// File is generated by gobind. Do not edit.
+
+import Foundation
+
+`
+
+// genDoc generates the doc comment of the declaration key of pkgDocs.
+func (g *swiftGen) genDoc(key string) {
+	for _, l := range g.docs.lines(key, g.pkg, g.docNames) {
+		if l == "" {
+			g.Printf("///\n")
+		} else {
+			g.Printf("/// %s\n", l)
+		}
+	}
+}
+
+func (g *swiftGen) gen() error {
+	if err := checkPrefix(g.prefix); err != nil {
+		return err
+	}
+	g.init()
+	for name := range g.docNames {
+		switch g.pkg.Scope().Lookup(name).(type) {
+		case *types.TypeName:
+			g.docNames[name] = name
+		default:
+			g.docNames[name] = swiftName(name)
+		}
+	}
+
+	g.Printf(swiftPreamble, g.pkg.Path(), g.pkg.Path())
+	g.genDoc("")
+	g.Printf("public enum %s {\n", capitalize(g.pkgName))
+	g.Indent()
+
+	// Declarations are separated by blank lines, without the
+	// indentation of the enum.
+	empty := true
+	sep := func() {
+		if !empty {
+			g.Outdent()
+			g.Printf("\n")
+			g.Indent()
+		}
+		empty = false
+	}
+
+	for _, obj := range g.names {
+		switch obj.Type().Underlying().(type) {
+		case *types.Struct:
+			g.genDoc(obj.Name())
+			g.Printf("public typealias %s = %s\n", obj.Name(), g.refClass(obj.Type()))
+			empty = false
+		case *types.Interface:
+			// Swift renames a protocol with the name of a class.
+			g.genDoc(obj.Name())
+			g.Printf("public typealias %s = %sProtocol\n", obj.Name(), g.refClass(obj.Type()))
+			empty = false
+		}
+	}
+
+	if len(g.consts) > 0 || len(g.errTypes) > 0 {
+		sep()
+	}
+	for _, obj := range g.consts {
+		if t := constType(obj); t != nil && checkConstValue(obj, t) == nil {
+			g.genDoc(obj.Name())
+			g.Printf("public static let %s: %s = %s%s\n", swiftName(obj.Name()), g.swiftType(t), g.namePrefix, obj.Name())
+		}
+	}
+	for _, obj := range g.errTypes {
+		g.Printf("public static let %s: String = %s%sDomain\n", swiftName(obj.Name()+"Domain"), g.namePrefix, obj.Name())
+	}
+
+	for _, obj := range g.vars {
+		sep()
+		g.genVar(obj)
+	}
+	for _, obj := range g.funcs {
+		sep()
+		g.genFunc(obj)
+	}
+
+	for _, obj := range g.names {
+		switch obj.Type().Underlying().(type) {
+		case *types.Struct, *types.Interface:
+			sep()
+			g.Printf("public static func cast%s(_ o: Any?) -> %s? {\n", obj.Name(), obj.Name())
+			g.Printf("    return %sCast%s(o)\n", g.namePrefix, obj.Name())
+			g.Printf("}\n")
+		}
+	}
+
+	g.Outdent()
+	g.Printf("}\n")
+
+	if len(g.err) > 0 {
+		return g.err
+	}
+	return nil
+}
+
+// genVar generates a static property for a package variable.
+func (g *swiftGen) genVar(o *types.Var) {
+	if isErrorType(o.Type()) {
+		g.errorf("%s: variables of type error are not supported", o.Name())
+		return
+	}
+	g.genDoc(o.Name())
+	g.Printf("public static var %s: %s {\n", swiftName(o.Name()), g.swiftType(o.Type()))
+	g.Indent()
+	g.Printf("get { return %s }\n", g.swiftResult(fmt.Sprintf("%s%s()", g.namePrefix, o.Name()), o.Type()))
+	g.Printf("set { %sSet%s(newValue) }\n", g.namePrefix, o.Name())
+	g.Outdent()
+	g.Printf("}\n")
+}
+
+// genFunc generates the static function calling the Objective-C
+// function of o.
+func (g *swiftGen) genFunc(o *types.Func) {
+	s := g.funcSummary(o)
+	if s == nil {
+		return
+	}
+	sig := o.Type().(*types.Signature)
+	res := sig.Results()
+
+	var params, args []string
+	for _, p := range s.params {
+		params = append(params, fmt.Sprintf("_ %s: %s", swiftIdent(p.name), g.swiftType(p.typ)))
+		args = append(args, swiftIdent(p.name))
+	}
+	g.genDoc(o.Name())
+	g.Printf("public static func %s(%s)", swiftName(o.Name()), strings.Join(params, ", "))
+	if returnsError(sig) {
+		g.Printf(" throws")
+	}
+	switch n := numValues(sig); n {
+	case 0:
+	case 1:
+		g.Printf(" -> %s", g.swiftType(res.At(0).Type()))
+	default:
+		var vals []string
+		for i := 0; i < n; i++ {
+			v := res.At(i)
+			t := g.swiftType(v.Type())
+			if v.Name() != "" && v.Name() != "_" {
+				t = swiftIdent(v.Name()) + ": " + t
+			}
+			vals = append(vals, t)
+		}
+		g.Printf(" -> (%s)", strings.Join(vals, ", "))
+	}
+	g.Printf(" {\n")
+	g.Indent()
+	defer func() {
+		g.Outdent()
+		g.Printf("}\n")
+	}()
+
+	if s.returnsVal() {
+		call := fmt.Sprintf("%s%s(%s)", g.namePrefix, o.Name(), strings.Join(args, ", "))
+		g.Printf("return %s\n", g.swiftResult(call, s.retParams[0].typ))
+		return
+	}
+
+	// The results are written to variables passed by reference.
+	var vals []string
+	for _, p := range s.retParams {
+		name := swiftIdent(p.name)
+		args = append(args, "&"+name)
+		if isErrorType(p.typ) {
+			g.Printf("var %s: NSError?\n", name)
+			continue
+		}
+		decl, val := g.swiftOut(name, p.typ)
+		g.Printf("var %s\n", decl)
+		vals = append(vals, val)
+	}
+	call := fmt.Sprintf("%s%s(%s)", g.namePrefix, o.Name(), strings.Join(args, ", "))
+	if returnsError(sig) {
+		g.Printf("if !%s {\n", call)
+		g.Printf("    throw error!\n")
+		g.Printf("}\n")
+	} else {
+		g.Printf("%s\n", call)
+	}
+	switch len(vals) {
+	case 0:
+	case 1:
+		g.Printf("return %s\n", vals[0])
+	default:
+		g.Printf("return (%s)\n", strings.Join(vals, ", "))
+	}
+}
+
+// swiftResult returns the Swift expression converting the value of
+// type typ returned by the Objective-C expression x.
+func (g *swiftGen) swiftResult(x string, typ types.Type) string {
+	switch typ := typ.(type) {
+	case *types.Slice, *types.Array:
+		if g.objcType(typ) == "NSArray*" {
+			return fmt.Sprintf("%s as? %s", x, strings.TrimSuffix(g.swiftType(typ), "?"))
+		}
+	case *types.Map:
+		return fmt.Sprintf("%s as? %s ?? [:]", x, g.swiftType(typ))
+	}
+	return x
+}
+
+// swiftOut returns the declaration of the variable name receiving a
+// result of type typ from Objective-C, and the Swift expression
+// converting its value.
+func (g *swiftGen) swiftOut(name string, typ types.Type) (decl, val string) {
+	st := g.swiftType(typ)
+	switch ot := g.objcType(typ); {
+	case ot == "BOOL":
+		return name + " = ObjCBool(false)", name + ".boolValue"
+	case !strings.HasSuffix(ot, "*") && !strings.HasPrefix(ot, "id<"):
+		return name + ": " + st + " = 0", name
+	case ot == "NSString*":
+		return name + ": NSString?", name + "! as String"
+	case ot == "NSData*":
+		return name + ": NSData?", name + " as Data?"
+	case ot == "NSMutableData*":
+		return name + ": NSMutableData?", name
+	case ot == "NSArray*", ot == "NSDictionary*":
+		return name + ": " + strings.TrimSuffix(ot, "*") + "?", g.swiftResult(name, typ)
+	case strings.HasPrefix(ot, "id<"):
+		return name + ": " + g.refClass(typ) + "Protocol?", name
+	default:
+		return name + ": " + strings.TrimSuffix(ot, "*") + "?", name
+	}
+}
+
+// swiftType returns the Swift type of the values of type typ passed
+// to or returned by the Objective-C API. The values that are never
+// nil have a non-optional type.
+func (g *swiftGen) swiftType(typ types.Type) string {
+	switch typ := typ.(type) {
+	case *types.Basic:
+		switch typ.Kind() {
+		case types.Bool:
+			return "Bool"
+		case types.Int:
+			return "Int32" // int in Objective-C
+		case types.Int8:
+			return "Int8"
+		case types.Int16:
+			return "Int16"
+		case types.Int32:
+			return "Int32"
+		case types.Int64:
+			return "Int64"
+		case types.Uint8:
+			return "UInt8"
+		case types.Uint:
+			return "UInt"
+		case types.Uint16:
+			return "UInt16"
+		case types.Uint32:
+			return "UInt32"
+		case types.Uint64:
+			return "UInt64"
+		case types.Float32:
+			return "Float"
+		case types.Float64:
+			return "Double"
+		case types.String:
+			return "String"
+		}
+	case *types.Slice, *types.Array:
+		// Slices may be nil.
+		if g.objcType(typ) == "NSData*" {
+			return "Data?"
+		}
+		return "[" + g.swiftElem(arrayElem(typ)) + "]?"
+	case *types.Map:
+		return "[" + g.swiftElem(typ.Key()) + ": " + g.swiftElem(typ.Elem()) + "]"
+	case *types.Chan:
+		return "GoSeqStream"
+	case *types.Pointer:
+		if isDirectBuffer(typ) {
+			return "NSMutableData?"
+		}
+		return g.swiftType(typ.Elem())
+	case *types.Named:
+		// Go nil pointers and interfaces are nil.
+		return g.swiftClass(typ) + "?"
+	}
+	g.errorf("unsupported type: %s", typ)
+	return "TODO"
+}
+
+// swiftElem returns the Swift type of the elements of type typ of the
+// arrays and dictionaries, which never hold nil.
+func (g *swiftGen) swiftElem(typ types.Type) string {
+	if p, ok := typ.(*types.Pointer); ok && !isDirectBuffer(p) {
+		typ = p.Elem()
+	}
+	if n, ok := typ.(*types.Named); ok {
+		return g.swiftClass(n)
+	}
+	return g.swiftType(typ)
+}
+
+// swiftClass returns the Swift name of the class or protocol of the
+// struct or interface type typ.
+func (g *swiftGen) swiftClass(typ *types.Named) string {
+	if g.objcType(typ) == "TODO" {
+		return "TODO" // reported by objcType
+	}
+	n := typ.Obj()
+	if n.Pkg().Path() != g.pkg.Path() {
+		// Types of other bound packages are nested in the
+		// enum of their package.
+		return capitalize(n.Pkg().Name()) + "." + n.Name()
+	}
+	return n.Name()
+}
+
+// swiftName returns the name of the Swift declaration wrapping the Go
+// declaration name.
+func swiftName(name string) string {
+	return swiftIdent(lowerCamel(name))
+}
+
+// swiftKeywords are the Swift keywords that cannot be identifiers.
+var swiftKeywords = map[string]bool{
+	"associatedtype": true, "class": true, "deinit": true, "enum": true,
+	"extension": true, "fileprivate": true, "func": true, "import": true,
+	"init": true, "inout": true, "internal": true, "let": true, "open": true,
+	"operator": true, "private": true, "protocol": true, "public": true,
+	"rethrows": true, "static": true, "struct": true, "subscript": true,
+	"typealias": true, "var": true, "break": true, "case": true,
+	"continue": true, "default": true, "defer": true, "do": true, "else": true,
+	"fallthrough": true, "for": true, "guard": true, "if": true, "in": true,
+	"repeat": true, "return": true, "switch": true, "where": true,
+	"while": true, "as": true, "catch": true, "false": true, "is": true,
+	"nil": true, "self": true, "super": true, "throw": true, "throws": true,
+	"true": true, "try": true,
+}
+
+// swiftIdent returns name as a Swift identifier, quoting keywords.
+func swiftIdent(name string) string {
+	if swiftKeywords[name] {
+		return fmt.Sprintf("`%s`", name)
+	}
+	return name
+}
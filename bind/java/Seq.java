@@ -68,6 +68,16 @@ public class Seq {
 	public native long readInt64();
 	public long readInt() { return readInt64(); }
 
+	// Unsigned integers are widened to the next larger Java type,
+	// except for uint64 and uint which have none: values above
+	// Long.MAX_VALUE are read as negative numbers, and negative
+	// numbers are written as the unsigned value with the same bits.
+	// Writing a value out of range of the Go type keeps the low bits.
+	public int readUint16() { return readInt16() & 0xffff; }
+	public long readUint32() { return readInt32() & 0xffffffffL; }
+	public long readUint64() { return readInt64(); }
+	public long readUint() { return readInt64(); }
+
 	public native float readFloat32();
 	public native double readFloat64();
 	public native String readUTF16();
@@ -81,12 +91,321 @@ public class Seq {
 	public native void writeInt64(long v);
 	public void writeInt(long v) { writeInt64(v); }
 
+	public void writeUint16(int v) { writeInt16((short)v); }
+	public void writeUint32(long v) { writeInt32((int)v); }
+	public void writeUint64(long v) { writeInt64(v); }
+	public void writeUint(long v) { writeInt64(v); }
+
 	public native void writeFloat32(float v);
 	public native void writeFloat64(double v);
 	public native void writeUTF16(String v);
 	public void writeString(String v) { writeUTF16(v); }
 	public native void writeByteArray(byte[] v);
 
+	// An error is encoded as its message, with null standing for no
+	// error, followed by the name of its type and its code.
+	public GoException readError() {
+		String msg = readString();
+		if (msg == null) {
+			return null;
+		}
+		String type = readString();
+		long code = readInt64();
+		return new GoException(msg, code, type);
+	}
+
+	public void writeError(Exception e) {
+		if (e == null) {
+			writeString(null);
+			return;
+		}
+		String msg = e.getMessage();
+		if (msg == null || msg.isEmpty()) {
+			msg = e.toString();
+		}
+		writeString(msg);
+		if (e instanceof GoException) {
+			GoException ge = (GoException)e;
+			writeString(ge.getType());
+			writeInt64(ge.getCode());
+		} else {
+			writeString(e.getClass().getName());
+			writeInt64(0);
+		}
+	}
+
+	// Arrays other than byte arrays are encoded as their length
+	// followed by each element. A null array is written as an
+	// empty array and an empty array is read as null, matching
+	// the byte array encoding.
+	public int readArrayLen() { return readInt32(); }
+
+	public boolean[] readBoolArray() {
+		int n = readArrayLen();
+		if (n == 0) {
+			return null;
+		}
+		boolean[] v = new boolean[n];
+		for (int i = 0; i < n; i++) {
+			v[i] = readBool();
+		}
+		return v;
+	}
+
+	public long[] readIntArray() { return readInt64Array(); }
+
+	public byte[] readInt8Array() {
+		int n = readArrayLen();
+		if (n == 0) {
+			return null;
+		}
+		byte[] v = new byte[n];
+		for (int i = 0; i < n; i++) {
+			v[i] = readInt8();
+		}
+		return v;
+	}
+
+	public short[] readInt16Array() {
+		int n = readArrayLen();
+		if (n == 0) {
+			return null;
+		}
+		short[] v = new short[n];
+		for (int i = 0; i < n; i++) {
+			v[i] = readInt16();
+		}
+		return v;
+	}
+
+	public int[] readInt32Array() {
+		int n = readArrayLen();
+		if (n == 0) {
+			return null;
+		}
+		int[] v = new int[n];
+		for (int i = 0; i < n; i++) {
+			v[i] = readInt32();
+		}
+		return v;
+	}
+
+	public long[] readInt64Array() {
+		int n = readArrayLen();
+		if (n == 0) {
+			return null;
+		}
+		long[] v = new long[n];
+		for (int i = 0; i < n; i++) {
+			v[i] = readInt64();
+		}
+		return v;
+	}
+
+	public int[] readUint16Array() {
+		int n = readArrayLen();
+		if (n == 0) {
+			return null;
+		}
+		int[] v = new int[n];
+		for (int i = 0; i < n; i++) {
+			v[i] = readUint16();
+		}
+		return v;
+	}
+
+	public long[] readUint32Array() {
+		int n = readArrayLen();
+		if (n == 0) {
+			return null;
+		}
+		long[] v = new long[n];
+		for (int i = 0; i < n; i++) {
+			v[i] = readUint32();
+		}
+		return v;
+	}
+
+	public long[] readUint64Array() { return readInt64Array(); }
+	public long[] readUintArray() { return readInt64Array(); }
+
+	public float[] readFloat32Array() {
+		int n = readArrayLen();
+		if (n == 0) {
+			return null;
+		}
+		float[] v = new float[n];
+		for (int i = 0; i < n; i++) {
+			v[i] = readFloat32();
+		}
+		return v;
+	}
+
+	public double[] readFloat64Array() {
+		int n = readArrayLen();
+		if (n == 0) {
+			return null;
+		}
+		double[] v = new double[n];
+		for (int i = 0; i < n; i++) {
+			v[i] = readFloat64();
+		}
+		return v;
+	}
+
+	public String[] readStringArray() {
+		int n = readArrayLen();
+		if (n == 0) {
+			return null;
+		}
+		String[] v = new String[n];
+		for (int i = 0; i < n; i++) {
+			v[i] = readString();
+		}
+		return v;
+	}
+
+	public void writeArrayLen(int n) { writeInt32(n); }
+
+	public void writeBoolArray(boolean[] v) {
+		if (v == null) {
+			writeArrayLen(0);
+			return;
+		}
+		writeArrayLen(v.length);
+		for (boolean e : v) {
+			writeBool(e);
+		}
+	}
+
+	public void writeIntArray(long[] v) { writeInt64Array(v); }
+
+	public void writeInt8Array(byte[] v) {
+		if (v == null) {
+			writeArrayLen(0);
+			return;
+		}
+		writeArrayLen(v.length);
+		for (byte e : v) {
+			writeInt8(e);
+		}
+	}
+
+	public void writeInt16Array(short[] v) {
+		if (v == null) {
+			writeArrayLen(0);
+			return;
+		}
+		writeArrayLen(v.length);
+		for (short e : v) {
+			writeInt16(e);
+		}
+	}
+
+	public void writeInt32Array(int[] v) {
+		if (v == null) {
+			writeArrayLen(0);
+			return;
+		}
+		writeArrayLen(v.length);
+		for (int e : v) {
+			writeInt32(e);
+		}
+	}
+
+	public void writeInt64Array(long[] v) {
+		if (v == null) {
+			writeArrayLen(0);
+			return;
+		}
+		writeArrayLen(v.length);
+		for (long e : v) {
+			writeInt64(e);
+		}
+	}
+
+	public void writeUint16Array(int[] v) {
+		if (v == null) {
+			writeArrayLen(0);
+			return;
+		}
+		writeArrayLen(v.length);
+		for (int e : v) {
+			writeUint16(e);
+		}
+	}
+
+	public void writeUint32Array(long[] v) {
+		if (v == null) {
+			writeArrayLen(0);
+			return;
+		}
+		writeArrayLen(v.length);
+		for (long e : v) {
+			writeUint32(e);
+		}
+	}
+
+	public void writeUint64Array(long[] v) { writeInt64Array(v); }
+	public void writeUintArray(long[] v) { writeInt64Array(v); }
+
+	public void writeFloat32Array(float[] v) {
+		if (v == null) {
+			writeArrayLen(0);
+			return;
+		}
+		writeArrayLen(v.length);
+		for (float e : v) {
+			writeFloat32(e);
+		}
+	}
+
+	public void writeFloat64Array(double[] v) {
+		if (v == null) {
+			writeArrayLen(0);
+			return;
+		}
+		writeArrayLen(v.length);
+		for (double e : v) {
+			writeFloat64(e);
+		}
+	}
+
+	public void writeStringArray(String[] v) {
+		if (v == null) {
+			writeArrayLen(0);
+			return;
+		}
+		writeArrayLen(v.length);
+		for (String e : v) {
+			writeString(e);
+		}
+	}
+
+	// writeRefArray writes the refs of an array of proxies or
+	// stubs. Reading them back requires the element type, so the
+	// generated code reads each Ref with readRef.
+	public void writeRefArray(Seq.Object[] v) {
+		if (v == null) {
+			writeArrayLen(0);
+			return;
+		}
+		writeArrayLen(v.length);
+		for (Seq.Object e : v) {
+			writeObject(e);
+		}
+	}
+
+	// writeObject writes the ref of a proxy or stub, or the null
+	// ref 0 for null, which Go reads as nil or the zero value.
+	public void writeObject(Seq.Object v) {
+		if (v == null) {
+			writeInt32(0);
+			return;
+		}
+		writeRef(v.ref());
+	}
+
 	public void writeRef(Ref ref) {
 		tracker.inc(ref);
 		writeInt32(ref.refnum);
@@ -97,6 +416,53 @@ public class Seq {
 		return tracker.get(refnum);
 	}
 
+	// A direct ByteBuffer is written as a Ref to a DirectBuffer
+	// holding it, followed by the address and the capacity of its
+	// memory, which Go reads and writes without copying. Go keeps
+	// the buffer alive until it is done with the Ref.
+	public void writeDirectBuffer(java.nio.ByteBuffer v) {
+		if (v == null) {
+			writeInt32(0);
+			writeInt64(0);
+			writeInt64(-1);
+			return;
+		}
+		if (!v.isDirect()) {
+			throw new IllegalArgumentException("not a direct ByteBuffer");
+		}
+		writeRef(new DirectBuffer(v).ref());
+		writeDirectBufferMemory(v);
+	}
+
+	private native void writeDirectBufferMemory(java.nio.ByteBuffer v);
+
+	public java.nio.ByteBuffer readDirectBuffer() {
+		int refnum = readInt32();
+		readInt64(); // address
+		readInt64(); // capacity
+		if (refnum <= 0) {
+			return null;
+		}
+		return ((DirectBuffer)tracker.get(refnum).obj).buf;
+	}
+
+	// A DirectBuffer pins a direct ByteBuffer passed to Go.
+	static final class DirectBuffer implements Seq.Object {
+		final java.nio.ByteBuffer buf;
+		private final Ref ref;
+
+		DirectBuffer(java.nio.ByteBuffer buf) {
+			this.buf = buf;
+			this.ref = createRef(this);
+		}
+
+		public Ref ref() { return ref; }
+
+		public void call(int code, Seq in, Seq out) {
+			throw new RuntimeException("DirectBuffer has no methods");
+		}
+	}
+
 	static native void initSeq();
 
 	// Informs the Go ref tracker that Java is done with this ref.
@@ -107,6 +473,39 @@ public class Seq {
 		return tracker.createRef(o);
 	}
 
+	// newObject returns the object of the class named className, of
+	// another bound package, referring to the Go object ref. The
+	// classes of Go structs and the Proxy classes of Go interfaces have
+	// no public constructors, so that only the bindings create them.
+	public static Object newObject(String className, Ref ref) {
+		try {
+			java.lang.reflect.Constructor<?> c = Class.forName(className).getDeclaredConstructor(Ref.class);
+			c.setAccessible(true);
+			return c.newInstance(ref);
+		} catch (Exception e) {
+			throw new RuntimeException("cannot create " + className, e);
+		}
+	}
+
+	// CAST is the code of the function of each bound Go struct and
+	// interface type reporting whether a Go object has that type.
+	static final int CAST = 0x00e;
+
+	// canCast reports whether o refers to a Go object of the struct
+	// or interface type of descriptor. It implements the checked
+	// casts of the generated classes.
+	public static boolean canCast(String descriptor, Seq.Object o) {
+		Ref ref = o.ref();
+		if (ref.refnum > 0) {
+			return false; // a Java object
+		}
+		Seq in = new Seq();
+		Seq out = new Seq();
+		in.writeRef(ref);
+		send(descriptor, CAST, in, out);
+		return out.readBool();
+	}
+
 	// sends a function invocation request to Go.
 	//
 	// Blocks until the function completes.
@@ -177,6 +576,113 @@ public class Seq {
 		public void call(int code, Seq in, Seq out);
 	}
 
+	// A Stream receives the values of a Go receive-only channel.
+	//
+	// Values are received either by iterating over the stream, which
+	// blocks until each value is available, or by passing a Listener
+	// to listen. The stream ends when the channel is closed, or
+	// when close is called.
+	public static abstract class Stream<T> implements java.util.Iterator<T>, java.io.Closeable {
+		private static final String DESCRIPTOR = "go.seq.Stream";
+		private static final int NEXT = 1;
+		private static final int CLOSE = 2;
+
+		private final Ref ref;
+		private boolean fetched;
+		private boolean done;
+		private T next;
+
+		protected Stream(Ref ref) {
+			this.ref = ref;
+		}
+
+		// read reads a value of the stream written by Go.
+		protected abstract T read(Seq in);
+
+		public synchronized boolean hasNext() {
+			if (!fetched && !done) {
+				Seq in = new Seq();
+				Seq out = new Seq();
+				in.writeRef(ref);
+				Seq.send(DESCRIPTOR, NEXT, in, out);
+				if (out.readBool()) {
+					next = read(out);
+					fetched = true;
+				} else {
+					done = true;
+				}
+			}
+			return !done;
+		}
+
+		public synchronized T next() {
+			if (!hasNext()) {
+				throw new java.util.NoSuchElementException();
+			}
+			fetched = false;
+			T v = next;
+			next = null;
+			return v;
+		}
+
+		public void remove() {
+			throw new UnsupportedOperationException();
+		}
+
+		// close cancels the stream. A blocked call to hasNext or next
+		// returns as if the channel was closed.
+		public void close() {
+			Seq in = new Seq();
+			Seq out = new Seq();
+			in.writeRef(ref);
+			Seq.send(DESCRIPTOR, CLOSE, in, out);
+		}
+
+		// listen receives the values of the stream on a new thread,
+		// passing each value to l, then calls l.onClose.
+		public void listen(final Listener<T> l) {
+			new Thread(new Runnable() {
+				public void run() {
+					while (hasNext()) {
+						l.onNext(next());
+					}
+					l.onClose();
+				}
+			}).start();
+		}
+
+		public interface Listener<T> {
+			public void onNext(T v);
+			public void onClose();
+		}
+	}
+
+	// A GoException is an error returned by Go.
+	//
+	// The type of the exception is the import path and name of the
+	// type of the Go error, such as "net/url.Error". The code is the
+	// result of the Code method of the Go error, or 0 if it has none.
+	// Generated exception classes for the error types of a bound
+	// package extend GoException, and may be thrown by Java
+	// implementations of Go interfaces.
+	public static class GoException extends Exception {
+		private final long code;
+		private final String type;
+
+		public GoException(String message) {
+			this(message, 0, "");
+		}
+
+		public GoException(String message, long code, String type) {
+			super(message);
+			this.code = code;
+			this.type = type;
+		}
+
+		public long getCode() { return code; }
+		public String getType() { return type; }
+	}
+
 	// A Ref is an object tagged with an integer for passing back and
 	// forth across the language boundary.
 	//
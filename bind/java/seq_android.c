@@ -449,3 +449,18 @@ Java_go_Seq_setContext(JNIEnv* env, jclass clazz, jobject ctx) {
 	}
 	setContext(vm, (*env)->NewGlobalRef(env, ctx));
 }
+
+JNIEXPORT void JNICALL
+Java_go_Seq_writeDirectBufferMemory(JNIEnv *env, jobject obj, jobject v) {
+	// The memory of a direct ByteBuffer is passed as its
+	// (address, capacity) pair encoded as two int64 values.
+	// The address of an empty buffer may be NULL: its memory is
+	// passed as (0, 0).
+	void *ptr = (*env)->GetDirectBufferAddress(env, v);
+	jlong cap = 0;
+	if (ptr != NULL) {
+		cap = (*env)->GetDirectBufferCapacity(env, v);
+	}
+	MEM_WRITE(int64_t) = (jlong)(uintptr_t)ptr;
+	MEM_WRITE(int64_t) = cap;
+}
@@ -34,6 +34,27 @@ typedef struct GoSeq {
 - (id)initWithRefnum:(int32_t)refnum obj:(id)obj;
 @end
 
+// GoSeqStream receives the values of a Go receive-only channel.
+// Values are boxed: numbers and booleans in NSNumber.
+// The stream ends when the channel is closed, or when it is canceled.
+@interface GoSeqStream : NSObject {
+}
+@property(strong, readonly) GoSeqRef *ref;
+
+- (id)initWithRef:(GoSeqRef *)ref read:(id (^)(GoSeq *in))read;
+
+// next blocks until the next value is received and stores it in
+// value. It returns NO when the stream has ended.
+- (BOOL)next:(id *)value;
+
+// subscribe receives the values of the stream on a background
+// queue, calling onNext for each value, then onDone if not nil.
+- (void)subscribe:(void (^)(id value))onNext done:(void (^)(void))onDone;
+
+// cancel ends the stream. A blocked call to next returns NO.
+- (void)cancel;
+@end
+
 // go_seq_free releases resources of the GoSeq.
 extern void go_seq_free(GoSeq *seq);
 
@@ -43,6 +64,10 @@ extern int8_t go_seq_readInt8(GoSeq *seq);
 extern int16_t go_seq_readInt16(GoSeq *seq);
 extern int32_t go_seq_readInt32(GoSeq *seq);
 extern int64_t go_seq_readInt64(GoSeq *seq);
+extern NSUInteger go_seq_readUint(GoSeq *seq);
+extern uint16_t go_seq_readUint16(GoSeq *seq);
+extern uint32_t go_seq_readUint32(GoSeq *seq);
+extern uint64_t go_seq_readUint64(GoSeq *seq);
 extern float go_seq_readFloat32(GoSeq *seq);
 extern double go_seq_readFloat64(GoSeq *seq);
 extern GoSeqRef *go_seq_readRef(GoSeq *seq);
@@ -55,6 +80,10 @@ extern void go_seq_writeInt8(GoSeq *seq, int8_t v);
 extern void go_seq_writeInt16(GoSeq *seq, int16_t v);
 extern void go_seq_writeInt32(GoSeq *seq, int32_t v);
 extern void go_seq_writeInt64(GoSeq *seq, int64_t v);
+extern void go_seq_writeUint(GoSeq *seq, NSUInteger v);
+extern void go_seq_writeUint16(GoSeq *seq, uint16_t v);
+extern void go_seq_writeUint32(GoSeq *seq, uint32_t v);
+extern void go_seq_writeUint64(GoSeq *seq, uint64_t v);
 extern void go_seq_writeFloat32(GoSeq *seq, float v);
 extern void go_seq_writeFloat64(GoSeq *seq, double v);
 extern void go_seq_writeRef(GoSeq *seq, GoSeqRef *v);
@@ -64,6 +93,47 @@ extern void go_seq_writeUTF8(GoSeq *seq, NSString *v);
 // data should be valid until the the subsequent go_seq_send call completes.
 extern void go_seq_writeByteArray(GoSeq *seq, NSData *data);
 
+// go_seq_writeDirectBuffer passes the memory of data to Go, which reads
+// and writes it without copying. Objective-C references are not yet
+// tracked by Go, so the data must be valid until the subsequent
+// go_seq_send call completes. go_seq_readDirectBuffer returns an
+// NSMutableData wrapping the memory without copying it.
+extern void go_seq_writeDirectBuffer(GoSeq *seq, NSMutableData *data);
+extern NSMutableData *go_seq_readDirectBuffer(GoSeq *seq);
+
+// Arrays other than byte arrays are encoded as their length followed
+// by each element. Elements of arrays of numbers and booleans are
+// boxed in NSNumber.
+extern int32_t go_seq_readArrayLen(GoSeq *seq);
+extern NSArray *go_seq_readBoolArray(GoSeq *seq);
+extern NSArray *go_seq_readIntArray(GoSeq *seq);
+extern NSArray *go_seq_readInt8Array(GoSeq *seq);
+extern NSArray *go_seq_readInt16Array(GoSeq *seq);
+extern NSArray *go_seq_readInt32Array(GoSeq *seq);
+extern NSArray *go_seq_readInt64Array(GoSeq *seq);
+extern NSArray *go_seq_readUintArray(GoSeq *seq);
+extern NSArray *go_seq_readUint16Array(GoSeq *seq);
+extern NSArray *go_seq_readUint32Array(GoSeq *seq);
+extern NSArray *go_seq_readUint64Array(GoSeq *seq);
+extern NSArray *go_seq_readFloat32Array(GoSeq *seq);
+extern NSArray *go_seq_readFloat64Array(GoSeq *seq);
+extern NSArray *go_seq_readUTF8Array(GoSeq *seq);
+
+extern void go_seq_writeArrayLen(GoSeq *seq, int32_t n);
+extern void go_seq_writeBoolArray(GoSeq *seq, NSArray *v);
+extern void go_seq_writeIntArray(GoSeq *seq, NSArray *v);
+extern void go_seq_writeInt8Array(GoSeq *seq, NSArray *v);
+extern void go_seq_writeInt16Array(GoSeq *seq, NSArray *v);
+extern void go_seq_writeInt32Array(GoSeq *seq, NSArray *v);
+extern void go_seq_writeInt64Array(GoSeq *seq, NSArray *v);
+extern void go_seq_writeUintArray(GoSeq *seq, NSArray *v);
+extern void go_seq_writeUint16Array(GoSeq *seq, NSArray *v);
+extern void go_seq_writeUint32Array(GoSeq *seq, NSArray *v);
+extern void go_seq_writeUint64Array(GoSeq *seq, NSArray *v);
+extern void go_seq_writeFloat32Array(GoSeq *seq, NSArray *v);
+extern void go_seq_writeFloat64Array(GoSeq *seq, NSArray *v);
+extern void go_seq_writeUTF8Array(GoSeq *seq, NSArray *v);
+
 // go_seq_send sends a function invocation request to Go.
 // It blocks until the function completes.
 // If the request is for a method, the first element in req is
@@ -131,6 +131,27 @@ int64_t go_seq_readInt64(GoSeq *seq) {
 }
 void go_seq_writeInt64(GoSeq *seq, int64_t v) { MEM_WRITE(seq, int64_t) = v; }
 
+// Unsigned integers are encoded as the signed integers of the same size.
+NSUInteger go_seq_readUint(GoSeq *seq) {
+  return (NSUInteger)go_seq_readInt64(seq); // Go-side used WriteUint.
+}
+void go_seq_writeUint(GoSeq *seq, NSUInteger v) { go_seq_writeInt64(seq, v); }
+
+uint16_t go_seq_readUint16(GoSeq *seq) {
+  return (uint16_t)go_seq_readInt16(seq);
+}
+void go_seq_writeUint16(GoSeq *seq, uint16_t v) { go_seq_writeInt16(seq, v); }
+
+uint32_t go_seq_readUint32(GoSeq *seq) {
+  return (uint32_t)go_seq_readInt32(seq);
+}
+void go_seq_writeUint32(GoSeq *seq, uint32_t v) { go_seq_writeInt32(seq, v); }
+
+uint64_t go_seq_readUint64(GoSeq *seq) {
+  return (uint64_t)go_seq_readInt64(seq);
+}
+void go_seq_writeUint64(GoSeq *seq, uint64_t v) { go_seq_writeInt64(seq, v); }
+
 float go_seq_readFloat32(GoSeq *seq) {
   float *v = MEM_READ(seq, float);
   return v == NULL ? 0 : *v;
@@ -200,6 +221,92 @@ void go_seq_writeByteArray(GoSeq *seq, NSData *data) {
   return;
 }
 
+void go_seq_writeDirectBuffer(GoSeq *seq, NSMutableData *data) {
+  go_seq_writeInt32(seq, 0); // no reference to data
+  if (data == nil) {
+    go_seq_writeInt64(seq, 0);
+    go_seq_writeInt64(seq, -1);
+    return;
+  }
+  // The bytes of an empty NSMutableData may be NULL.
+  go_seq_writeInt64(seq, (int64_t)data.mutableBytes);
+  go_seq_writeInt64(seq, data.length);
+}
+
+NSMutableData *go_seq_readDirectBuffer(GoSeq *seq) {
+  go_seq_readInt32(seq);
+  void *ptr = (void *)go_seq_readInt64(seq);
+  int64_t sz = go_seq_readInt64(seq);
+  if (sz < 0) {
+    return nil;
+  }
+  if (ptr == NULL || sz == 0) {
+    return [NSMutableData data];
+  }
+  return [NSMutableData dataWithBytesNoCopy:ptr length:sz freeWhenDone:NO];
+}
+
+int32_t go_seq_readArrayLen(GoSeq *seq) {
+  int32_t n = go_seq_readInt32(seq);
+  if (n < 0) {
+    LOG_FATAL(@"array length negative: %d", n);
+  }
+  return n;
+}
+
+void go_seq_writeArrayLen(GoSeq *seq, int32_t n) {
+  go_seq_writeInt32(seq, n);
+}
+
+// SEQ_ARRAY defines the reader and writer of an array whose elements
+// are read and written with go_seq_read##name and go_seq_write##name,
+// converting elements with the given NSNumber methods.
+#define SEQ_ARRAY(name, box, unbox)                                            \
+  NSArray *go_seq_read##name##Array(GoSeq *seq) {                              \
+    int32_t n = go_seq_readArrayLen(seq);                                      \
+    NSMutableArray *v = [NSMutableArray arrayWithCapacity:n];                  \
+    for (int32_t i = 0; i < n; i++) {                                          \
+      [v addObject:[NSNumber box:go_seq_read##name(seq)]];                     \
+    }                                                                          \
+    return v;                                                                  \
+  }                                                                            \
+  void go_seq_write##name##Array(GoSeq *seq, NSArray *v) {                     \
+    go_seq_writeArrayLen(seq, (int32_t)[v count]);                             \
+    for (NSNumber * e in v) {                                                  \
+      go_seq_write##name(seq, [e unbox]);                                      \
+    }                                                                          \
+  }
+
+SEQ_ARRAY(Bool, numberWithBool, boolValue)
+SEQ_ARRAY(Int, numberWithInt, intValue)
+SEQ_ARRAY(Int8, numberWithChar, charValue)
+SEQ_ARRAY(Int16, numberWithShort, shortValue)
+SEQ_ARRAY(Int32, numberWithInt, intValue)
+SEQ_ARRAY(Int64, numberWithLongLong, longLongValue)
+SEQ_ARRAY(Uint, numberWithUnsignedInteger, unsignedIntegerValue)
+SEQ_ARRAY(Uint16, numberWithUnsignedShort, unsignedShortValue)
+SEQ_ARRAY(Uint32, numberWithUnsignedInt, unsignedIntValue)
+SEQ_ARRAY(Uint64, numberWithUnsignedLongLong, unsignedLongLongValue)
+SEQ_ARRAY(Float32, numberWithFloat, floatValue)
+SEQ_ARRAY(Float64, numberWithDouble, doubleValue)
+
+NSArray *go_seq_readUTF8Array(GoSeq *seq) {
+  int32_t n = go_seq_readArrayLen(seq);
+  NSMutableArray *v = [NSMutableArray arrayWithCapacity:n];
+  for (int32_t i = 0; i < n; i++) {
+    NSString *e = go_seq_readUTF8(seq);
+    [v addObject:(e == NULL ? @"" : e)];
+  }
+  return v;
+}
+
+void go_seq_writeUTF8Array(GoSeq *seq, NSArray *v) {
+  go_seq_writeArrayLen(seq, (int32_t)[v count]);
+  for (NSString *e in v) {
+    go_seq_writeUTF8(seq, e);
+  }
+}
+
 void go_seq_send(char *descriptor, int code, GoSeq *req, GoSeq *res) {
   if (descriptor == NULL) {
     LOG_FATAL(@"invalid NULL descriptor");
@@ -242,6 +349,11 @@ GoSeqRef *go_seq_readRef(GoSeq *seq) {
 }
 
 void go_seq_writeRef(GoSeq *seq, GoSeqRef *v) {
+  if (v == nil) {
+    // nil is the null ref, which Go reads as nil or the zero value.
+    go_seq_writeInt32(seq, 0);
+    return;
+  }
   int32_t refnum = v.refnum;
   if (!IS_FROM_GO(refnum)) {
     LOG_FATAL(@"passing Objective-C objects is not implemented yet");
@@ -277,3 +389,59 @@ void go_seq_writeRef(GoSeq *seq, GoSeqRef *v) {
   }
 }
 @end
+
+#define _GO_SEQ_STREAM_DESCRIPTOR_ "go.seq.Stream"
+#define _GO_SEQ_STREAM_NEXT_ 1
+#define _GO_SEQ_STREAM_CLOSE_ 2
+
+@implementation GoSeqStream {
+  id (^_read)(GoSeq *);
+}
+
+- (id)initWithRef:(GoSeqRef *)ref read:(id (^)(GoSeq *in))read {
+  self = [super init];
+  if (self) {
+    _ref = ref;
+    _read = read;
+  }
+  return self;
+}
+
+- (BOOL)next:(id *)value {
+  GoSeq in_ = {};
+  GoSeq out_ = {};
+  go_seq_writeRef(&in_, self.ref);
+  go_seq_send(_GO_SEQ_STREAM_DESCRIPTOR_, _GO_SEQ_STREAM_NEXT_, &in_, &out_);
+  BOOL ok = go_seq_readBool(&out_);
+  if (ok) {
+    id v = _read(&out_);
+    if (value != NULL) {
+      *value = v;
+    }
+  }
+  go_seq_free(&in_);
+  go_seq_free(&out_);
+  return ok;
+}
+
+- (void)subscribe:(void (^)(id value))onNext done:(void (^)(void))onDone {
+  dispatch_async(dispatch_get_global_queue(DISPATCH_QUEUE_PRIORITY_DEFAULT, 0), ^{
+    id v = nil;
+    while ([self next:&v]) {
+      onNext(v);
+    }
+    if (onDone != nil) {
+      onDone();
+    }
+  });
+}
+
+- (void)cancel {
+  GoSeq in_ = {};
+  GoSeq out_ = {};
+  go_seq_writeRef(&in_, self.ref);
+  go_seq_send(_GO_SEQ_STREAM_DESCRIPTOR_, _GO_SEQ_STREAM_CLOSE_, &in_, &out_);
+  go_seq_free(&in_);
+  go_seq_free(&out_);
+}
+@end
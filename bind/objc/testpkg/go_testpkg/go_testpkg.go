@@ -17,9 +17,12 @@ func proxy_BytesAppend(out, in *seq.Buffer) {
 }
 
 func proxy_CallSSum(out, in *seq.Buffer) {
-	// Must be a Go object
+	// Must be a Go object, or null
 	param_s_ref := in.ReadRef()
-	param_s := param_s_ref.Get().(*testpkg.S)
+	var param_s *testpkg.S
+	if param_s_ref.Num != 0 {
+		param_s = param_s_ref.Get().(*testpkg.S)
+	}
 	res := testpkg.CallSSum(param_s)
 	out.WriteFloat64(res)
 }
@@ -57,15 +60,12 @@ func proxy_ReturnsError(out, in *seq.Buffer) {
 	param_b := in.ReadBool()
 	res, err := testpkg.ReturnsError(param_b)
 	out.WriteString(res)
-	if err == nil {
-		out.WriteString("")
-	} else {
-		out.WriteString(err.Error())
-	}
+	out.WriteError(err)
 }
 
 const (
-	proxyS_Descriptor         = "go.testpkg.S"
+	proxyS_Descriptor         = "go.golang.org/x/mobile/bind/objc/testpkg.S"
+	proxyS_cast_Code          = 0x00e
 	proxyS_X_Get_Code         = 0x00f
 	proxyS_X_Set_Code         = 0x01f
 	proxyS_Y_Get_Code         = 0x10f
@@ -76,6 +76,15 @@ const (
 
 type proxyS seq.Ref
 
+func proxyS_cast(out, in *seq.Buffer) {
+	ref := in.ReadRef()
+	ok := false
+	if ref.Num < 0 { // go object
+		_, ok = ref.Get().(*testpkg.S)
+	}
+	out.WriteBool(ok)
+}
+
 func proxyS_X_Set(out, in *seq.Buffer) {
 	ref := in.ReadRef()
 	v := in.ReadFloat64()
@@ -117,6 +126,7 @@ func proxyS_TryTwoStrings(out, in *seq.Buffer) {
 }
 
 func init() {
+	seq.Register(proxyS_Descriptor, proxyS_cast_Code, proxyS_cast)
 	seq.Register(proxyS_Descriptor, proxyS_X_Set_Code, proxyS_X_Set)
 	seq.Register(proxyS_Descriptor, proxyS_X_Get_Code, proxyS_X_Get)
 	seq.Register(proxyS_Descriptor, proxyS_Y_Set_Code, proxyS_Y_Set)
@@ -133,13 +143,13 @@ func proxy_Sum(out, in *seq.Buffer) {
 }
 
 func init() {
-	seq.Register("testpkg", 1, proxy_BytesAppend)
-	seq.Register("testpkg", 2, proxy_CallSSum)
-	seq.Register("testpkg", 3, proxy_CollectS)
-	seq.Register("testpkg", 4, proxy_Hello)
-	seq.Register("testpkg", 5, proxy_Hi)
-	seq.Register("testpkg", 6, proxy_Int)
-	seq.Register("testpkg", 7, proxy_NewS)
-	seq.Register("testpkg", 8, proxy_ReturnsError)
-	seq.Register("testpkg", 9, proxy_Sum)
+	seq.Register("golang.org/x/mobile/bind/objc/testpkg", 1, proxy_BytesAppend)
+	seq.Register("golang.org/x/mobile/bind/objc/testpkg", 2, proxy_CallSSum)
+	seq.Register("golang.org/x/mobile/bind/objc/testpkg", 3, proxy_CollectS)
+	seq.Register("golang.org/x/mobile/bind/objc/testpkg", 4, proxy_Hello)
+	seq.Register("golang.org/x/mobile/bind/objc/testpkg", 5, proxy_Hi)
+	seq.Register("golang.org/x/mobile/bind/objc/testpkg", 6, proxy_Int)
+	seq.Register("golang.org/x/mobile/bind/objc/testpkg", 7, proxy_NewS)
+	seq.Register("golang.org/x/mobile/bind/objc/testpkg", 8, proxy_ReturnsError)
+	seq.Register("golang.org/x/mobile/bind/objc/testpkg", 9, proxy_Sum)
 }
@@ -41,4 +41,6 @@ FOUNDATION_EXPORT BOOL GoTestpkgReturnsError(BOOL b, NSString** ret0_, NSError**
 
 FOUNDATION_EXPORT int64_t GoTestpkgSum(int64_t x, int64_t y);
 
+FOUNDATION_EXPORT GoTestpkgS* GoTestpkgCastS(id o);
+
 #endif
@@ -9,11 +9,7 @@
 
 static NSString *errDomain = @"go.golang.org/x/mobile/bind/objc/testpkg";
 
-@protocol goSeqRefInterface
--(GoSeqRef*) ref;
-@end
-
-#define _DESCRIPTOR_ "testpkg"
+#define _DESCRIPTOR_ "golang.org/x/mobile/bind/objc/testpkg"
 
 #define _CALL_BytesAppend_ 1
 #define _CALL_CallSSum_ 2
@@ -25,7 +21,8 @@ static NSString *errDomain = @"go.golang.org/x/mobile/bind/objc/testpkg";
 #define _CALL_ReturnsError_ 8
 #define _CALL_Sum_ 9
 
-#define _GO_testpkg_S_DESCRIPTOR_ "go.testpkg.S"
+#define _GO_testpkg_S_DESCRIPTOR_ "go.golang.org/x/mobile/bind/objc/testpkg.S"
+#define _GO_testpkg_S_CAST_ (0x00e)
 #define _GO_testpkg_S_FIELD_X_GET_ (0x00f)
 #define _GO_testpkg_S_FIELD_X_SET_ (0x01f)
 #define _GO_testpkg_S_FIELD_Y_GET_ (0x10f)
@@ -125,12 +122,7 @@ NSData* GoTestpkgBytesAppend(NSData* a, NSData* b) {
 double GoTestpkgCallSSum(GoTestpkgS* s) {
 	GoSeq in_ = {};
 	GoSeq out_ = {};
-	if (![s respondsToSelector:@selector(ref)]) {
-		@throw [NSException exceptionWithName:@"InvalidGoSeqRef"
-		                               reason:@"not a subclass of GoTestpkgSStub"
-		                             userInfo:nil];
-	}
-	go_seq_writeRef(&in_, [(id<goSeqRefInterface>)s ref]);
+	go_seq_writeRef(&in_, s.ref);
 	go_seq_send(_DESCRIPTOR_, _CALL_CallSSum_, &in_, &out_);
 	double ret0_ = go_seq_readFloat64(&out_);
 	go_seq_free(&in_);
@@ -204,10 +196,17 @@ BOOL GoTestpkgReturnsError(BOOL b, NSString** ret0_, NSError** error) {
 		*ret0_ = ret0__val;
 	}
 	NSString* _error = go_seq_readUTF8(&out_);
-	if ([_error length] != 0 && error != nil) {
-		NSMutableDictionary *details = [NSMutableDictionary dictionary];
-		[details setValue:_error forKey:NSLocalizedDescriptionKey];
-		*error = [NSError errorWithDomain:errDomain code:1 userInfo:details];
+	if ([_error length] != 0) {
+		NSString* _error_domain = go_seq_readUTF8(&out_);
+		int64_t _error_code = go_seq_readInt64(&out_);
+		if ([_error_domain length] == 0) {
+			_error_domain = errDomain;
+		}
+		if (error != nil) {
+			NSMutableDictionary *details = [NSMutableDictionary dictionary];
+			[details setValue:_error forKey:NSLocalizedDescriptionKey];
+			*error = [NSError errorWithDomain:_error_domain code:(NSInteger)_error_code userInfo:details];
+		}
 	}
 	go_seq_free(&in_);
 	go_seq_free(&out_);
@@ -226,3 +225,24 @@ int64_t GoTestpkgSum(int64_t x, int64_t y) {
 	return ret0_;
 }
 
+GoTestpkgS* GoTestpkgCastS(id o) {
+	if (o == nil || [o isKindOfClass:[GoTestpkgS class]]) {
+		return o;
+	}
+	if (![o respondsToSelector:@selector(ref)]) {
+		return nil;
+	}
+	GoSeqRef* ref = [(GoTestpkgS*)o ref];
+	GoSeq in_ = {};
+	GoSeq out_ = {};
+	go_seq_writeRef(&in_, ref);
+	go_seq_send(_GO_testpkg_S_DESCRIPTOR_, _GO_testpkg_S_CAST_, &in_, &out_);
+	BOOL ok = go_seq_readBool(&out_);
+	go_seq_free(&in_);
+	go_seq_free(&out_);
+	if (!ok) {
+		return nil;
+	}
+	return [[GoTestpkgS alloc] initWithRef:ref];
+}
+
@@ -9,7 +9,7 @@
 
 static NSString *errDomain = @"go.golang.org/x/mobile/bind/objc/testpkg";
 
-#define _DESCRIPTOR_ "testpkg"
+#define _DESCRIPTOR_ "golang.org/x/mobile/bind/objc/testpkg"
 
 #define _CALL_BytesAppend_ 1
 #define _CALL_CallI_ 2
@@ -22,14 +22,14 @@ static NSString *errDomain = @"go.golang.org/x/mobile/bind/objc/testpkg";
 #define _CALL_ReturnsError_ 9
 #define _CALL_Sum_ 10
 
-#define _GO_testpkg_S_DESCRIPTOR_ "go.testpkg.S"
+#define _GO_testpkg_S_DESCRIPTOR_ "go.golang.org/x/mobile/bind/objc/testpkg.S"
 #define _GO_testpkg_S_FIELD_X_GET_ (0x00f)
 #define _GO_testpkg_S_FIELD_X_SET_ (0x01f)
 #define _GO_testpkg_S_FIELD_Y_GET_ (0x10f)
 #define _GO_testpkg_S_FIELD_Y_SET_ (0x11f)
 #define _GO_testpkg_S_Sum_ (0x00c)
 
-#define _GO_testpkg_I_DESCRIPTOR_ "go.testpkg.I"
+#define _GO_testpkg_I_DESCRIPTOR_ "go.golang.org/x/mobile/bind/objc/testpkg.I"
 #define _GO_testpkg_I_Fn_ 0x10a
 
 @implementation GoTestpkgI {
@@ -4,8 +4,8 @@
 
 package testpkg
 
-//go:generate gobind -lang=go -outdir=go_testpkg .
-//go:generate gobind -lang=objc -outdir=objc_testpkg .
+//go:generate gobind -lang=go -outdir=go_testpkg golang.org/x/mobile/bind/objc/testpkg
+//go:generate gobind -lang=objc -outdir=objc_testpkg golang.org/x/mobile/bind/objc/testpkg
 
 import (
 	"errors"
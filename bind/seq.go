@@ -6,9 +6,22 @@ import (
 	"golang.org/x/tools/go/types"
 )
 
+// seqDesc returns the descriptor of the type or variable name of the
+// bound package pkg in the seq registry, or of its functions if name is
+// empty. The generated Go code registers its proxies under the
+// descriptors that the Java and Objective-C bindings send. They hold the
+// path of pkg, so that bound packages of the same name do not collide,
+// whatever their Java package or Objective-C prefix.
+func seqDesc(pkg *types.Package, name string) string {
+	if name == "" {
+		return pkg.Path()
+	}
+	return "go." + pkg.Path() + "." + name
+}
+
 // seqType returns a string that can be used for reading and writing a
-// type using the seq library.
-// TODO(hyangah): avoid panic; gobind needs to output the problematic code location.
+// type using the seq library. It panics on the types that cannot be
+// bound, which Check reports with their location beforehand.
 func seqType(t types.Type) string {
 	if isErrorType(t) {
 		return "String"
@@ -31,7 +44,14 @@ func seqType(t types.Type) string {
 		case types.Uint8: // Byte.
 			// TODO(crawshaw): questionable, but vital?
 			return "Byte"
-		// TODO(crawshaw): case types.Uint, types.Uint16, types.Uint32, types.Uint64:
+		case types.Uint:
+			return "Uint"
+		case types.Uint16:
+			return "Uint16"
+		case types.Uint32:
+			return "Uint32"
+		case types.Uint64:
+			return "Uint64"
 		case types.Float32:
 			return "Float32"
 		case types.Float64:
@@ -44,35 +64,87 @@ func seqType(t types.Type) string {
 		}
 	case *types.Named:
 		switch u := t.Underlying().(type) {
-		case *types.Interface:
+		case *types.Interface, *types.Struct:
 			return "Ref"
 		default:
 			panic(fmt.Sprintf("unsupported named seqType: %s / %T", u, u))
 		}
 	case *types.Slice:
-		switch e := t.Elem().(type) {
-		case *types.Basic:
-			switch e.Kind() {
-			case types.Uint8: // Byte.
-				return "ByteArray"
-			default:
-				panic(fmt.Sprintf("unsupported seqType: %s(%s) / %T(%T)", t, e, t, e))
-			}
-		default:
-			panic(fmt.Sprintf("unsupported seqType: %s(%s) / %T(%T)", t, e, t, e))
-		}
-	// TODO: let the types.Array case handled like types.Slice?
+		return seqArrayType(t, t.Elem())
+	case *types.Array:
+		return seqArrayType(t, t.Elem())
 	case *types.Pointer:
+		if isDirectBuffer(t) {
+			return "DirectBuffer"
+		}
 		if _, ok := t.Elem().(*types.Named); ok {
 			return "Ref"
 		}
 		panic(fmt.Sprintf("not supported yet, pointer type: %s / %T", t, t))
+	case *types.Map:
+		if _, ok := t.Key().(*types.Basic); !ok {
+			panic(fmt.Sprintf("unsupported map key type: %s", t))
+		}
+		if e := seqType(t.Elem()); e == "Stream" || e == "DirectBuffer" {
+			panic(fmt.Sprintf("unsupported map value type: %s", t))
+		}
+		return "Map"
+	case *types.Chan:
+		if t.Dir() == types.RecvOnly {
+			return "Stream"
+		}
+		panic(fmt.Sprintf("unsupported channel type: %s, only receive-only channels are supported", t))
 
 	default:
 		panic(fmt.Sprintf("unsupported seqType: %s / %T", t, t))
 	}
 }
 
+// isDirectBuffer reports whether t is *seq.DirectBuffer, the Go type
+// of the memory of foreign buffers, passed without copying.
+func isDirectBuffer(t types.Type) bool {
+	p, ok := t.(*types.Pointer)
+	if !ok {
+		return false
+	}
+	n, ok := p.Elem().(*types.Named)
+	if !ok {
+		return false
+	}
+	o := n.Obj()
+	return o.Pkg() != nil && o.Pkg().Path() == "golang.org/x/mobile/bind/seq" && o.Name() == "DirectBuffer"
+}
+
+// seqArrayType returns the seq type of a slice or array type t
+// with elements of type elem.
+func seqArrayType(t, elem types.Type) string {
+	if isErrorType(elem) {
+		panic(fmt.Sprintf("unsupported seqType: %s / %T", t, t))
+	}
+	switch e := seqType(elem); e {
+	case "Byte":
+		return "ByteArray"
+	case "Bool", "Int", "Int8", "Int16", "Int32", "Int64",
+		"Uint", "Uint16", "Uint32", "Uint64",
+		"Float32", "Float64", "String", "Ref":
+		return e + "Array"
+	default:
+		panic(fmt.Sprintf("unsupported seqType: %s(%s) / %T(%T)", t, elem, t, elem))
+	}
+}
+
+// arrayElem returns the element type of a slice or array type,
+// or nil if t is neither.
+func arrayElem(t types.Type) types.Type {
+	switch t := t.(type) {
+	case *types.Slice:
+		return t.Elem()
+	case *types.Array:
+		return t.Elem()
+	}
+	return nil
+}
+
 func seqRead(o types.Type) string {
 	t := seqType(o)
 	return t + "()"
@@ -81,8 +153,8 @@ func seqRead(o types.Type) string {
 func seqWrite(o types.Type, name string) string {
 	t := seqType(o)
 	if t == "Ref" {
-		// TODO(crawshaw): do something cleaner, i.e. genWrite.
-		return t + "(" + name + ".ref())"
+		// A null object is written as the null ref.
+		return "Object(" + name + ")"
 	}
 	return t + "(" + name + ")"
 }
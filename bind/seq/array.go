@@ -0,0 +1,290 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import "fmt"
+
+// Arrays other than byte arrays are encoded as an int32 length
+// followed by each element, encoded as if it were written alone.
+// Arrays of references are written element by element by the
+// generated code, framed by WriteArrayLen and ReadArrayLen.
+//
+// Maps are encoded the same way, as the number of entries
+// followed by the key and the value of each entry, written by
+// the generated code.
+
+// CheckArrayLen panics unless n, the length of an array read for a Go
+// array of length want, is want. The foreign array would otherwise be
+// truncated, or padded with zero values.
+func CheckArrayLen(n, want int) {
+	if n != want {
+		panic(fmt.Sprintf("array length %d, want %d", n, want))
+	}
+}
+
+// ReadArrayLen reads the number of elements of an encoded array.
+func (b *Buffer) ReadArrayLen() int {
+	n := int(b.ReadInt32())
+	if n < 0 {
+		panic(fmt.Sprintf("array length negative: %d", n))
+	}
+	return n
+}
+
+// WriteArrayLen writes the number of elements of an array.
+// It must be followed by exactly n encoded elements.
+func (b *Buffer) WriteArrayLen(n int) {
+	if n > 1<<31-1 {
+		panic(fmt.Sprintf("array too long: %d", n))
+	}
+	b.WriteInt32(int32(n))
+}
+
+func (b *Buffer) ReadBoolArray() []bool {
+	n := b.ReadArrayLen()
+	if n == 0 {
+		return nil
+	}
+	v := make([]bool, n)
+	for i := range v {
+		v[i] = b.ReadBool()
+	}
+	return v
+}
+
+func (b *Buffer) ReadIntArray() []int {
+	n := b.ReadArrayLen()
+	if n == 0 {
+		return nil
+	}
+	v := make([]int, n)
+	for i := range v {
+		v[i] = b.ReadInt()
+	}
+	return v
+}
+
+func (b *Buffer) ReadInt8Array() []int8 {
+	n := b.ReadArrayLen()
+	if n == 0 {
+		return nil
+	}
+	v := make([]int8, n)
+	for i := range v {
+		v[i] = b.ReadInt8()
+	}
+	return v
+}
+
+func (b *Buffer) ReadInt16Array() []int16 {
+	n := b.ReadArrayLen()
+	if n == 0 {
+		return nil
+	}
+	v := make([]int16, n)
+	for i := range v {
+		v[i] = b.ReadInt16()
+	}
+	return v
+}
+
+func (b *Buffer) ReadInt32Array() []int32 {
+	n := b.ReadArrayLen()
+	if n == 0 {
+		return nil
+	}
+	v := make([]int32, n)
+	for i := range v {
+		v[i] = b.ReadInt32()
+	}
+	return v
+}
+
+func (b *Buffer) ReadInt64Array() []int64 {
+	n := b.ReadArrayLen()
+	if n == 0 {
+		return nil
+	}
+	v := make([]int64, n)
+	for i := range v {
+		v[i] = b.ReadInt64()
+	}
+	return v
+}
+
+func (b *Buffer) ReadUintArray() []uint {
+	n := b.ReadArrayLen()
+	if n == 0 {
+		return nil
+	}
+	v := make([]uint, n)
+	for i := range v {
+		v[i] = b.ReadUint()
+	}
+	return v
+}
+
+func (b *Buffer) ReadUint16Array() []uint16 {
+	n := b.ReadArrayLen()
+	if n == 0 {
+		return nil
+	}
+	v := make([]uint16, n)
+	for i := range v {
+		v[i] = b.ReadUint16()
+	}
+	return v
+}
+
+func (b *Buffer) ReadUint32Array() []uint32 {
+	n := b.ReadArrayLen()
+	if n == 0 {
+		return nil
+	}
+	v := make([]uint32, n)
+	for i := range v {
+		v[i] = b.ReadUint32()
+	}
+	return v
+}
+
+func (b *Buffer) ReadUint64Array() []uint64 {
+	n := b.ReadArrayLen()
+	if n == 0 {
+		return nil
+	}
+	v := make([]uint64, n)
+	for i := range v {
+		v[i] = b.ReadUint64()
+	}
+	return v
+}
+
+func (b *Buffer) ReadFloat32Array() []float32 {
+	n := b.ReadArrayLen()
+	if n == 0 {
+		return nil
+	}
+	v := make([]float32, n)
+	for i := range v {
+		v[i] = b.ReadFloat32()
+	}
+	return v
+}
+
+func (b *Buffer) ReadFloat64Array() []float64 {
+	n := b.ReadArrayLen()
+	if n == 0 {
+		return nil
+	}
+	v := make([]float64, n)
+	for i := range v {
+		v[i] = b.ReadFloat64()
+	}
+	return v
+}
+
+func (b *Buffer) ReadStringArray() []string {
+	n := b.ReadArrayLen()
+	if n == 0 {
+		return nil
+	}
+	v := make([]string, n)
+	for i := range v {
+		v[i] = b.ReadString()
+	}
+	return v
+}
+
+func (b *Buffer) WriteBoolArray(v []bool) {
+	b.WriteArrayLen(len(v))
+	for _, e := range v {
+		b.WriteBool(e)
+	}
+}
+
+func (b *Buffer) WriteIntArray(v []int) {
+	b.WriteArrayLen(len(v))
+	for _, e := range v {
+		b.WriteInt(e)
+	}
+}
+
+func (b *Buffer) WriteInt8Array(v []int8) {
+	b.WriteArrayLen(len(v))
+	for _, e := range v {
+		b.WriteInt8(e)
+	}
+}
+
+func (b *Buffer) WriteInt16Array(v []int16) {
+	b.WriteArrayLen(len(v))
+	for _, e := range v {
+		b.WriteInt16(e)
+	}
+}
+
+func (b *Buffer) WriteInt32Array(v []int32) {
+	b.WriteArrayLen(len(v))
+	for _, e := range v {
+		b.WriteInt32(e)
+	}
+}
+
+func (b *Buffer) WriteInt64Array(v []int64) {
+	b.WriteArrayLen(len(v))
+	for _, e := range v {
+		b.WriteInt64(e)
+	}
+}
+
+func (b *Buffer) WriteUintArray(v []uint) {
+	b.WriteArrayLen(len(v))
+	for _, e := range v {
+		b.WriteUint(e)
+	}
+}
+
+func (b *Buffer) WriteUint16Array(v []uint16) {
+	b.WriteArrayLen(len(v))
+	for _, e := range v {
+		b.WriteUint16(e)
+	}
+}
+
+func (b *Buffer) WriteUint32Array(v []uint32) {
+	b.WriteArrayLen(len(v))
+	for _, e := range v {
+		b.WriteUint32(e)
+	}
+}
+
+func (b *Buffer) WriteUint64Array(v []uint64) {
+	b.WriteArrayLen(len(v))
+	for _, e := range v {
+		b.WriteUint64(e)
+	}
+}
+
+func (b *Buffer) WriteFloat32Array(v []float32) {
+	b.WriteArrayLen(len(v))
+	for _, e := range v {
+		b.WriteFloat32(e)
+	}
+}
+
+func (b *Buffer) WriteFloat64Array(v []float64) {
+	b.WriteArrayLen(len(v))
+	for _, e := range v {
+		b.WriteFloat64(e)
+	}
+}
+
+func (b *Buffer) WriteStringArray(v []string) {
+	b.WriteArrayLen(len(v))
+	for _, e := range v {
+		b.WriteString(e)
+	}
+}
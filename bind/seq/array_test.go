@@ -0,0 +1,78 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestArray(t *testing.T) {
+	EncString = (*Buffer).WriteUTF16
+	DecString = (*Buffer).ReadUTF16
+
+	buf := new(Buffer)
+	buf.WriteInt8(3) // misalign the following arrays
+	buf.WriteBoolArray([]bool{true, false, true})
+	buf.WriteIntArray([]int{1 << 40, -1})
+	buf.WriteInt8Array([]int8{-128, 127})
+	buf.WriteInt16Array([]int16{1 << 14})
+	buf.WriteInt32Array(nil)
+	buf.WriteInt64Array([]int64{1 << 62, 5})
+	buf.WriteUintArray([]uint{1 << 31})
+	buf.WriteUint16Array([]uint16{1<<16 - 1, 0})
+	buf.WriteUint32Array([]uint32{1<<32 - 1})
+	buf.WriteUint64Array([]uint64{1<<64 - 1, 7})
+	buf.WriteFloat32Array([]float32{1.5})
+	buf.WriteFloat64Array([]float64{4.02, -1})
+	buf.WriteStringArray([]string{"Hello", "", "世界"})
+	buf.WriteArrayLen(2)
+	buf.WriteGoRef(new(int))
+	buf.WriteGoRef(new(int))
+
+	buf.Offset = 0
+	buf.ReadInt8()
+
+	check := func(name string, got, want interface{}) {
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", name, got, want)
+		}
+	}
+	check("BoolArray", buf.ReadBoolArray(), []bool{true, false, true})
+	check("IntArray", buf.ReadIntArray(), []int{1 << 40, -1})
+	check("Int8Array", buf.ReadInt8Array(), []int8{-128, 127})
+	check("Int16Array", buf.ReadInt16Array(), []int16{1 << 14})
+	check("Int32Array", buf.ReadInt32Array(), []int32(nil))
+	check("Int64Array", buf.ReadInt64Array(), []int64{1 << 62, 5})
+	check("UintArray", buf.ReadUintArray(), []uint{1 << 31})
+	check("Uint16Array", buf.ReadUint16Array(), []uint16{1<<16 - 1, 0})
+	check("Uint32Array", buf.ReadUint32Array(), []uint32{1<<32 - 1})
+	check("Uint64Array", buf.ReadUint64Array(), []uint64{1<<64 - 1, 7})
+	check("Float32Array", buf.ReadFloat32Array(), []float32{1.5})
+	check("Float64Array", buf.ReadFloat64Array(), []float64{4.02, -1})
+	check("StringArray", buf.ReadStringArray(), []string{"Hello", "", "世界"})
+
+	n := buf.ReadArrayLen()
+	check("ArrayLen", n, 2)
+	for i := 0; i < n; i++ {
+		if ref := buf.ReadRef(); ref.Num >= 0 {
+			t.Errorf("ref %d: got refnum %d, want a Go ref", i, ref.Num)
+		}
+	}
+}
+
+func TestCheckArrayLen(t *testing.T) {
+	CheckArrayLen(3, 3)
+	for _, n := range []int{0, 2, 4} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("CheckArrayLen(%d, 3) did not panic", n)
+				}
+			}()
+			CheckArrayLen(n, 3)
+		}()
+	}
+}
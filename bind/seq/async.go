@@ -0,0 +1,99 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import "sync"
+
+// MaxAsync is the maximum number of asynchronous calls queued and not
+// yet started. TransactAsync blocks while that many calls are queued.
+const MaxAsync = 1024
+
+// asyncCall is a pending asynchronous call.
+type asyncCall struct {
+	ref        *Ref
+	code       int
+	in         *Buffer
+	returnsErr bool
+}
+
+// async holds the pending asynchronous calls, queued by reference
+// number. The calls of each queue are made in order by a goroutine
+// running while the queue is not empty.
+var async struct {
+	sync.Mutex
+	queues  map[int32][]asyncCall
+	handler func(error)
+	slots   chan struct{}  // one value per queued call
+	pending sync.WaitGroup // queued and running calls
+}
+
+func init() {
+	async.queues = make(map[int32][]asyncCall)
+	async.slots = make(chan struct{}, MaxAsync)
+}
+
+// TransactAsync calls a method on a foreign object instance without
+// waiting for the call to complete. The calls on an object are made
+// in the order of the calls to TransactAsync. If returnsErr is set,
+// the method returns an error, passed to the handler set by
+// SetAsyncErrorHandler if it is not nil.
+//
+// A call leaves the queue when it starts, so the foreign callbacks
+// into Go made by a call may call TransactAsync. A callback queuing
+// more than MaxAsync calls on the object of its call blocks forever,
+// as these calls wait for the call making the callback.
+func TransactAsync(ref *Ref, code int, in *Buffer, returnsErr bool) {
+	async.slots <- struct{}{}
+	async.pending.Add(1)
+
+	async.Lock()
+	q, running := async.queues[ref.Num]
+	async.queues[ref.Num] = append(q, asyncCall{ref, code, in, returnsErr})
+	async.Unlock()
+	if !running {
+		go runAsync(ref.Num)
+	}
+}
+
+// runAsync makes the calls queued for the reference number num,
+// until its queue is empty.
+func runAsync(num int32) {
+	for {
+		async.Lock()
+		q := async.queues[num]
+		if len(q) == 0 {
+			delete(async.queues, num)
+			async.Unlock()
+			return
+		}
+		c := q[0]
+		async.queues[num] = q[1:]
+		handler := async.handler
+		async.Unlock()
+		<-async.slots
+
+		out := Transact(c.ref, c.code, c.in)
+		if c.returnsErr {
+			if err := out.ReadError(); err != nil && handler != nil {
+				handler(err)
+			}
+		}
+		async.pending.Done()
+	}
+}
+
+// SetAsyncErrorHandler sets the function called with the non-nil
+// errors returned by asynchronous calls. They are ignored if h is nil.
+// Calls to h may be concurrent.
+func SetAsyncErrorHandler(h func(err error)) {
+	async.Lock()
+	async.handler = h
+	async.Unlock()
+}
+
+// WaitAsync waits until no asynchronous call is pending.
+func WaitAsync() {
+	async.pending.Wait()
+}
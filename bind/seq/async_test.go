@@ -0,0 +1,100 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTransactAsync(t *testing.T) {
+	EncString = (*Buffer).WriteUTF16
+	DecString = (*Buffer).ReadUTF16
+
+	var mu sync.Mutex
+	calls := make(map[int32][]int) // codes called by ref
+	defer func(tr func(*Ref, int, *Buffer) *Buffer) { Transact = tr }(Transact)
+	Transact = func(ref *Ref, code int, in *Buffer) *Buffer {
+		mu.Lock()
+		calls[ref.Num] = append(calls[ref.Num], code)
+		mu.Unlock()
+		out := new(Buffer)
+		if code%10 == 0 {
+			out.WriteError(&ForeignError{Message: "failed", Type: "Exception", Code: int64(code)})
+		} else {
+			out.WriteError(nil)
+		}
+		out.Offset = 0
+		return out
+	}
+	var errs []error
+	SetAsyncErrorHandler(func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	})
+	defer SetAsyncErrorHandler(nil)
+
+	const n = 3 * MaxAsync
+	for code := 1; code <= n; code++ {
+		for num := int32(1); num <= 3; num++ {
+			TransactAsync(&Ref{num}, code, new(Buffer), num != 3)
+		}
+	}
+	WaitAsync()
+
+	for num := int32(1); num <= 3; num++ {
+		if len(calls[num]) != n {
+			t.Fatalf("ref %d: got %d calls, want %d", num, len(calls[num]), n)
+		}
+		for i, code := range calls[num] {
+			if code != i+1 {
+				t.Fatalf("ref %d: call %d has code %d, want %d", num, i, code, i+1)
+			}
+		}
+	}
+	if got, want := len(errs), 2*n/10; got != want {
+		t.Errorf("got %d errors, want %d", got, want)
+	}
+	for _, err := range errs {
+		if e, ok := err.(*ForeignError); !ok || e.Message != "failed" {
+			t.Errorf("got error %v, want a ForeignError", err)
+		}
+	}
+}
+
+func TestTransactAsyncCallback(t *testing.T) {
+	defer func(tr func(*Ref, int, *Buffer) *Buffer) { Transact = tr }(Transact)
+	var mu sync.Mutex
+	var calls int
+	Transact = func(ref *Ref, code int, in *Buffer) *Buffer {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		if code == 0 {
+			// A callback into Go queuing calls on the same object.
+			for i := 0; i < MaxAsync; i++ {
+				TransactAsync(ref, 1, new(Buffer), false)
+			}
+		}
+		return new(Buffer)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		TransactAsync(&Ref{1}, 0, new(Buffer), false)
+		WaitAsync()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("callback queuing MaxAsync calls blocked")
+	}
+	if calls != MaxAsync+1 {
+		t.Errorf("got %d calls, want %d", calls, MaxAsync+1)
+	}
+}
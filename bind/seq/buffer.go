@@ -108,6 +108,25 @@ func (b *Buffer) ReadInt() int {
 	return int(b.ReadInt64())
 }
 
+// Unsigned integers are encoded as the signed integers of the same
+// size with the same bits.
+
+func (b *Buffer) ReadUint16() uint16 {
+	return uint16(b.ReadInt16())
+}
+
+func (b *Buffer) ReadUint32() uint32 {
+	return uint32(b.ReadInt32())
+}
+
+func (b *Buffer) ReadUint64() uint64 {
+	return uint64(b.ReadInt64())
+}
+
+func (b *Buffer) ReadUint() uint {
+	return uint(b.ReadInt64())
+}
+
 func (b *Buffer) ReadFloat32() float32 {
 	offset := align(b.Offset, 4)
 	if len(b.Data)-offset < 4 {
@@ -206,6 +225,22 @@ func (b *Buffer) WriteInt(v int) {
 	b.WriteInt64(int64(v))
 }
 
+func (b *Buffer) WriteUint16(v uint16) {
+	b.WriteInt16(int16(v))
+}
+
+func (b *Buffer) WriteUint32(v uint32) {
+	b.WriteInt32(int32(v))
+}
+
+func (b *Buffer) WriteUint64(v uint64) {
+	b.WriteInt64(int64(v))
+}
+
+func (b *Buffer) WriteUint(v uint) {
+	b.WriteInt64(int64(v))
+}
+
 func (b *Buffer) WriteFloat32(v float32) {
 	offset := align(b.Offset, 4)
 	if len(b.Data)-offset < 4 {
@@ -242,23 +277,7 @@ func (b *Buffer) WriteString(v string) {
 }
 
 func (b *Buffer) WriteGoRef(obj interface{}) {
-	refs.Lock()
-	num := refs.refs[obj]
-	if num != 0 {
-		s := refs.objs[num]
-		refs.objs[num] = countedObj{s.obj, s.cnt + 1}
-	} else {
-		num = refs.next
-		refs.next--
-		if refs.next > 0 {
-			panic("refs.next underflow")
-		}
-		refs.refs[obj] = num
-		refs.objs[num] = countedObj{obj, 1}
-	}
-	refs.Unlock()
-
-	b.WriteInt32(int32(num))
+	b.WriteInt32(pin(obj))
 }
 
 /*  TODO: Will we need it?
@@ -0,0 +1,83 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+// A DirectBuffer is the memory of a foreign buffer, read and written
+// by Go without copying: a direct java.nio.ByteBuffer in Java, or an
+// NSMutableData in Objective-C. Functions of bound packages take and
+// return *DirectBuffer to pass large payloads across the language
+// boundary, where []byte values are copied.
+//
+// A DirectBuffer holds a reference to the foreign buffer, keeping
+// its memory valid until the DirectBuffer is unreachable. Objective-C
+// references are not yet tracked, so the memory of an NSMutableData
+// is only valid during the call it is passed to.
+type DirectBuffer struct {
+	ref  *Ref // foreign buffer, nil if not tracked
+	data []byte
+}
+
+// Bytes returns the memory of the buffer. The slice must not be used
+// once b is unreachable.
+func (b *DirectBuffer) Bytes() []byte {
+	return b.data
+}
+
+// Len returns the size of the buffer in bytes.
+func (b *DirectBuffer) Len() int {
+	return len(b.data)
+}
+
+// A DirectBuffer is encoded as the reference number of the foreign
+// buffer, or 0, followed by the address and the size of its memory.
+// The address of an empty buffer may be 0. A nil DirectBuffer is
+// encoded as 0, 0 and the size -1.
+
+// ReadDirectBuffer reads a foreign buffer, nil if the buffer is null.
+func (b *Buffer) ReadDirectBuffer() *DirectBuffer {
+	ref := b.ReadRef()
+	ptr := uintptr(b.ReadInt64())
+	n := int(b.ReadInt64())
+	if n < 0 {
+		return nil
+	}
+	d := new(DirectBuffer)
+	if ref.Num != 0 {
+		d.ref = ref
+	}
+	if ptr != 0 && n > 0 {
+		h := (*reflect.SliceHeader)(unsafe.Pointer(&d.data))
+		h.Data = ptr
+		h.Len = n
+		h.Cap = n
+	}
+	return d
+}
+
+// WriteDirectBuffer writes a foreign buffer read by ReadDirectBuffer.
+func (b *Buffer) WriteDirectBuffer(d *DirectBuffer) {
+	if d == nil {
+		b.WriteInt32(0)
+		b.WriteInt64(0)
+		b.WriteInt64(-1)
+		return
+	}
+	var num int32
+	if d.ref != nil {
+		num = d.ref.Num
+	}
+	var ptr uintptr
+	if len(d.data) > 0 {
+		ptr = uintptr(unsafe.Pointer(&d.data[0]))
+	}
+	b.WriteInt32(num)
+	b.WriteInt64(int64(ptr))
+	b.WriteInt64(int64(len(d.data)))
+}
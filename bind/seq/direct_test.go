@@ -0,0 +1,106 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import (
+	"testing"
+	"unsafe"
+)
+
+// writeForeignBuffer writes mem as a foreign language writes the
+// memory of a buffer with reference number num.
+func writeForeignBuffer(b *Buffer, num int32, mem []byte) {
+	b.WriteInt32(num)
+	b.WriteInt64(int64(uintptr(unsafe.Pointer(&mem[0]))))
+	b.WriteInt64(int64(len(mem)))
+}
+
+func TestDirectBuffer(t *testing.T) {
+	mem := []byte("hello, world")
+	in := new(Buffer)
+	in.WriteInt8(1) // misalign the buffer
+	writeForeignBuffer(in, 42, mem)
+	in.WriteDirectBuffer(nil)
+	// An empty foreign buffer, whose memory may have no address.
+	in.WriteInt32(0)
+	in.WriteInt64(0)
+	in.WriteInt64(0)
+
+	in.Offset = 0
+	in.ReadInt8()
+	d := in.ReadDirectBuffer()
+	if d == nil {
+		t.Fatal("ReadDirectBuffer()=nil, want a buffer")
+	}
+	if got := in.ReadDirectBuffer(); got != nil {
+		t.Errorf("ReadDirectBuffer()=%v, want nil", got)
+	}
+	if got := in.ReadDirectBuffer(); got == nil || got.Len() != 0 {
+		t.Errorf("ReadDirectBuffer()=%v, want an empty buffer", got)
+	}
+	if got, want := string(d.Bytes()), string(mem); got != want || d.Len() != len(mem) {
+		t.Errorf("Bytes()=%q, want %q", got, want)
+	}
+	d.Bytes()[0] = 'H'
+	if mem[0] != 'H' {
+		t.Errorf("write to Bytes() not visible in the foreign buffer: %q", mem)
+	}
+
+	out := new(Buffer)
+	out.WriteDirectBuffer(d)
+	out.Offset = 0
+	if num := out.ReadInt32(); num != 42 {
+		t.Errorf("WriteDirectBuffer wrote refnum %d, want 42", num)
+	}
+	if ptr := uintptr(out.ReadInt64()); ptr != uintptr(unsafe.Pointer(&mem[0])) {
+		t.Errorf("WriteDirectBuffer wrote address %#x, want %p", ptr, &mem[0])
+	}
+	if n := out.ReadInt64(); n != int64(len(mem)) {
+		t.Errorf("WriteDirectBuffer wrote size %d, want %d", n, len(mem))
+	}
+
+	out = new(Buffer)
+	out.WriteDirectBuffer(nil)
+	out.WriteDirectBuffer(new(DirectBuffer))
+	out.Offset = 0
+	if got := out.ReadDirectBuffer(); got != nil {
+		t.Errorf("nil buffer read as %v", got)
+	}
+	if got := out.ReadDirectBuffer(); got == nil {
+		t.Error("empty buffer read as nil")
+	}
+}
+
+const benchSize = 1 << 20
+
+// BenchmarkByteArray passes a byte slice from a foreign language
+// to Go, which copies it.
+func BenchmarkByteArray(b *testing.B) {
+	mem := make([]byte, benchSize)
+	b.SetBytes(benchSize)
+	for i := 0; i < b.N; i++ {
+		buf := new(Buffer)
+		buf.WriteByteArray(mem)
+		buf.Offset = 0
+		if v := buf.ReadByteArray(); len(v) != benchSize {
+			b.Fatalf("read %d bytes, want %d", len(v), benchSize)
+		}
+	}
+}
+
+// BenchmarkDirectBuffer passes a foreign buffer to Go, which reads
+// it in place.
+func BenchmarkDirectBuffer(b *testing.B) {
+	mem := make([]byte, benchSize)
+	b.SetBytes(benchSize)
+	for i := 0; i < b.N; i++ {
+		buf := new(Buffer)
+		writeForeignBuffer(buf, 0, mem)
+		buf.Offset = 0
+		if d := buf.ReadDirectBuffer(); d.Len() != benchSize {
+			b.Fatalf("read %d bytes, want %d", d.Len(), benchSize)
+		}
+	}
+}
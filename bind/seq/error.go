@@ -0,0 +1,90 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// An error is encoded as its message, with the empty string
+// standing for nil. A non-nil error is followed by the name of
+// its type and its code.
+//
+// Errors written by Go are named by the import path and name of
+// their Go type, such as "net/url.Error", and their code is the
+// result of their Code method, if they have one. Errors written
+// by Java are named by the class name of the exception, errors
+// written by Objective-C by the domain of the NSError.
+
+// A ForeignError is an error returned by Java or Objective-C code.
+type ForeignError struct {
+	Message string // exception message or NSError description
+	Type    string // Java class name or NSError domain
+	Code    int64
+}
+
+func (e *ForeignError) Error() string { return e.Message }
+
+// ReadError reads an error. Errors are always returned as a
+// *ForeignError, even if they were originally written by Go.
+func (b *Buffer) ReadError() error {
+	msg := b.ReadString()
+	if msg == "" {
+		return nil
+	}
+	typ := b.ReadString()
+	code := b.ReadInt64()
+	return &ForeignError{Message: msg, Type: typ, Code: code}
+}
+
+// WriteError writes an error.
+//
+// A ForeignError is written with its original type and code, so
+// that errors returned by foreign code keep their identity when
+// passed back.
+func (b *Buffer) WriteError(err error) {
+	if err == nil {
+		b.WriteString("")
+		return
+	}
+	var typ string
+	var code int64
+	if e, ok := err.(*ForeignError); ok {
+		typ, code = e.Type, e.Code
+	} else {
+		typ = errorTypeName(err)
+		if c, ok := err.(interface {
+			Code() int
+		}); ok {
+			code = int64(c.Code())
+		}
+	}
+	msg := err.Error()
+	if msg == "" {
+		// The empty message stands for nil.
+		msg = fmt.Sprintf("%T", err)
+	}
+	b.WriteString(msg)
+	b.WriteString(typ)
+	b.WriteInt64(code)
+}
+
+// errorTypeName returns the qualified name of the type of err,
+// ignoring a pointer indirection, or the empty string if the
+// type is not named.
+func errorTypeName(err error) string {
+	t := reflect.TypeOf(err)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Name() == "" {
+		return ""
+	}
+	if t.PkgPath() == "" {
+		return t.Name()
+	}
+	return t.PkgPath() + "." + t.Name()
+}
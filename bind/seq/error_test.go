@@ -0,0 +1,50 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type codeError int
+
+func (e codeError) Error() string { return "code error" }
+func (e codeError) Code() int     { return int(e) }
+
+type emptyError struct{}
+
+func (*emptyError) Error() string { return "" }
+
+func TestError(t *testing.T) {
+	EncString = (*Buffer).WriteUTF16
+	DecString = (*Buffer).ReadUTF16
+
+	foreign := &ForeignError{Message: "disk full", Type: "java.io.IOException", Code: 28}
+	tests := []struct {
+		err  error
+		want error
+	}{
+		{nil, nil},
+		{errors.New("bad"), &ForeignError{Message: "bad", Type: "errors.errorString"}},
+		{codeError(7), &ForeignError{Message: "code error", Type: "golang.org/x/mobile/bind/seq.codeError", Code: 7}},
+		{&emptyError{}, &ForeignError{Message: "*seq.emptyError", Type: "golang.org/x/mobile/bind/seq.emptyError"}},
+		{foreign, foreign},
+	}
+	for _, test := range tests {
+		buf := new(Buffer)
+		buf.WriteError(test.err)
+		buf.WriteInt32(42)
+		buf.Offset = 0
+		got := buf.ReadError()
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %#v, want %#v", test.err, got, test.want)
+		}
+		if n := buf.ReadInt32(); n != 42 {
+			t.Errorf("%v: read past the error: got %d, want 42", test.err, n)
+		}
+	}
+}
@@ -11,20 +11,25 @@ package seq
 
 import (
 	"fmt"
+	"runtime"
 	"sync"
+	"time"
 )
 
 type countedObj struct {
-	obj interface{}
-	cnt int32
+	obj     interface{}
+	cnt     int32
+	created time.Time // when the object was first pinned
+	stack   []uintptr // call stack pinning it, if refs.debug was set
 }
 
 // refs stores Go objects that have been passed to another language.
 var refs struct {
 	sync.Mutex
-	next int32 // next reference number to use for Go object, always negative
-	refs map[interface{}]int32
-	objs map[int32]countedObj
+	next  int32 // next reference number to use for Go object, always negative
+	refs  map[interface{}]int32
+	objs  map[int32]countedObj
+	debug bool // capture the call stacks pinning objects
 }
 
 func init() {
@@ -36,7 +41,9 @@ func init() {
 }
 
 // A Ref represents a Java or Go object passed across the language
-// boundary.
+// boundary. Go objects have negative numbers and foreign objects
+// positive ones; the number 0 is null, such as a null element of a
+// Java array.
 type Ref struct {
 	Num int32
 }
@@ -52,6 +59,35 @@ func (r *Ref) Get() interface{} {
 	return o.obj
 }
 
+// pin pins obj, passed to another language, in the object map and
+// returns its reference number. Pinning an object already in the map
+// increments its reference count.
+func pin(obj interface{}) int32 {
+	refs.Lock()
+	defer refs.Unlock()
+	num := refs.refs[obj]
+	if num != 0 {
+		s := refs.objs[num]
+		s.cnt++
+		refs.objs[num] = s
+		return num
+	}
+	num = refs.next
+	refs.next--
+	if refs.next > 0 {
+		panic("refs.next underflow")
+	}
+	o := countedObj{obj: obj, cnt: 1, created: time.Now()}
+	if refs.debug {
+		// Skip runtime.Callers, pin and Buffer.WriteGoRef.
+		pc := make([]uintptr, 32)
+		o.stack = pc[:runtime.Callers(3, pc)]
+	}
+	refs.refs[obj] = num
+	refs.objs[num] = o
+	return num
+}
+
 // Delete decrements the reference count and removes the pinned object
 // from the object map when the reference count becomes zero.
 func Delete(num int32) {
@@ -65,6 +101,7 @@ func Delete(num int32) {
 		delete(refs.objs, num)
 		delete(refs.refs, o.obj)
 	} else {
-		refs.objs[num] = countedObj{o.obj, o.cnt - 1}
+		o.cnt--
+		refs.objs[num] = o
 	}
 }
@@ -0,0 +1,166 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"time"
+)
+
+// The functions of this file report the Go objects pinned by the
+// references held in other languages, to track down the objects
+// leaked by foreign code never releasing its proxies.
+
+// SetDebugRefs sets whether the call stacks pinning Go objects are
+// recorded, to be printed by WriteLeakReport. Recording them slows
+// down passing new objects to other languages, so it is meant for
+// debugging. Only objects pinned while it is set have a call stack.
+func SetDebugRefs(on bool) {
+	refs.Lock()
+	refs.debug = on
+	refs.Unlock()
+}
+
+// LiveRefs returns the number of Go objects pinned by references
+// held in other languages.
+func LiveRefs() int {
+	refs.Lock()
+	defer refs.Unlock()
+	return len(refs.objs)
+}
+
+// A RefStat summarizes the pinned Go objects of one type.
+type RefStat struct {
+	Type   string    // Go type of the objects, as printed by %T
+	Objs   int       // number of objects pinned
+	Refs   int       // number of references to them
+	Oldest time.Time // when the oldest object was pinned
+}
+
+// RefStats returns the pinned Go objects by type, the types with
+// the most objects first.
+func RefStats() []RefStat {
+	byType := make(map[string]*RefStat)
+	for _, o := range pinned() {
+		typ := fmt.Sprintf("%T", o.obj)
+		s := byType[typ]
+		if s == nil {
+			s = &RefStat{Type: typ, Oldest: o.created}
+			byType[typ] = s
+		}
+		s.Objs++
+		s.Refs += int(o.cnt)
+		if o.created.Before(s.Oldest) {
+			s.Oldest = o.created
+		}
+	}
+	stats := make([]RefStat, 0, len(byType))
+	for _, s := range byType {
+		stats = append(stats, *s)
+	}
+	sort.Sort(refStatsByObjs(stats))
+	return stats
+}
+
+type refStatsByObjs []RefStat
+
+func (a refStatsByObjs) Len() int      { return len(a) }
+func (a refStatsByObjs) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a refStatsByObjs) Less(i, j int) bool {
+	if a[i].Objs != a[j].Objs {
+		return a[i].Objs > a[j].Objs
+	}
+	return a[i].Type < a[j].Type
+}
+
+// WriteLeakReport writes to w a report of the Go objects pinned for
+// at least minAge, grouped by type and by the call stack pinning
+// them, if SetDebugRefs was set then. Objects pinned for long are
+// usually held by foreign objects that are themselves leaked, or by
+// a reference cycle across the language boundary.
+func WriteLeakReport(w io.Writer, minAge time.Duration) error {
+	all := pinned()
+	now := time.Now()
+	var leaks []*leak
+	byKey := make(map[string]*leak)
+	for _, o := range all {
+		if now.Sub(o.created) < minAge {
+			continue
+		}
+		typ := fmt.Sprintf("%T", o.obj)
+		key := fmt.Sprint(typ, o.stack)
+		l := byKey[key]
+		if l == nil {
+			l = &leak{typ: typ, stack: o.stack, oldest: o.created}
+			byKey[key] = l
+			leaks = append(leaks, l)
+		}
+		l.objs++
+		l.refs += int(o.cnt)
+		if o.created.Before(l.oldest) {
+			l.oldest = o.created
+		}
+	}
+	sort.Sort(leaksByObjs(leaks))
+
+	buf := new(bytes.Buffer)
+	n := 0
+	for _, l := range leaks {
+		n += l.objs
+	}
+	fmt.Fprintf(buf, "seq: %d pinned Go objects, %d pinned for at least %v\n", len(all), n, minAge)
+	for _, l := range leaks {
+		fmt.Fprintf(buf, "\n%d %s, %d refs, oldest pinned %v ago\n", l.objs, l.typ, l.refs, now.Sub(l.oldest))
+		if l.stack == nil {
+			continue
+		}
+		for _, pc := range l.stack {
+			f := runtime.FuncForPC(pc - 1)
+			if f == nil {
+				fmt.Fprintf(buf, "\t%#x\n", pc)
+				continue
+			}
+			file, line := f.FileLine(pc - 1)
+			fmt.Fprintf(buf, "\t%s\n\t\t%s:%d\n", f.Name(), file, line)
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// A leak groups the objects of a leak report.
+type leak struct {
+	typ    string
+	stack  []uintptr
+	objs   int
+	refs   int
+	oldest time.Time
+}
+
+type leaksByObjs []*leak
+
+func (a leaksByObjs) Len() int      { return len(a) }
+func (a leaksByObjs) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a leaksByObjs) Less(i, j int) bool {
+	if a[i].objs != a[j].objs {
+		return a[i].objs > a[j].objs
+	}
+	return a[i].oldest.Before(a[j].oldest)
+}
+
+// pinned returns a copy of the pinned objects.
+func pinned() []countedObj {
+	refs.Lock()
+	defer refs.Unlock()
+	objs := make([]countedObj, 0, len(refs.objs))
+	for _, o := range refs.objs {
+		objs = append(objs, o)
+	}
+	return objs
+}
@@ -0,0 +1,71 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type leaky struct{ n int }
+
+func findStat(typ string) (RefStat, bool) {
+	for _, s := range RefStats() {
+		if s.Type == typ {
+			return s, true
+		}
+	}
+	return RefStat{}, false
+}
+
+func TestRefStats(t *testing.T) {
+	live := LiveRefs()
+
+	SetDebugRefs(true)
+	defer SetDebugRefs(false)
+	a, b := &leaky{1}, &leaky{2}
+	buf := new(Buffer)
+	for _, o := range []*leaky{a, a, b} {
+		buf.WriteGoRef(o) // a single call site
+	}
+
+	if got, want := LiveRefs(), live+2; got != want {
+		t.Errorf("LiveRefs()=%d, want %d", got, want)
+	}
+	s, ok := findStat("*seq.leaky")
+	if !ok {
+		t.Fatalf("RefStats(): no *seq.leaky in %v", RefStats())
+	}
+	if s.Objs != 2 || s.Refs != 3 {
+		t.Errorf("RefStats(): got %d objects and %d refs, want 2 and 3", s.Objs, s.Refs)
+	}
+
+	report := new(bytes.Buffer)
+	if err := WriteLeakReport(report, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := report.String(); !strings.Contains(got, "2 *seq.leaky, 3 refs") || !strings.Contains(got, "seq.TestRefStats") {
+		t.Errorf("WriteLeakReport(): got\n%s\nwant the leaky objects pinned by TestRefStats", got)
+	}
+	report.Reset()
+	if err := WriteLeakReport(report, 1<<62); err != nil {
+		t.Fatal(err)
+	}
+	if got := report.String(); strings.Contains(got, "leaky") {
+		t.Errorf("WriteLeakReport(): got\n%s\nwant no recently pinned objects", got)
+	}
+
+	buf.Offset = 0
+	for i := 0; i < 3; i++ {
+		Delete(buf.ReadInt32())
+	}
+	if got := LiveRefs(); got != live {
+		t.Errorf("LiveRefs()=%d after deleting the refs, want %d", got, live)
+	}
+	if _, ok := findStat("*seq.leaky"); ok {
+		t.Errorf("RefStats(): *seq.leaky still pinned after deleting the refs")
+	}
+}
@@ -6,6 +6,8 @@
 //
 // Implementations of Transact and FinalizeRef are provided by a
 // specific foreign language binding package, e.g. go.mobile/bind/java.
+// Package golang.org/x/mobile/bind/seq/seqtest provides them in Go, to
+// test generated bindings on the host.
 //
 // Designed only for use by the code generated by gobind. Don't try to
 // use this directly.
@@ -13,6 +13,10 @@ func TestBuffer(t *testing.T) {
 	buf.WriteUTF16("Hello, world")
 	buf.WriteFloat64(4.02)
 	buf.WriteFloat32(1.2)
+	buf.WriteUint16(1<<16 - 1)
+	buf.WriteUint32(1<<32 - 1)
+	buf.WriteUint64(1<<64 - 1)
+	buf.WriteUint(1 << 31)
 	buf.WriteGoRef(new(int))
 	buf.WriteGoRef(new(int))
 
@@ -33,4 +37,16 @@ func TestBuffer(t *testing.T) {
 	if got, want := buf.ReadFloat32(), float32(1.2); got != want {
 		t.Errorf("buf.ReadFloat32()=%f, want %f", got, want)
 	}
+	if got, want := buf.ReadUint16(), uint16(1<<16-1); got != want {
+		t.Errorf("buf.ReadUint16()=%d, want %d", got, want)
+	}
+	if got, want := buf.ReadUint32(), uint32(1<<32-1); got != want {
+		t.Errorf("buf.ReadUint32()=%d, want %d", got, want)
+	}
+	if got, want := buf.ReadUint64(), uint64(1<<64-1); got != want {
+		t.Errorf("buf.ReadUint64()=%d, want %d", got, want)
+	}
+	if got, want := buf.ReadUint(), uint(1<<31); got != want {
+		t.Errorf("buf.ReadUint()=%d, want %d", got, want)
+	}
 }
@@ -0,0 +1,134 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package seqtest implements the foreign language side of the seq
+// protocol in Go, to test the Go code generated by gobind on the
+// host, without an Android or iOS device.
+//
+// Importing seqtest installs its implementations of seq.Transact,
+// seq.FinalizeRef, seq.EncString and seq.DecString. Tests then call
+// the generated Go functions with Send, as Java and Objective-C do,
+// and implement foreign objects, such as the Java implementations of
+// Go interfaces, with Object. Strings are encoded in UTF-8.
+package seqtest // import "golang.org/x/mobile/bind/seq/seqtest"
+
+import (
+	"fmt"
+	"sync"
+
+	"golang.org/x/mobile/bind/seq"
+)
+
+// An Object is a foreign object passed to Go. Go calls its methods
+// through Call, as the Stub classes generated for Java are called.
+type Object interface {
+	Call(code int, in, out *seq.Buffer)
+}
+
+// objs tracks the foreign objects referred to by Go.
+var objs struct {
+	sync.Mutex
+	next int32 // next reference number, always positive
+	nums map[Object]int32
+	refs map[int32]*foreignRef
+}
+
+type foreignRef struct {
+	obj Object
+	cnt int // number of references held by Go
+}
+
+func init() {
+	objs.next = 42 // foreign objects get positive reference numbers
+	objs.nums = make(map[Object]int32)
+	objs.refs = make(map[int32]*foreignRef)
+
+	seq.Transact = transact
+	seq.FinalizeRef = finalizeRef
+	seq.EncString = func(out *seq.Buffer, v string) { out.WriteUTF8(v) }
+	seq.DecString = func(in *seq.Buffer) string { return in.ReadUTF8() }
+}
+
+// Send calls the Go function registered for descriptor and code with
+// the arguments written to in, and returns its results.
+func Send(descriptor string, code int, in *seq.Buffer) (out *seq.Buffer) {
+	fn := seq.Registry[descriptor][code]
+	if fn == nil {
+		panic(fmt.Sprintf("seqtest: invalid descriptor(%s) and code(0x%x)", descriptor, code))
+	}
+	in.Offset = 0
+	out = new(seq.Buffer)
+	fn(out, in)
+	out.Offset = 0
+	return out
+}
+
+// WriteRef writes a reference to the foreign object o, passing it
+// to Go.
+func WriteRef(b *seq.Buffer, o Object) {
+	objs.Lock()
+	num := objs.nums[o]
+	if num == 0 {
+		num = objs.next
+		objs.next++
+		objs.nums[o] = num
+		objs.refs[num] = &foreignRef{obj: o}
+	}
+	objs.refs[num].cnt++
+	objs.Unlock()
+	b.WriteInt32(num)
+}
+
+// ReadObject reads a reference to a foreign object passed back by Go.
+func ReadObject(b *seq.Buffer) Object {
+	return lookup(b.ReadInt32())
+}
+
+// Release tells Go the foreign language is done with the reference
+// num to a Go object, as the finalizers of Java proxies do.
+func Release(num int32) {
+	seq.Delete(num)
+}
+
+// LiveObjects returns the number of foreign objects referred to by Go.
+func LiveObjects() int {
+	objs.Lock()
+	defer objs.Unlock()
+	return len(objs.refs)
+}
+
+func lookup(num int32) Object {
+	objs.Lock()
+	defer objs.Unlock()
+	r := objs.refs[num]
+	if r == nil {
+		panic(fmt.Sprintf("seqtest: unknown foreign ref %d", num))
+	}
+	return r.obj
+}
+
+func transact(ref *seq.Ref, code int, in *seq.Buffer) *seq.Buffer {
+	o := lookup(ref.Num)
+	in.Offset = 0
+	out := new(seq.Buffer)
+	o.Call(code, in, out)
+	out.Offset = 0
+	return out
+}
+
+func finalizeRef(ref *seq.Ref) {
+	if ref.Num < 0 {
+		panic(fmt.Sprintf("seqtest: not a foreign ref: %d", ref.Num))
+	}
+	objs.Lock()
+	defer objs.Unlock()
+	r := objs.refs[ref.Num]
+	if r == nil {
+		panic(fmt.Sprintf("seqtest: finalizing unknown foreign ref %d", ref.Num))
+	}
+	if r.cnt--; r.cnt == 0 {
+		delete(objs.refs, ref.Num)
+		delete(objs.nums, r.obj)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seqtest
+
+import (
+	"testing"
+
+	"golang.org/x/mobile/bind/seq"
+	_ "golang.org/x/mobile/bind/seq/seqtest/testpkg/go_testpkg"
+)
+
+// Descriptor and codes of the functions of testpkg, numbered in name order.
+const (
+	testpkgDesc    = "golang.org/x/mobile/bind/seq/seqtest/testpkg"
+	callCallI      = 1
+	callDiv        = 2
+	callHello      = 3
+	callNewCounter = 4
+)
+
+// Descriptor and codes of the testpkg.Counter struct.
+const (
+	counterDesc     = "go." + testpkgDesc + ".Counter"
+	counterValueGet = 0x00f
+	counterValueSet = 0x01f
+	counterInc      = 0x00c
+)
+
+func TestFunc(t *testing.T) {
+	in := new(seq.Buffer)
+	in.WriteString("gopher")
+	out := Send(testpkgDesc, callHello, in)
+	if got, want := out.ReadString(), "Hello, gopher"; got != want {
+		t.Errorf("Hello: got %q, want %q", got, want)
+	}
+}
+
+func TestError(t *testing.T) {
+	div := func(a, b int) (int, error) {
+		in := new(seq.Buffer)
+		in.WriteInt(a)
+		in.WriteInt(b)
+		out := Send(testpkgDesc, callDiv, in)
+		return out.ReadInt(), out.ReadError()
+	}
+	if v, err := div(7, 2); v != 3 || err != nil {
+		t.Errorf("Div(7, 2)=%d, %v, want 3, nil", v, err)
+	}
+	_, err := div(1, 0)
+	if err == nil || err.Error() != "division by zero" {
+		t.Errorf("Div(1, 0) error: got %v, want division by zero", err)
+	}
+}
+
+func TestGoRef(t *testing.T) {
+	live := seq.LiveRefs()
+	out := Send(testpkgDesc, callNewCounter, new(seq.Buffer))
+	num := out.ReadInt32()
+	if num >= 0 {
+		t.Fatalf("NewCounter: got ref %d, want a Go ref", num)
+	}
+	call := func(code int, args ...int) *seq.Buffer {
+		in := new(seq.Buffer)
+		in.WriteInt32(num)
+		for _, v := range args {
+			in.WriteInt(v)
+		}
+		return Send(counterDesc, code, in)
+	}
+	call(counterValueSet, 41)
+	call(counterInc)
+	if v := call(counterValueGet).ReadInt(); v != 42 {
+		t.Errorf("Counter.Value=%d, want 42", v)
+	}
+
+	if got := seq.LiveRefs(); got != live+1 {
+		t.Errorf("LiveRefs()=%d while holding the counter, want %d", got, live+1)
+	}
+	Release(num)
+	if got := seq.LiveRefs(); got != live {
+		t.Errorf("LiveRefs()=%d after releasing the counter, want %d", got, live)
+	}
+}
+
+// times implements testpkg.I.
+type times struct {
+	k int
+}
+
+func (x *times) Call(code int, in, out *seq.Buffer) {
+	switch code {
+	case 0x10a: // Times
+		out.WriteInt(x.k * in.ReadInt())
+	default:
+		panic("unknown code")
+	}
+}
+
+func TestCallback(t *testing.T) {
+	in := new(seq.Buffer)
+	WriteRef(in, &times{3})
+	in.WriteInt(5)
+	out := Send(testpkgDesc, callCallI, in)
+	if got := out.ReadInt(); got != 15 {
+		t.Errorf("CallI(times{3}, 5)=%d, want 15", got)
+	}
+
+	if LiveObjects() == 0 {
+		t.Errorf("LiveObjects()=0, want the foreign object tracked")
+	}
+}
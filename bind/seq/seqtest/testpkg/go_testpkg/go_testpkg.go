@@ -0,0 +1,131 @@
+// Package go_testpkg is an autogenerated binder stub for package testpkg.
+//   gobind -lang=go golang.org/x/mobile/bind/seq/seqtest/testpkg
+//
+// File is generated by gobind. Do not edit.
+package go_testpkg
+
+import (
+	"golang.org/x/mobile/bind/seq"
+	"golang.org/x/mobile/bind/seq/seqtest/testpkg"
+)
+
+func proxy_CallI(out, in *seq.Buffer) {
+	var param_i testpkg.I
+	param_i_ref := in.ReadRef()
+	if param_i_ref.Num < 0 { // go object
+		param_i = param_i_ref.Get().(testpkg.I)
+	} else if param_i_ref.Num > 0 { // foreign object, not null
+		param_i = (*proxyI)(param_i_ref)
+	}
+	param_v := in.ReadInt()
+	res := testpkg.CallI(param_i, param_v)
+	out.WriteInt(res)
+}
+
+const (
+	proxyCounter_Descriptor     = "go.golang.org/x/mobile/bind/seq/seqtest/testpkg.Counter"
+	proxyCounter_cast_Code      = 0x00e
+	proxyCounter_Value_Get_Code = 0x00f
+	proxyCounter_Value_Set_Code = 0x01f
+	proxyCounter_Inc_Code       = 0x00c
+)
+
+type proxyCounter seq.Ref
+
+func proxyCounter_cast(out, in *seq.Buffer) {
+	ref := in.ReadRef()
+	ok := false
+	if ref.Num < 0 { // go object
+		_, ok = ref.Get().(*testpkg.Counter)
+	}
+	out.WriteBool(ok)
+}
+
+func proxyCounter_Value_Set(out, in *seq.Buffer) {
+	ref := in.ReadRef()
+	v := in.ReadInt()
+	ref.Get().(*testpkg.Counter).Value = v
+}
+
+func proxyCounter_Value_Get(out, in *seq.Buffer) {
+	ref := in.ReadRef()
+	v := ref.Get().(*testpkg.Counter).Value
+	out.WriteInt(v)
+}
+
+func proxyCounter_Inc(out, in *seq.Buffer) {
+	ref := in.ReadRef()
+	v := ref.Get().(*testpkg.Counter)
+	v.Inc()
+}
+
+func init() {
+	seq.Register(proxyCounter_Descriptor, proxyCounter_cast_Code, proxyCounter_cast)
+	seq.Register(proxyCounter_Descriptor, proxyCounter_Value_Set_Code, proxyCounter_Value_Set)
+	seq.Register(proxyCounter_Descriptor, proxyCounter_Value_Get_Code, proxyCounter_Value_Get)
+	seq.Register(proxyCounter_Descriptor, proxyCounter_Inc_Code, proxyCounter_Inc)
+}
+
+func proxy_Div(out, in *seq.Buffer) {
+	param_a := in.ReadInt()
+	param_b := in.ReadInt()
+	res, err := testpkg.Div(param_a, param_b)
+	out.WriteInt(res)
+	out.WriteError(err)
+}
+
+func proxy_Hello(out, in *seq.Buffer) {
+	param_name := in.ReadString()
+	res := testpkg.Hello(param_name)
+	out.WriteString(res)
+}
+
+const (
+	proxyI_Descriptor = "go.golang.org/x/mobile/bind/seq/seqtest/testpkg.I"
+	proxyI_cast_Code  = 0x00e
+	proxyI_Times_Code = 0x10a
+)
+
+func proxyI_cast(out, in *seq.Buffer) {
+	ref := in.ReadRef()
+	ok := false
+	if ref.Num < 0 { // go object
+		_, ok = ref.Get().(testpkg.I)
+	}
+	out.WriteBool(ok)
+}
+
+func proxyI_Times(out, in *seq.Buffer) {
+	ref := in.ReadRef()
+	v := ref.Get().(testpkg.I)
+	param_v := in.ReadInt()
+	res := v.Times(param_v)
+	out.WriteInt(res)
+}
+
+func init() {
+	seq.Register(proxyI_Descriptor, proxyI_cast_Code, proxyI_cast)
+	seq.Register(proxyI_Descriptor, proxyI_Times_Code, proxyI_Times)
+}
+
+type proxyI seq.Ref
+
+func (p *proxyI) Times(v int) int {
+	in := new(seq.Buffer)
+	in.WriteInt(v)
+	out := seq.Transact((*seq.Ref)(p), proxyI_Times_Code, in)
+	res_0 := out.ReadInt()
+	return res_0
+}
+
+func proxy_NewCounter(out, in *seq.Buffer) {
+	res := testpkg.NewCounter()
+	out.WriteGoRef(res)
+}
+
+func init() {
+	seq.Register("golang.org/x/mobile/bind/seq/seqtest/testpkg", 1, proxy_CallI)
+	seq.Register("golang.org/x/mobile/bind/seq/seqtest/testpkg", 2, proxy_Div)
+	seq.Register("golang.org/x/mobile/bind/seq/seqtest/testpkg", 3, proxy_Hello)
+	seq.Register("golang.org/x/mobile/bind/seq/seqtest/testpkg", 4, proxy_NewCounter)
+}
@@ -0,0 +1,41 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package testpkg contains bound functions for testing the Go code
+// generated by gobind on the host.
+// This is used in tests of golang.org/x/mobile/bind/seq/seqtest.
+package testpkg
+
+//go:generate gobind -lang=go -outdir=go_testpkg golang.org/x/mobile/bind/seq/seqtest/testpkg
+
+import "errors"
+
+func Hello(name string) string {
+	return "Hello, " + name
+}
+
+var errDivByZero = errors.New("division by zero")
+
+func Div(a, b int) (int, error) {
+	if b == 0 {
+		return 0, errDivByZero
+	}
+	return a / b, nil
+}
+
+type Counter struct {
+	Value int
+}
+
+func (c *Counter) Inc() { c.Value++ }
+
+func NewCounter() *Counter { return new(Counter) }
+
+type I interface {
+	Times(v int) int
+}
+
+func CallI(i I, v int) int {
+	return i.Times(v)
+}
@@ -0,0 +1,61 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import "sync"
+
+// Receive-only channels are passed to foreign code as a reference
+// to a Stream. Foreign code receives the values of the channel
+// one at a time by calling StreamNextCode, which blocks until a
+// value is received and writes true followed by the value, or
+// writes false if the channel is closed or the stream canceled.
+// Calling StreamCloseCode cancels the stream.
+const (
+	StreamDescriptor = "go.seq.Stream"
+	StreamNextCode   = 1
+	StreamCloseCode  = 2
+)
+
+// A Stream is a receive-only channel passed to foreign code.
+type Stream struct {
+	recv func(out *Buffer, done <-chan struct{})
+
+	once sync.Once
+	done chan struct{}
+}
+
+// NewStream returns a Stream receiving values with recv.
+//
+// recv must receive a value from the channel and write true and
+// the value to out, or write false if the channel is closed or
+// done is closed before a value is received.
+func NewStream(recv func(out *Buffer, done <-chan struct{})) *Stream {
+	return &Stream{
+		recv: recv,
+		done: make(chan struct{}),
+	}
+}
+
+// Close cancels the stream. Blocked and later calls to receive
+// values report the end of the stream. Close does not drain the
+// channel: senders blocked on it stay blocked.
+func (s *Stream) Close() {
+	s.once.Do(func() { close(s.done) })
+}
+
+func init() {
+	Register(StreamDescriptor, StreamNextCode, func(out, in *Buffer) {
+		s := in.ReadRef().Get().(*Stream)
+		select {
+		case <-s.done:
+			out.WriteBool(false)
+		default:
+			s.recv(out, s.done)
+		}
+	})
+	Register(StreamDescriptor, StreamCloseCode, func(out, in *Buffer) {
+		in.ReadRef().Get().(*Stream).Close()
+	})
+}
@@ -0,0 +1,63 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package seq
+
+import "testing"
+
+func streamCall(s *Stream, code int) *Buffer {
+	in := new(Buffer)
+	in.WriteGoRef(s)
+	in.Offset = 0
+	out := new(Buffer)
+	Registry[StreamDescriptor][code](out, in)
+	out.Offset = 0
+	return out
+}
+
+func newIntStream(c <-chan int) *Stream {
+	return NewStream(func(out *Buffer, done <-chan struct{}) {
+		select {
+		case v, ok := <-c:
+			out.WriteBool(ok)
+			if ok {
+				out.WriteInt(v)
+			}
+		case <-done:
+			out.WriteBool(false)
+		}
+	})
+}
+
+func TestStream(t *testing.T) {
+	c := make(chan int, 2)
+	c <- 1
+	c <- 2
+	close(c)
+	s := newIntStream(c)
+
+	for _, want := range []int{1, 2} {
+		out := streamCall(s, StreamNextCode)
+		if !out.ReadBool() {
+			t.Fatalf("stream ended early, want %d", want)
+		}
+		if got := out.ReadInt(); got != want {
+			t.Errorf("got %d, want %d", got, want)
+		}
+	}
+	if streamCall(s, StreamNextCode).ReadBool() {
+		t.Error("got a value from a closed channel")
+	}
+}
+
+func TestStreamClose(t *testing.T) {
+	c := make(chan int, 1)
+	c <- 1
+	s := newIntStream(c)
+	streamCall(s, StreamCloseCode)
+	streamCall(s, StreamCloseCode) // closing twice is fine
+	if streamCall(s, StreamNextCode).ReadBool() {
+		t.Error("got a value from a canceled stream")
+	}
+}
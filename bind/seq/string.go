@@ -5,7 +5,6 @@
 package seq
 
 import (
-	"errors"
 	"fmt"
 	"unicode/utf16"
 	"unsafe"
@@ -86,13 +85,6 @@ func (b *Buffer) WriteUTF8(s string) {
 
 const maxSliceLen = (1<<31 - 1) / 2
 
-func (b *Buffer) ReadError() error {
-	if s := b.ReadUTF16(); s != "" {
-		return errors.New(s)
-	}
-	return nil
-}
-
 func (b *Buffer) ReadUTF16() string {
 	size := int(b.ReadInt32())
 	if size == 0 {
@@ -6,6 +6,10 @@ package basictypes
 
 func Ints(x int8, y int16, z int32, t int64, u int) {}
 
+func Uints(x uint16, y uint32, z uint64, u uint) {}
+
+func Hash(s string) uint32 { return 0 }
+
 func Error() error { return nil }
 
 func ErrorPair() (int, error) { return 0, nil }
@@ -0,0 +1,31 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package casts
+
+type Shape interface {
+	Area() float64
+}
+
+type Named interface {
+	Name() string
+}
+
+// Square implements Shape and Named.
+type Square struct {
+	Side float64
+}
+
+func (s *Square) Area() float64 { return s.Side * s.Side }
+func (s *Square) Name() string  { return "square" }
+
+type circle struct {
+	r float64
+}
+
+func (c circle) Area() float64 { return 3 * c.r * c.r }
+
+func NewCircle(r float64) Shape { return circle{r} }
+
+func NewSquare(side float64) Shape { return &Square{side} }
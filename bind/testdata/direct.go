@@ -0,0 +1,19 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package direct
+
+import "golang.org/x/mobile/bind/seq"
+
+func Fill(b *seq.DirectBuffer, v byte) {}
+
+func Same(b *seq.DirectBuffer) *seq.DirectBuffer { return b }
+
+type Frame struct {
+	Pixels *seq.DirectBuffer
+}
+
+type Sink interface {
+	Write(b *seq.DirectBuffer) error
+}
@@ -0,0 +1,36 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package docs tests the doc comments of the generated bindings.
+package docs
+
+// Limit is the largest count.
+const Limit = 10
+
+// Greeting is written by Greet.
+var Greeting = "hello"
+
+// Counter counts, up to Limit.
+//
+// Counters are made by docs.NewCounter.
+type Counter struct {
+	// Value is the current count.
+	Value int
+	Step  int // added by Inc
+}
+
+// Inc adds Step to Value. The */ ends no comment.
+func (c *Counter) Inc() {}
+
+// NewCounter returns a Counter at 0.
+func NewCounter() *Counter { return nil }
+
+// Greet returns Greeting, or an error.
+func Greet(name string) (string, error) { return "", nil }
+
+// A Listener is notified of the changes of a Counter.
+type Listener interface {
+	// Changed is called with the new Value.
+	Changed(v int)
+}
@@ -0,0 +1,27 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package draw
+
+import "geom"
+
+type Canvas struct {
+	Origin geom.Point
+}
+
+func (c *Canvas) Add(s geom.Shape) error { return nil }
+
+func (c *Canvas) At(p *geom.Point) []geom.Shape { return nil }
+
+func New(size *geom.Point) *Canvas { return nil }
+
+// A Circle is a geom.Shape, so its classes implement those of Shape.
+type Circle struct {
+	C geom.Point
+	R float64
+}
+
+func (c *Circle) Area() float64 { return 3 * c.R * c.R }
+
+func (c *Circle) Center() *geom.Point { return &c.C }
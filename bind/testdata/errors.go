@@ -0,0 +1,22 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package errors
+
+type NotFound struct {
+	Name string
+}
+
+func (e *NotFound) Error() string { return e.Name + " not found" }
+
+type Status int
+
+func (s Status) Error() string { return "status error" }
+func (s Status) Code() int     { return int(s) }
+
+func Open(name string) (*NotFound, error) { return nil, nil }
+
+type Opener interface {
+	Open(name string) error
+}
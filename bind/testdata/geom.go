@@ -0,0 +1,14 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package geom
+
+type Point struct {
+	X, Y float64
+}
+
+type Shape interface {
+	Area() float64
+	Center() *Point
+}
@@ -0,0 +1,17 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package results
+
+func Split(s string) (head, tail string, err error) { return "", "", nil }
+
+func MinMax(v []int32) (int32, int32) { return 0, 0 }
+
+type S struct{}
+
+func (s *S) Lookup(key string) (v *S, ok bool) { return nil, false }
+
+type I interface {
+	Pair() (int, string, error)
+}
@@ -0,0 +1,38 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package slices
+
+type S struct {
+	Names []string
+	Ss    []*S
+}
+
+type I interface {
+	Values() []int32
+	Siblings(s []I) ([]*S, error)
+	Join(sep string, names ...string) string
+}
+
+func Int32s(v []int32) []int32 { return v }
+
+func Float64s(v []float64) ([]float64, error) { return v, nil }
+
+func Strings(v []string) []string { return v }
+
+func Structs(v []*S) []*S { return v }
+
+func Interfaces(v []I) []I { return v }
+
+func Array(v [3]int64) [3]int64 { return v }
+
+func Uints(a []uint16, b []uint32, c []uint64, d []uint) []uint32 { return b }
+
+func Sum(xs ...int32) int32 {
+	var sum int32
+	for _, x := range xs {
+		sum += x
+	}
+	return sum
+}
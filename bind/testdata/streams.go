@@ -0,0 +1,17 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package streams
+
+type Event struct {
+	Name string
+}
+
+func Progress() <-chan float64 { return nil }
+
+func Events(filter string) (<-chan *Event, error) { return nil, nil }
+
+type Watcher struct{}
+
+func (w *Watcher) Names() <-chan string { return nil }
@@ -24,3 +24,20 @@ func Identity(s *S) *S {
 func IdentityWithError(s *S) (*S, error) {
 	return s, nil
 }
+
+type T struct {
+	Name   string
+	Data   []byte
+	Next   *T
+	Origin S
+	Shape  I
+	Err    error
+}
+
+type I interface {
+	Translate(s S) S
+}
+
+func Value(s S) S {
+	return s
+}
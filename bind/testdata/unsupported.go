@@ -0,0 +1,70 @@
+// Copyright 2015 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package unsupported
+
+import "go/token"
+
+const C complex128 = 1i
+
+const Overflow = 1 << 63
+
+var E error
+
+type Celsius float64
+
+func Temp() Celsius { return 0 }
+
+func Callback(f func()) {}
+
+func Send(c chan int) {}
+
+func Lookup(k map[S]int) {}
+
+func Nested() [][]int { return nil }
+
+func Pos() token.Pos { return 0 }
+
+func Errs() (error, int) { return nil, 0 }
+
+type S struct {
+	X int
+	P *int
+}
+
+func (s *S) Bad() {}
+
+// T uses S, omitted with it.
+type T struct {
+	S *S
+}
+
+type I interface {
+	Values() <-chan int
+}
+
+func UseI(i I) {}
+
+type Good struct {
+	X int
+}
+
+func (g *Good) Values() <-chan int { return nil }
+
+func Hello(name string) string { return name }
+
+type hidden struct{}
+
+func UseHidden(h *hidden) {}
+
+func Callbacks(fs ...func()) {}
+
+func Sum(xs ...int) int { return 0 }
+
+// DivResult collides with the Java class of the results of Div.
+type DivResult struct {
+	Q, R int
+}
+
+func Div(a, b int) (int, int) { return a / b, a % b }
@@ -0,0 +1,34 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package vars
+
+const (
+	AString = "a string\n\"quoted\""
+	AnInt   = 7
+	ABool   = true
+	AFloat  = 0.12345
+	Log2E   = 1.4426950408889634
+
+	MinInt64  int64  = -1 << 63
+	MaxUint32 uint32 = 1<<32 - 1
+	Small     int8   = -3
+	Big              = 1 << 40
+)
+
+var (
+	AStringVar string
+	AnIntVar   int
+	ABoolVar   bool
+	AFloatVar  float64
+	ABytesVar  []byte
+	AStructVar *S
+	AnIfaceVar I
+)
+
+type S struct{}
+
+type I interface {
+	F()
+}
@@ -24,6 +24,12 @@ package can then be _ imported into a Go program, typically built
 with -buildmode=c-archive for iOS or -buildmode=c-shared for Android.
 These details are handled by the `gomobile bind` command.
 
+The doc comments of the package and of its exported declarations are
+copied to the generated declarations, as Javadoc in Java and as
+header docs in Objective-C. The names of the bound declarations they
+mention are replaced by their generated names, such as GoMypkgNew for
+New in Objective-C.
+
 Passing Go objects to target languages
 
 Consider a type for counting:
@@ -121,7 +127,68 @@ The Java implementation can be used like so:
 	Myfmt.Printer printer = new SysPrint();
 	Myfmt.PrintHello(printer);
 
-Objective-C support for interfaces will be available soon.
+In Objective-C, a Go interface Printer is bound as a protocol
+GoMyfmtPrinter, and the Go values of other types implementing it are
+returned as instances of a class of the same name conforming to it.
+Objective-C implementations of Go interfaces are not yet supported.
+
+Calls from Go to target language objects block until they return.
+For each method M of an interface with no results other than an
+error, the Go proxy of target language objects also has a method
+MAsync, with the parameters of M and no results, queuing the call and
+returning immediately. The calls on an object are made in order,
+and the errors they return are passed to the handler set with
+SetAsyncErrorHandler in package golang.org/x/mobile/bind/seq. A Go
+caller reaches MAsync with a type assertion:
+
+	type asyncTracer interface {
+		EventAsync(name string)
+	}
+
+	if t, ok := tracer.(asyncTracer); ok {
+		t.EventAsync("start")
+	} else {
+		tracer.Event("start")
+	}
+
+At most seq.MaxAsync calls are queued and not yet started; MAsync
+blocks while the queue is full. A call leaves the queue when it
+starts, so the callbacks into Go of a call may call MAsync, but not
+in a loop: a callback queuing more than seq.MaxAsync calls on the
+object of its call blocks forever.
+
+Implemented interfaces and casts
+
+The Java class of a Go struct type S implements the Java interface of
+each exported interface that *S implements, of its package and of the
+bound packages that its package imports, and its Objective-C class
+conforms to the protocol of each of them. For instance, with
+
+	type Shape interface {
+		Area() float64
+	}
+
+	type Square struct {
+		Side float64
+	}
+
+	func (s *Square) Area() float64 { return s.Side * s.Side }
+
+	func Unit() Shape { return &Square{Side: 1} }
+
+the Java class Square implements Shape, and GoPkgSquare conforms to
+the GoPkgShape protocol. Interfaces with methods returning several
+values or channels are not implemented in Java, their methods having
+distinct Java signatures in the struct classes.
+
+A Go value received as an interface is not an instance of the class
+of its dynamic type. For each struct and interface type T, gobind
+generates a checked cast: Pkg.castT in Java and GoPkgCastT in
+Objective-C. It asks Go whether the received object refers to a Go
+value of type T, and returns a proxy of type T for it if it does, or
+null (nil) otherwise:
+
+	Square s = Pkg.castSquare(Pkg.Unit());
 
 Type restrictions
 
@@ -132,30 +199,211 @@ Supported types include:
 
 	- Signed integer and floating point types.
 
+	- Unsigned integer types. Java has no unsigned types, so uint16
+	  is widened to int, uint32 to long, and uint64 and uint are
+	  mapped to long with the same bits: values above
+	  Long.MAX_VALUE appear negative in Java. Values passed from
+	  Java that do not fit the Go type are truncated to its low
+	  bits. In Objective-C they are uint16_t, uint32_t, uint64_t
+	  and NSUInteger.
+
 	- String and boolean types.
 
-	- Byte slice types.
+	- Byte slice types. Byte slices are copied across the language
+	  boundary. To pass large buffers without copying, use
+	  *seq.DirectBuffer from package golang.org/x/mobile/bind/seq,
+	  a direct java.nio.ByteBuffer in Java and an NSMutableData in
+	  Objective-C, whose memory Go reads and writes in place.
+
+	- Slice and array types whose elements are integers, floating
+	  point numbers, booleans, strings, pointers to supported
+	  struct types or supported interface types. In Java they are
+	  arrays of the element type, widened like unsigned integers,
+	  in Objective-C byte slices are NSData and other slices are
+	  NSArray, with numbers boxed as NSNumber.
+
+	- Map types whose keys are of basic types and whose values
+	  are of supported types other than channels. In Java they are
+	  java.util.Map, in Objective-C NSDictionary, with numbers
+	  boxed as NSNumber. Maps are copied across the language
+	  boundary, and a null or nil map is sent as an empty map.
+
+	- Receive-only channels of supported types, as results of
+	  functions and methods only. In Java they are go.Seq.Stream,
+	  an Iterator with a close method and a listen method taking
+	  a callback; in Objective-C they are GoSeqStream, with a
+	  blocking next method, a block-based subscribe method and a
+	  cancel method. Values are received from the channel as they
+	  are requested. Closing or canceling the stream ends it on
+	  both sides, but does not drain the channel.
 
 	- Any function type all of whose parameters and results have
-	  supported types. Functions must return either no results,
-	  one result, or two results where the type of the second is
-	  the built-in 'error' type.
+	  supported types. Only the last result may be of the built-in
+	  'error' type. A function with more than one other result
+	  returns an instance of a generated class in Java, named after
+	  the function with a Result suffix and holding one field per
+	  result, and returns its results through pointer parameters
+	  in Objective-C. The package must not have an exported type of
+	  the name of that class. The last parameter of a variadic
+	  function is a varargs parameter in Java and an NSArray in
+	  Objective-C.
 
 	- Any interface type, all of whose exported methods have
 	  supported function types.
 
 	- Any struct type, all of whose exported methods have
 	  supported function types and all of whose exported fields
-	  have supported types.
+	  have supported types. Fields of type error are not bound.
+	  Structs may be passed by pointer or by value. A struct
+	  passed by value is copied, and the copy is passed by
+	  pointer. A null or nil struct passed to Go by value is its
+	  zero value.
+
+Exported package-level constants of boolean, numeric and string
+type are copied into the generated code: as static final fields of
+the package class in Java, and as static constants in Objective-C.
+Untyped constants have their default Go type; the int constants that
+do not fit in 32 bits are int64_t in Objective-C. A constant whose
+value does not fit its type, such as 1<<63 as an int, is an error.
+Exported package-level variables of supported types are accessed
+through generated functions, getX and setX in Java, and GoPkgX and
+GoPkgSetX in Objective-C, which read and write the Go variable.
 
 Unexported symbols have no effect on the cross-language interface, and
 as such are not restricted.
 
+Before generating bindings, gobind checks the exported declarations
+of the packages, and reports those it cannot bind with their
+location, the reason and what would bind instead, such as:
+
+	p.go:12:6: Send: channels are only supported as results of functions and struct methods (return the channel from a function, or pass an interface called for each value)
+
+The -check flag only reports them. The -skip flag omits them with a
+warning instead of failing, together with the struct and interface
+types having such fields or methods and the declarations using the
+omitted types.
+
 The set of supported types will eventually be expanded to cover more
 Go types, but this is a work in progress.
 
-Exceptions and panics are not yet supported. If either pass a language
-boundary, the program will exit.
+Binding several packages
+
+Several packages can be bound together by naming them all:
+
+	gobind -lang=java -outdir=out example.com/sdk/a example.com/sdk/b
+
+The bindings of each package may then use the struct and interface
+types of the others, such as a function of package a returning a
+*b.Thing. In Java the type is go.b.B.Thing, in Objective-C it is
+GoBThing, declared in the GoB.h header included by GoA.h. The
+packages bound together must have distinct names.
+
+Naming
+
+By default, the class of a bound package mypkg is in the Java
+package go.mypkg, and the Objective-C names of its declarations
+start with GoMypkg. Libraries used by the same app must not declare
+the same names, so the -javapkg flag sets the prefix of the Java
+packages and the -prefix flag the Objective-C prefix:
+
+	gobind -lang=java -javapkg=com.example.sdk example.com/sdk/api
+	gobind -lang=objc -prefix=EXS example.com/sdk/api
+
+generate the class com.example.sdk.api.Api, and for a function New
+the Objective-C function EXSApiNew declared in EXSApi.h. The flags
+apply to all the packages bound together, and also to -lang=kotlin
+and -lang=swift. They do not change the Go bindings, nor the names
+of the support classes of package golang.org/x/mobile/bind, such as
+go.Seq in Java and GoSeqRef in Objective-C.
+
+Errors
+
+A non-nil error returned by Go is thrown in Java as a
+go.Seq.GoException, and returned in Objective-C as an NSError. The
+error keeps the name of its Go type, such as "net/url.Error", as the
+exception type or NSError domain, and the result of its Code method,
+if it has a method Code() int, as the exception or NSError code.
+
+For each exported type T of the bound package implementing error,
+gobind generates a TException class extending go.Seq.GoException in
+Java, thrown in place of the generic exception, and a GoPkgTDomain
+constant holding the NSError domain in Objective-C.
+
+An exception thrown by a Java implementation of a Go interface is
+returned in Go as a *seq.ForeignError from package
+golang.org/x/mobile/bind/seq, holding the exception message, class
+name and, for a GoException, its code. A ForeignError passed back
+to Java keeps its type and code.
+
+Other exceptions and panics are not yet supported. If either pass a
+language boundary, the program will exit.
+
+Kotlin
+
+Kotlin uses the Java bindings. With -lang=kotlin, gobind generates a
+Kotlin file, Mypkg.kt, declaring in package go.mypkg:
+
+	- for each function F, a top-level function f with Kotlin types,
+	  which are nullable for the values Go may pass as nil: slices,
+	  *seq.DirectBuffer, structs, pointers to structs and interfaces;
+	- for each variable V, a top-level property v;
+	- for each function or method F returning an error, a function f
+	  returning a kotlin.Result in place of throwing the error;
+	- for each function or method returning several values, the
+	  component functions destructuring its result class.
+
+With the Counter example above and a function
+DivMod(a, b int) (q, r int, err error):
+
+	val c = new()!! // New never returns nil
+	c.value = 3     // struct fields are Kotlin properties
+	val (q, r) = divMod(7, 2).getOrThrow()
+
+The Kotlin file is compiled together with the Java bindings generated
+with -lang=java.
+
+Swift
+
+Swift uses the Objective-C bindings, imported through a bridging
+header. With -lang=swift, gobind generates a Swift file,
+GoMypkg.swift, declaring a Mypkg enum holding, without the Go prefix
+of their Objective-C names:
+
+	- type aliases of the class of each struct and of the protocol of
+	  each interface;
+	- a static function for each function, throwing the error returned
+	  by Go and returning several values as a tuple;
+	- static properties for the variables and constants.
+
+Values that may be nil, slices, *seq.DirectBuffer, structs and
+interfaces, have optional types; the others do not. With the Counter
+example above:
+
+	let c = Mypkg.new()!
+	c.setValue(3)
+	let (q, r) = try Mypkg.divMod(7, 2)
+
+Structs remain classes rather than Swift structs: only Go can create a
+Go struct, and its value is shared with Go by reference. Their methods
+are those of the Objective-C classes, which Swift imports as throwing
+if they return an error.
+
+API compatibility
+
+With -lang=api, gobind describes the bound API of a package, one line
+per declaration, in the format of the API files of the Go
+distribution:
+
+	pkg example.com/mypkg, func New() *Counter
+	pkg example.com/mypkg, method (*Counter) Inc()
+	pkg example.com/mypkg, type Counter struct, Value int64
+
+Comparing the description of a release with the one of the next
+tells whether the bindings of the new version break the apps using
+them: removing a declaration or changing its type breaks them, adding
+a declaration does not, except for a method added to an interface
+implemented in another language. The gomobile bind command checks
+the API against a description with its -apidiff flag.
 
 Avoid reference cycles
 
@@ -178,6 +426,21 @@ We recommend that implementations of foreign interfaces do not hold
 references to proxies of objects. That is: if you extend a Stub in
 Java, do not store an instance of Seq.Object inside it.
 
+The Go objects held by other languages can be inspected from Go with
+package golang.org/x/mobile/bind/seq: LiveRefs returns their number,
+RefStats counts them by type, and WriteLeakReport lists those held
+for longer than a given duration. After calling SetDebugRefs(true),
+the report includes the call stack that passed each object to the
+other language.
+
+Testing bindings
+
+The Go code generated by gobind can be tested on the host, without a
+device. Package golang.org/x/mobile/bind/seq/seqtest plays the part
+of the other language: a test imports the generated go_<pkg> package,
+calls its functions with seqtest.Send and implements Go interfaces
+with seqtest.Object. See bind/seq/seqtest/testpkg for an example.
+
 Further reading
 
 Examples can be found in http://golang.org/x/mobile/example.
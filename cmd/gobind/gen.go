@@ -22,52 +22,105 @@ import (
 	"golang.org/x/tools/go/types"
 )
 
-func genPkg(pkg *build.Package) {
-	if len(pkg.CgoFiles) > 0 {
-		errorf("gobind: cannot use cgo-dependent package as service definition: %s", pkg.CgoFiles[0])
-		return
-	}
-
-	files := parseFiles(pkg.Dir, pkg.GoFiles)
-	if len(files) == 0 {
-		return // some error has been reported
-	}
-
+// genPkgs type-checks the packages together and generates the
+// bindings of each of them. The bindings of a package may refer
+// to the types of the other packages.
+func genPkgs(pkgs []*build.Package) {
 	conf := loader.Config{
 		Fset: fset,
 	}
 	conf.TypeChecker.Error = func(err error) {
 		errorf("%v", err)
 	}
-	conf.CreateFromFiles(pkg.ImportPath, files...)
+
+	names := make(map[string]string) // package name -> import path
+	for _, pkg := range pkgs {
+		if len(pkg.CgoFiles) > 0 {
+			errorf("gobind: cannot use cgo-dependent package as service definition: %s", pkg.CgoFiles[0])
+			return
+		}
+		// Bindings are named after their package.
+		if path, ok := names[pkg.Name]; ok {
+			errorf("gobind: packages %s and %s have the same name %s", path, pkg.ImportPath, pkg.Name)
+			return
+		}
+		names[pkg.Name] = pkg.ImportPath
+
+		files := parseFiles(pkg.Dir, pkg.GoFiles)
+		if len(files) == 0 {
+			return // some error has been reported
+		}
+		conf.CreateFromFiles(pkg.ImportPath, files...)
+	}
+
 	program, err := conf.Load()
 	if err != nil {
 		errorf("%v", err)
 		return
 	}
-	p := program.Created[0].Pkg
+	var allPkg []*types.Package
+	for _, info := range program.Created {
+		allPkg = append(allPkg, info.Pkg)
+	}
+
+	// Declarations that cannot be bound are reported before generating
+	// anything, or omitted with -skip.
+	if *skip && !*check {
+		var probs []*bind.Problem
+		allPkg, probs = bind.Omit(fset, allPkg)
+		for _, p := range probs {
+			warnf("omitted %v", p)
+		}
+	} else {
+		for _, p := range allPkg {
+			for _, prob := range bind.Check(fset, p, allPkg) {
+				errorf("%v", prob)
+			}
+		}
+	}
+	if *check || exitStatus != 0 {
+		return
+	}
+
+	for _, p := range allPkg {
+		genPkg(p, allPkg)
+	}
+}
 
+func genPkg(p *types.Package, allPkg []*types.Package) {
 	fname := defaultFileName(*lang, p)
 	switch *lang {
 	case "java":
 		w, closer := writer(fname, p)
-		processErr(bind.GenJava(w, fset, p))
+		processErr(bind.GenJava(w, fset, p, allPkg, *javaPkg))
+		closer()
+	case "swift":
+		w, closer := writer(fname, p)
+		processErr(bind.GenSwift(w, fset, p, allPkg, *prefix))
+		closer()
+	case "kotlin":
+		w, closer := writer(fname, p)
+		processErr(bind.GenKotlin(w, fset, p, allPkg, *javaPkg))
 		closer()
 	case "go":
 		w, closer := writer(fname, p)
-		processErr(bind.GenGo(w, fset, p))
+		processErr(bind.GenGo(w, fset, p, allPkg))
+		closer()
+	case "api":
+		w, closer := writer(fname, p)
+		processErr(bind.GenAPI(w, p))
 		closer()
 	case "objc":
 		if fname == "" {
-			processErr(bind.GenObjc(os.Stdout, fset, p, true))
-			processErr(bind.GenObjc(os.Stdout, fset, p, false))
+			processErr(bind.GenObjc(os.Stdout, fset, p, allPkg, *prefix, true))
+			processErr(bind.GenObjc(os.Stdout, fset, p, allPkg, *prefix, false))
 		} else {
 			hname := fname[:len(fname)-2] + ".h"
 			w, closer := writer(hname, p)
-			processErr(bind.GenObjc(w, fset, p, true))
+			processErr(bind.GenObjc(w, fset, p, allPkg, *prefix, true))
 			closer()
 			w, closer = writer(fname, p)
-			processErr(bind.GenObjc(w, fset, p, false))
+			processErr(bind.GenObjc(w, fset, p, allPkg, *prefix, false))
 			closer()
 		}
 	default:
@@ -147,14 +200,32 @@ func defaultFileName(lang string, pkg *types.Package) string {
 		firstRune, size := utf8.DecodeRuneInString(pkg.Name())
 		className := string(unicode.ToUpper(firstRune)) + pkg.Name()[size:]
 		return filepath.Join(*outdir, className+".java")
+	case "swift":
+		firstRune, size := utf8.DecodeRuneInString(pkg.Name())
+		className := string(unicode.ToUpper(firstRune)) + pkg.Name()[size:]
+		return filepath.Join(*outdir, objcPrefix()+className+".swift")
+	case "kotlin":
+		firstRune, size := utf8.DecodeRuneInString(pkg.Name())
+		className := string(unicode.ToUpper(firstRune)) + pkg.Name()[size:]
+		return filepath.Join(*outdir, className+".kt")
 	case "go":
 		return filepath.Join(*outdir, "go_"+pkg.Name()+".go")
+	case "api":
+		return filepath.Join(*outdir, pkg.Name()+".api")
 	case "objc":
 		firstRune, size := utf8.DecodeRuneInString(pkg.Name())
 		className := string(unicode.ToUpper(firstRune)) + pkg.Name()[size:]
-		return filepath.Join(*outdir, "Go"+className+".m")
+		return filepath.Join(*outdir, objcPrefix()+className+".m")
 	}
 	errorf("unknown target language: %q", lang)
 	os.Exit(exitStatus)
 	return ""
 }
+
+// objcPrefix returns the prefix of the Objective-C names, set by -prefix.
+func objcPrefix() string {
+	if *prefix == "" {
+		return "Go"
+	}
+	return *prefix
+}
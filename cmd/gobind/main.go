@@ -13,8 +13,12 @@ import (
 )
 
 var (
-	lang   = flag.String("lang", "java", "target language for bindings, either java, go, or objc (experimental).")
-	outdir = flag.String("outdir", "", "result will be written to the directory instead of stdout.")
+	lang    = flag.String("lang", "java", "target language for bindings, either java, kotlin, go, objc (experimental), swift (experimental) or api.")
+	outdir  = flag.String("outdir", "", "result will be written to the directory instead of stdout.")
+	javaPkg = flag.String("javapkg", "", "prefix of the Java packages of the bound packages, go by default (java and kotlin).")
+	prefix  = flag.String("prefix", "", "prefix of the Objective-C names of the bound packages, Go by default (objc and swift).")
+	check   = flag.Bool("check", false, "report the declarations that cannot be bound, without generating bindings.")
+	skip    = flag.Bool("skip", false, "omit the declarations that cannot be bound, with a warning, instead of failing.")
 )
 
 var usage = `The Gobind tool generates Java language bindings for Go.
@@ -28,14 +32,16 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	var pkgs []*build.Package
 	for _, arg := range flag.Args() {
 		pkg, err := build.Import(arg, cwd, 0)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "%s: %v\n", arg, err)
 			os.Exit(1)
 		}
-		genPkg(pkg)
+		pkgs = append(pkgs, pkg)
 	}
+	genPkgs(pkgs)
 	os.Exit(exitStatus)
 }
 
@@ -46,3 +52,8 @@ func errorf(format string, args ...interface{}) {
 	fmt.Fprintln(os.Stderr)
 	exitStatus = 1
 }
+
+func warnf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "warning: "+format, args...)
+	fmt.Fprintln(os.Stderr)
+}
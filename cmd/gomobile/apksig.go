@@ -0,0 +1,537 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+// The APK Signature Schemes v2 and v3 sign the whole APK, instead of its
+// entries as the JAR signature (v1) does. Their signatures are stored in
+// the APK Signing Block, inserted between the entries and the ZIP central
+// directory:
+//
+//	size of the block, excluding this field (uint64)
+//	ID-value pairs: the length (uint64), ID (uint32) and value of each
+//	size of the block, repeated (uint64)
+//	magic "APK Sig Block 42"
+//
+// All the integers are little-endian. The v2 and v3 values are made of
+// length-prefixed sequences, with uint32 lengths:
+//
+//	signers: each a sequence of
+//	    signed data: sequences of
+//	        digests: the algorithm ID (uint32) and digest of each
+//	        certificates: the DER encoding of each
+//	        v3 only: minimum and maximum SDK versions (uint32)
+//	        additional attributes: the ID (uint32) and value of each
+//	    v3 only: minimum and maximum SDK versions (uint32)
+//	    signatures: the algorithm ID (uint32) and signature of the
+//	        signed data of each
+//	    public key: the DER encoding of its SubjectPublicKeyInfo
+//
+// The digest covers the entries, the central directory and the end of
+// central directory record, whose offset of the central directory is the
+// offset of the APK Signing Block. Each is split into 1MB chunks. The
+// digest of a chunk is the digest of 0xa5, the length of the chunk
+// (uint32) and the chunk, and the digest of the APK is the digest of 0x5a,
+// the number of chunks (uint32) and the digests of the chunks.
+//
+// See https://source.android.com/security/apksigning/v2.
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// apkSigning selects the signature schemes of an APK.
+type apkSigning struct {
+	v1       bool // JAR signature, for Android 6 and earlier
+	v1SHA256 bool // SHA-256 digests for v1, supported by Android 4.3 and later
+	v2       bool // APK Signature Scheme v2, Android 7 and later
+	v3       bool // APK Signature Scheme v3, Android 9 and later
+}
+
+// defaultSigning signs the APKs for all the Android versions.
+var defaultSigning = apkSigning{v1: true, v2: true}
+
+// parseSigning parses the comma-separated list of signature schemes s,
+// among v1, v1-sha256, v2 and v3.
+func parseSigning(s string) (apkSigning, error) {
+	var sign apkSigning
+	for _, scheme := range strings.Split(s, ",") {
+		switch strings.TrimSpace(scheme) {
+		case "v1":
+			sign.v1 = true
+		case "v1-sha256":
+			sign.v1, sign.v1SHA256 = true, true
+		case "v2":
+			sign.v2 = true
+		case "v3":
+			sign.v3 = true
+		default:
+			return apkSigning{}, fmt.Errorf("unknown signature scheme %q, want v1, v1-sha256, v2 or v3", scheme)
+		}
+	}
+	return sign, nil
+}
+
+// String returns the value of the X-Android-APK-Signed header of the v1
+// signature, naming the other schemes so they cannot be stripped.
+func (s apkSigning) String() string {
+	var ids []string
+	if s.v2 {
+		ids = append(ids, "2")
+	}
+	if s.v3 {
+		ids = append(ids, "3")
+	}
+	return strings.Join(ids, ", ")
+}
+
+const (
+	apkSigBlockMagic = "APK Sig Block 42"
+	apkSigV2ID       = 0x7109871a
+	apkSigV3ID       = 0xf05368c0
+
+	// rsaPKCS1SHA256 is RSASSA-PKCS1-v1_5 with SHA-256 digests.
+	rsaPKCS1SHA256 = 0x0103
+
+	// strippingProtectionAttr marks the v2 signed data of APKs also
+	// signed with v3.
+	strippingProtectionAttr = 0xbeeff00d
+
+	// The SDK versions signed by v3: Android 9 and later.
+	v3MinSDK = 28
+	v3MaxSDK = 0x7fffffff
+
+	digestChunkSize = 1 << 20
+)
+
+// chunkDigester computes the digests of the chunks of an APK.
+type chunkDigester struct {
+	hash    crypto.Hash // SHA-256 or SHA-512
+	chunk   []byte
+	digests [][]byte
+}
+
+func (d *chunkDigester) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		m := digestChunkSize - len(d.chunk)
+		if m > len(p) {
+			m = len(p)
+		}
+		d.chunk = append(d.chunk, p[:m]...)
+		p = p[m:]
+		if len(d.chunk) == digestChunkSize {
+			d.endSection()
+		}
+	}
+	return n, nil
+}
+
+// endSection digests the last chunk of a section of the APK, as chunks
+// do not span sections.
+func (d *chunkDigester) endSection() {
+	if len(d.chunk) == 0 {
+		return
+	}
+	h := d.hash.New()
+	h.Write([]byte{0xa5})
+	binary.Write(h, binary.LittleEndian, uint32(len(d.chunk)))
+	h.Write(d.chunk)
+	d.digests = append(d.digests, h.Sum(nil))
+	d.chunk = d.chunk[:0]
+}
+
+// sum returns the digest of the APK, after its last section.
+func (d *chunkDigester) sum() []byte {
+	d.endSection()
+	h := d.hash.New()
+	h.Write([]byte{0x5a})
+	binary.Write(h, binary.LittleEndian, uint32(len(d.digests)))
+	for _, digest := range d.digests {
+		h.Write(digest)
+	}
+	return h.Sum(nil)
+}
+
+// apkSigBlock returns the APK Signing Block of the APK digest, signed by
+// priv and carrying cert, with the schemes of sign.
+func apkSigBlock(sign apkSigning, priv *rsa.PrivateKey, cert *x509.Certificate, digest []byte) ([]byte, error) {
+	pub, err := x509.MarshalPKIXPublicKey(priv.Public())
+	if err != nil {
+		return nil, err
+	}
+	type pair struct {
+		id    uint32
+		value []byte
+	}
+	var pairs []pair
+	if sign.v2 {
+		var attrs []byte
+		if sign.v3 {
+			attrs = lenPrefixed(appendUint32(appendUint32(nil, strippingProtectionAttr), 3))
+		}
+		signed := bytes.Join([][]byte{
+			lenPrefixed(lenPrefixed(appendUint32(nil, rsaPKCS1SHA256), lenPrefixed(digest))),
+			lenPrefixed(lenPrefixed(cert.Raw)),
+			lenPrefixed(attrs),
+		}, nil)
+		signer, err := apkSigner(priv, signed, nil, pub)
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, pair{apkSigV2ID, lenPrefixed(lenPrefixed(signer))})
+	}
+	if sign.v3 {
+		sdk := appendUint32(appendUint32(nil, v3MinSDK), v3MaxSDK)
+		signed := bytes.Join([][]byte{
+			lenPrefixed(lenPrefixed(appendUint32(nil, rsaPKCS1SHA256), lenPrefixed(digest))),
+			lenPrefixed(lenPrefixed(cert.Raw)),
+			sdk,
+			lenPrefixed(nil),
+		}, nil)
+		signer, err := apkSigner(priv, signed, sdk, pub)
+		if err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, pair{apkSigV3ID, lenPrefixed(lenPrefixed(signer))})
+	}
+
+	var body []byte
+	for _, p := range pairs {
+		body = appendUint64(body, uint64(4+len(p.value)))
+		body = appendUint32(body, p.id)
+		body = append(body, p.value...)
+	}
+	size := uint64(len(body) + 8 + len(apkSigBlockMagic))
+	block := appendUint64(nil, size)
+	block = append(block, body...)
+	block = appendUint64(block, size)
+	return append(block, apkSigBlockMagic...), nil
+}
+
+// apkSigner returns a signer of the v2 or v3 schemes, signing signed
+// with priv. The v3 signers repeat their SDK versions sdk.
+func apkSigner(priv *rsa.PrivateKey, signed, sdk, pub []byte) ([]byte, error) {
+	hashed := sha256.Sum256(signed)
+	sig, err := rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, hashed[:])
+	if err != nil {
+		return nil, err
+	}
+	return bytes.Join([][]byte{
+		lenPrefixed(signed),
+		sdk,
+		lenPrefixed(lenPrefixed(appendUint32(nil, rsaPKCS1SHA256), lenPrefixed(sig))),
+		lenPrefixed(pub),
+	}, nil), nil
+}
+
+// lenPrefixed returns the concatenation of b, prefixed by its length.
+func lenPrefixed(b ...[]byte) []byte {
+	v := bytes.Join(b, nil)
+	return append(appendUint32(nil, uint32(len(v))), v...)
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+func appendUint64(b []byte, v uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	return append(b, buf[:]...)
+}
+
+// The end of central directory record of the ZIP archives written by
+// the Writer, which have no comment.
+const (
+	eocdLen       = 22
+	eocdCDOffset  = 16 // offset of the central directory, in the record
+	eocdSignature = 0x06054b50
+)
+
+// splitZIPTail splits the end of a ZIP archive, written from the offset
+// start, into the end of the last entry, the central directory and the
+// end of central directory record.
+func splitZIPTail(tail []byte, start int) (entries, cd, eocd []byte, err error) {
+	if len(tail) < eocdLen {
+		return nil, nil, nil, errors.New("no end of central directory")
+	}
+	eocd = tail[len(tail)-eocdLen:]
+	if binary.LittleEndian.Uint32(eocd) != eocdSignature {
+		return nil, nil, nil, errors.New("no end of central directory")
+	}
+	off := binary.LittleEndian.Uint32(eocd[eocdCDOffset:])
+	if off == 0xffffffff {
+		return nil, nil, nil, errors.New("ZIP64 archives are not supported")
+	}
+	n := int(off) - start
+	if n < 0 || n > len(tail)-eocdLen {
+		return nil, nil, nil, errors.New("bad offset of the central directory")
+	}
+	return tail[:n], tail[n : len(tail)-eocdLen], eocd, nil
+}
+
+// moveCD moves the central directory of the end of central directory
+// record eocd by n bytes, the length of the APK Signing Block.
+func moveCD(eocd []byte, n int) {
+	off := binary.LittleEndian.Uint32(eocd[eocdCDOffset:])
+	binary.LittleEndian.PutUint32(eocd[eocdCDOffset:], off+uint32(n))
+}
+
+// An apkSignature is a verified signature of the APK Signing Block.
+type apkSignature struct {
+	scheme int // 2 or 3
+	cert   *x509.Certificate
+}
+
+// apkSigAlgs are the supported algorithms of the v2 and v3 signatures.
+var apkSigAlgs = map[uint32]struct {
+	hash  crypto.Hash
+	key   string // RSA, RSA-PSS or ECDSA
+	order int    // of preference
+}{
+	0x0101: {crypto.SHA256, "RSA-PSS", 2},
+	0x0102: {crypto.SHA512, "RSA-PSS", 5},
+	0x0103: {crypto.SHA256, "RSA", 1},
+	0x0104: {crypto.SHA512, "RSA", 4},
+	0x0201: {crypto.SHA256, "ECDSA", 3},
+	0x0202: {crypto.SHA512, "ECDSA", 6},
+}
+
+// verifySigBlock verifies the v2 and v3 signatures of the APK Signing
+// Block of apk, and returns them. It returns none if apk has no block.
+func verifySigBlock(apk []byte) ([]apkSignature, error) {
+	eocdOff, err := findEOCD(apk)
+	if err != nil {
+		return nil, err
+	}
+	cdOff := int(binary.LittleEndian.Uint32(apk[eocdOff+eocdCDOffset:]))
+	if cdOff > eocdOff {
+		return nil, errors.New("bad offset of the central directory")
+	}
+	if cdOff < 32 || string(apk[cdOff-16:cdOff]) != apkSigBlockMagic {
+		return nil, nil
+	}
+	size := binary.LittleEndian.Uint64(apk[cdOff-24:])
+	if size < 24 || size > uint64(cdOff-8) {
+		return nil, fmt.Errorf("bad APK Signing Block size %d", size)
+	}
+	start := cdOff - int(size) - 8
+	if binary.LittleEndian.Uint64(apk[start:]) != size {
+		return nil, errors.New("APK Signing Block sizes differ")
+	}
+
+	// The digests of the APK, the end of central directory pointing at
+	// the block.
+	eocd := append([]byte(nil), apk[eocdOff:]...)
+	binary.LittleEndian.PutUint32(eocd[eocdCDOffset:], uint32(start))
+	digests := make(map[crypto.Hash][]byte)
+	digest := func(hash crypto.Hash) []byte {
+		if digests[hash] == nil {
+			d := &chunkDigester{hash: hash}
+			for _, section := range [][]byte{apk[:start], apk[cdOff:eocdOff], eocd} {
+				d.Write(section)
+				d.endSection()
+			}
+			digests[hash] = d.sum()
+		}
+		return digests[hash]
+	}
+
+	r := &binReader{b: apk[start+8 : cdOff-24]}
+	var sigs []apkSignature
+	stripped := false
+	for len(r.b) > 0 && r.err == nil {
+		n := binary.LittleEndian.Uint64(r.next(8))
+		if r.err != nil || n < 4 || n > uint64(len(r.b)) {
+			return nil, errors.New("bad APK Signing Block pair")
+		}
+		pair := &binReader{b: r.next(int(n))}
+		scheme := 0
+		switch pair.uint32() {
+		case apkSigV2ID:
+			scheme = 2
+		case apkSigV3ID:
+			scheme = 3
+		default:
+			continue // padding, or another scheme
+		}
+		signers := &binReader{b: lenValue(pair)}
+		for len(signers.b) > 0 {
+			cert, strip, err := verifyAPKSigner(lenValue(signers), scheme, digest)
+			if err == nil {
+				err = signers.err
+			}
+			if err != nil {
+				return nil, fmt.Errorf("v%d signature: %v", scheme, err)
+			}
+			sigs = append(sigs, apkSignature{scheme: scheme, cert: cert})
+			stripped = stripped || strip
+		}
+		if pair.err != nil || signers.err != nil {
+			return nil, fmt.Errorf("v%d signature: truncated", scheme)
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if stripped {
+		v3 := false
+		for _, sig := range sigs {
+			v3 = v3 || sig.scheme == 3
+		}
+		if !v3 {
+			return nil, errors.New("v2 signature names a stripped v3 signature")
+		}
+	}
+	return sigs, nil
+}
+
+// verifyAPKSigner verifies a signer of the v2 or v3 schemes, and returns
+// its certificate. It reports whether its signed data says the APK is also
+// signed with v3. The digest function returns the digests of the APK.
+func verifyAPKSigner(signer []byte, scheme int, digest func(crypto.Hash) []byte) (cert *x509.Certificate, v3 bool, err error) {
+	r := &binReader{b: signer}
+	signed := lenValue(r)
+	if scheme == 3 {
+		r.next(8) // SDK versions
+	}
+	sigs := lenValue(r)
+	pubDER := lenValue(r)
+	if r.err != nil {
+		return nil, false, errors.New("truncated signer")
+	}
+	pub, err := x509.ParsePKIXPublicKey(pubDER)
+	if err != nil {
+		return nil, false, fmt.Errorf("bad public key: %v", err)
+	}
+
+	// The signatures of the signed data, by the best algorithm.
+	sr := &binReader{b: sigs}
+	var algs []uint32
+	best := uint32(0)
+	for len(sr.b) > 0 && sr.err == nil {
+		s := &binReader{b: lenValue(sr)}
+		alg := s.uint32()
+		sig := lenValue(s)
+		if s.err != nil {
+			return nil, false, errors.New("truncated signature")
+		}
+		algs = append(algs, alg)
+		a, ok := apkSigAlgs[alg]
+		if !ok {
+			continue
+		}
+		if err := verifySignature(pub, a.key, a.hash, signed, sig); err != nil {
+			return nil, false, err
+		}
+		if best == 0 || a.order < apkSigAlgs[best].order {
+			best = alg
+		}
+	}
+	if sr.err != nil {
+		return nil, false, errors.New("truncated signatures")
+	}
+	if best == 0 {
+		return nil, false, errors.New("no supported signature")
+	}
+
+	sd := &binReader{b: signed}
+	digests := &binReader{b: lenValue(sd)}
+	certs := &binReader{b: lenValue(sd)}
+	if scheme == 3 {
+		sd.next(8) // SDK versions
+	}
+	attrs := &binReader{b: lenValue(sd)}
+	if sd.err != nil {
+		return nil, false, errors.New("truncated signed data")
+	}
+	var digestAlgs []uint32
+	for len(digests.b) > 0 && digests.err == nil {
+		d := &binReader{b: lenValue(digests)}
+		alg := d.uint32()
+		value := lenValue(d)
+		if d.err != nil {
+			return nil, false, errors.New("truncated digest")
+		}
+		digestAlgs = append(digestAlgs, alg)
+		if alg == best && !bytes.Equal(value, digest(apkSigAlgs[alg].hash)) {
+			return nil, false, errors.New("APK digest mismatch, the APK was modified")
+		}
+	}
+	if fmt.Sprint(digestAlgs) != fmt.Sprint(algs) {
+		return nil, false, errors.New("the algorithms of the signatures and digests differ")
+	}
+	if cert, err = x509.ParseCertificate(lenValue(certs)); certs.err != nil || err != nil {
+		return nil, false, fmt.Errorf("bad certificate: %v", err)
+	}
+	if !bytes.Equal(cert.RawSubjectPublicKeyInfo, pubDER) {
+		return nil, false, errors.New("the certificate is not of the public key")
+	}
+	for len(attrs.b) > 0 && attrs.err == nil {
+		a := &binReader{b: lenValue(attrs)}
+		if a.uint32() == strippingProtectionAttr && a.uint32() == 3 {
+			v3 = true
+		}
+	}
+	return cert, v3, nil
+}
+
+// verifySignature verifies the signature sig of msg by pub, a key of
+// the type key.
+func verifySignature(pub crypto.PublicKey, key string, hash crypto.Hash, msg, sig []byte) error {
+	h := hash.New()
+	h.Write(msg)
+	hashed := h.Sum(nil)
+	var err error
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		switch key {
+		case "RSA":
+			err = rsa.VerifyPKCS1v15(pub, hash, hashed, sig)
+		case "RSA-PSS":
+			err = rsa.VerifyPSS(pub, hash, hashed, sig, &rsa.PSSOptions{SaltLength: hash.Size()})
+		default:
+			err = fmt.Errorf("%s signature of an RSA key", key)
+		}
+	case *ecdsa.PublicKey:
+		if key != "ECDSA" || !verifyECDSA(pub, hashed, sig) {
+			err = errors.New("verification error")
+		}
+	default:
+		err = fmt.Errorf("unsupported %T key", pub)
+	}
+	if err != nil {
+		return fmt.Errorf("bad signature: %v", err)
+	}
+	return nil
+}
+
+// lenValue returns the next length-prefixed value of r.
+func lenValue(r *binReader) []byte {
+	return r.next(int(r.uint32()))
+}
+
+// findEOCD returns the offset of the end of central directory record of
+// the ZIP archive b, which may end with a comment.
+func findEOCD(b []byte) (int, error) {
+	for i := len(b) - eocdLen; i >= 0 && i >= len(b)-eocdLen-0xffff; i-- {
+		if binary.LittleEndian.Uint32(b[i:]) == eocdSignature && int(binary.LittleEndian.Uint16(b[i+20:])) == len(b)-i-eocdLen {
+			return i, nil
+		}
+	}
+	return 0, errors.New("no end of central directory")
+}
@@ -5,9 +5,13 @@
 package main
 
 import (
+	"bytes"
+	"encoding/binary"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
@@ -66,7 +70,10 @@ import (
 //		(ResXMLTree_node; ResXMLTree_endElementExt)
 //	...
 //	Chunk: Namespace End
-func binaryXML(r io.Reader) ([]byte, error) {
+//
+// The android attributes refer to the resources of res, such as
+// @string/app_name, by their resource IDs. res may be nil if there are none.
+func binaryXML(r io.Reader, res *resTable) ([]byte, error) {
 	lr := &lineReader{r: r}
 	d := xml.NewDecoder(lr)
 
@@ -102,7 +109,7 @@ func binaryXML(r io.Reader) ([]byte, error) {
 					}}, namespaceEnds[depth]...)
 					continue
 				}
-				ba, err := pool.getAttr(a)
+				ba, err := pool.getAttr(a, res)
 				if err != nil {
 					return nil, fmt.Errorf("%d: %s: %v", line, a.Name.Local, err)
 				}
@@ -225,28 +232,48 @@ func appendU32(b []byte, v uint32) []byte {
 }
 
 func appendHeader(b []byte, typ headerType, size int) []byte {
+	return appendChunkHeader(b, typ, 8, size)
+}
+
+func appendChunkHeader(b []byte, typ headerType, headerSize, size int) []byte {
 	b = appendU16(b, uint16(typ))
-	b = appendU16(b, 8)
-	b = appendU16(b, uint16(size))
-	b = appendU16(b, 0)
+	b = appendU16(b, uint16(headerSize))
+	b = appendU32(b, uint32(size))
 	return b
 }
 
+// androidNS is the namespace of the android attributes.
+const androidNS = "http://schemas.android.com/apk/res/android"
+
 // Attributes of the form android:key are mapped to resource IDs, which are
 // embedded into the Binary XML format.
 //
 // http://developer.android.com/reference/android/R.attr.html
 var resourceCodes = map[string]uint32{
-	"versionCode":      0x0101021b,
-	"versionName":      0x0101021c,
-	"minSdkVersion":    0x0101020c,
-	"windowFullscreen": 0x0101020d,
-	"label":            0x01010001,
-	"hasCode":          0x0101000c,
-	"debuggable":       0x0101000f,
-	"name":             0x01010003,
-	"configChanges":    0x0101001f,
-	"value":            0x01010024,
+	"versionCode":         0x0101021b,
+	"versionName":         0x0101021c,
+	"minSdkVersion":       0x0101020c,
+	"targetSdkVersion":    0x01010270,
+	"maxSdkVersion":       0x01010271,
+	"windowFullscreen":    0x0101020d,
+	"theme":               0x01010000,
+	"label":               0x01010001,
+	"icon":                0x01010002,
+	"roundIcon":           0x0101052c,
+	"drawable":            0x01010199,
+	"hasCode":             0x0101000c,
+	"debuggable":          0x0101000f,
+	"exported":            0x01010010,
+	"allowBackup":         0x01010280,
+	"hardwareAccelerated": 0x010102d3,
+	"name":                0x01010003,
+	"launchMode":          0x0101001d,
+	"screenOrientation":   0x0101001e,
+	"configChanges":       0x0101001f,
+	"installLocation":     0x010102b7,
+	"glEsVersion":         0x01010281,
+	"required":            0x0101028e,
+	"value":               0x01010024,
 }
 
 // http://developer.android.com/reference/android/R.attr.html#configChanges
@@ -267,6 +294,41 @@ var configChanges = map[string]uint32{
 	"fontScale":          0x40000000,
 }
 
+// The android attributes with enumerated values, encoded as integers.
+//
+// http://developer.android.com/reference/android/R.attr.html#screenOrientation
+var attrEnums = map[string]map[string]int{
+	"installLocation": {
+		"auto":           0,
+		"internalOnly":   1,
+		"preferExternal": 2,
+	},
+	"launchMode": {
+		"standard":       0,
+		"singleTop":      1,
+		"singleTask":     2,
+		"singleInstance": 3,
+	},
+	"screenOrientation": {
+		"unspecified":      -1,
+		"landscape":        0,
+		"portrait":         1,
+		"user":             2,
+		"behind":           3,
+		"sensor":           4,
+		"nosensor":         5,
+		"sensorLandscape":  6,
+		"sensorPortrait":   7,
+		"reverseLandscape": 8,
+		"reversePortrait":  9,
+		"fullSensor":       10,
+		"userLandscape":    11,
+		"userPortrait":     12,
+		"fullUser":         13,
+		"locked":           14,
+	},
+}
+
 type lineReader struct {
 	off   int64
 	lines []int64
@@ -370,25 +432,48 @@ func (p *binStringPool) getNS(ns string) *bstring {
 	return p.get(ns)
 }
 
-func (p *binStringPool) getAttr(attr xml.Attr) (*binAttr, error) {
+func (p *binStringPool) getAttr(attr xml.Attr, res *resTable) (*binAttr, error) {
 	a := &binAttr{
 		ns:   p.getNS(attr.Name.Space),
 		name: p.get(attr.Name.Local),
 	}
-	if attr.Name.Space != "http://schemas.android.com/apk/res/android" {
+	if attr.Name.Space != androidNS {
 		a.data = p.get(attr.Value)
 		return a, nil
 	}
 
+	// References to the resources of the app, but not to those of
+	// the android framework, such as @android:style/Theme.
+	if typ, name, ok := parseResRef(attr.Value); ok {
+		id, ok := res.id(typ, name)
+		if !ok {
+			return nil, fmt.Errorf("unknown resource %s", attr.Value)
+		}
+		a.data = resRef(id)
+		return a, nil
+	}
+
 	// Some android attributes have interesting values.
 	switch attr.Name.Local {
-	case "versionCode", "minSdkVersion":
+	case "versionCode", "minSdkVersion", "targetSdkVersion", "maxSdkVersion":
 		v, err := strconv.Atoi(attr.Value)
 		if err != nil {
 			return nil, err
 		}
 		a.data = int(v)
-	case "hasCode", "debuggable":
+	case "installLocation", "launchMode", "screenOrientation":
+		v, ok := attrEnums[attr.Name.Local][attr.Value]
+		if !ok {
+			return nil, fmt.Errorf("unknown value %q", attr.Value)
+		}
+		a.data = v
+	case "glEsVersion":
+		v, err := strconv.ParseUint(attr.Value, 0, 32)
+		if err != nil {
+			return nil, err
+		}
+		a.data = uint32(v)
+	case "hasCode", "debuggable", "exported", "allowBackup", "hardwareAccelerated":
 		v, err := strconv.ParseBool(attr.Value)
 		if err != nil {
 			return nil, err
@@ -406,6 +491,20 @@ func (p *binStringPool) getAttr(attr xml.Attr) (*binAttr, error) {
 	return a, nil
 }
 
+// parseResRef returns the type and name of the reference ref to a
+// resource of the app, of the form @type/name or @+type/name.
+func parseResRef(ref string) (typ, name string, ok bool) {
+	if !strings.HasPrefix(ref, "@") || strings.Contains(ref, ":") {
+		return "", "", false
+	}
+	ref = strings.TrimPrefix(ref[1:], "+")
+	i := strings.Index(ref, "/")
+	if i <= 0 || i == len(ref)-1 {
+		return "", "", false
+	}
+	return ref[:i], ref[i+1:], true
+}
+
 const stringPoolPreamble = 0 +
 	8 + // chunk header
 	4 + // string count
@@ -468,8 +567,7 @@ func (p *binStringPool) append(b []byte) []byte {
 	stringsStart := uint32(stringPoolPreamble + 4*len(p.s))
 	b = appendU16(b, uint16(headerStringPool))
 	b = appendU16(b, 0x1c) // chunk header size
-	b = appendU16(b, uint16(p.size()))
-	b = appendU16(b, 0)
+	b = appendU32(b, uint32(p.size()))
 	b = appendU32(b, uint32(len(p.s)))
 	b = appendU32(b, 0) // style count
 	b = appendU32(b, 0) // flags
@@ -537,9 +635,12 @@ func (e *binStartElement) append(b []byte) []byte {
 type binAttr struct {
 	ns   *bstring
 	name *bstring
-	data interface{} // either int (INT_DEC) or *bstring (STRING)
+	data interface{} // int (INT_DEC), bool, uint32 (INT_HEX), resRef or *bstring (STRING)
 }
 
+// resRef is the resource ID of a reference to a resource.
+type resRef uint32
+
 func (a *binAttr) append(b []byte) []byte {
 	if a.ns != nil {
 		b = appendU32(b, a.ns.ind)
@@ -570,6 +671,12 @@ func (a *binAttr) append(b []byte) []byte {
 		b = append(b, 0)             // unused padding
 		b = append(b, 0x11)          // INT_HEX
 		b = appendU32(b, uint32(v))
+	case resRef:
+		b = appendU32(b, 0xffffffff) // raw value
+		b = appendU16(b, 8)          // size
+		b = append(b, 0)             // unused padding
+		b = append(b, 0x01)          // REFERENCE
+		b = appendU32(b, uint32(v))
 	case *bstring:
 		b = appendU32(b, v.ind) // raw value
 		b = appendU16(b, 8)     // size
@@ -692,3 +799,408 @@ func (e *binCharData) append(b []byte) []byte {
 	b = appendU16(b, 0)
 	return b
 }
+
+// xmlElement is an element of a decoded binary XML file. Its names have
+// the URL of their namespace as Space.
+type xmlElement struct {
+	name     xml.Name
+	ns       []xml.Attr    // namespaces declared by the element, prefix as Local
+	attr     []xml.Attr    // attributes, with their values as text
+	children []interface{} // *xmlElement or string
+}
+
+// attrValue returns the value of the attribute space:local of e.
+func (e *xmlElement) attrValue(space, local string) (string, bool) {
+	for _, a := range e.attr {
+		if a.Name.Space == space && a.Name.Local == local {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// decodeBinaryXML decodes the binary XML b, as written by binaryXML or
+// aapt, and returns its root element. The references to the resources of
+// res are decoded by name; res may be nil.
+func decodeBinaryXML(b []byte, res *resTable) (*xmlElement, error) {
+	r := &binReader{b: b}
+	typ, headerSize, size := headerType(r.uint16()), int(r.uint16()), int(r.uint32())
+	if r.err != nil || typ != headerXML || headerSize < 8 || size < headerSize || size > len(b) {
+		return nil, errors.New("not a binary XML file")
+	}
+
+	var (
+		pool   []string
+		resIDs []uint32
+		root   *xmlElement
+		stack  []*xmlElement
+		ns     []xml.Attr // declared for the next element
+	)
+	str := func(i uint32) (string, error) {
+		if i == 0xffffffff {
+			return "", nil
+		}
+		if int(i) >= len(pool) {
+			return "", fmt.Errorf("string %d out of range", i)
+		}
+		return pool[i], nil
+	}
+	name := func(nsIndex, nameIndex uint32) (xml.Name, error) {
+		space, err := str(nsIndex)
+		if err != nil {
+			return xml.Name{}, err
+		}
+		local, err := str(nameIndex)
+		if err != nil {
+			return xml.Name{}, err
+		}
+		if local == "" && int(nameIndex) < len(resIDs) {
+			// aapt2 may drop the names of the android attributes.
+			local = resourceName(resIDs[nameIndex])
+		}
+		return xml.Name{Space: space, Local: local}, nil
+	}
+
+	rest := b[headerSize:size]
+	for len(rest) > 0 {
+		c := &binReader{b: rest}
+		typ, headerSize, size := headerType(c.uint16()), int(c.uint16()), int(c.uint32())
+		if c.err != nil || headerSize < 8 || size < headerSize || size > len(rest) {
+			return nil, errors.New("truncated binary XML file")
+		}
+		chunk := rest[:size]
+		rest = rest[size:]
+		c.b = chunk[headerSize:] // after the line number and comment of nodes
+
+		var err error
+		switch typ {
+		case headerStringPool:
+			pool, err = decodeStringPool(chunk, headerSize)
+		case headerResourceMap:
+			for len(c.b) >= 4 {
+				resIDs = append(resIDs, c.uint32())
+			}
+		case headerStartNamespace:
+			var a xml.Attr
+			if a.Name.Local, err = str(c.uint32()); err != nil {
+				break
+			}
+			a.Value, err = str(c.uint32())
+			ns = append(ns, a)
+		case headerStartElement:
+			ext := c.b
+			e := &xmlElement{ns: ns}
+			ns = nil
+			if e.name, err = name(c.uint32(), c.uint32()); err != nil {
+				break
+			}
+			attrStart, attrSize, attrCount := int(c.uint16()), int(c.uint16()), int(c.uint16())
+			attrs := &binReader{b: ext}
+			attrs.next(attrStart)
+			for i := 0; i < attrCount && err == nil; i++ {
+				a := &binReader{b: attrs.next(attrSize)}
+				var attr xml.Attr
+				if attr.Name, err = name(a.uint32(), a.uint32()); err != nil {
+					break
+				}
+				raw := a.uint32()
+				a.next(3) // size and padding
+				dataType := a.next(1)
+				data := a.uint32()
+				if err = a.err; err != nil {
+					break
+				}
+				if raw != 0xffffffff {
+					attr.Value, err = str(raw)
+				} else {
+					attr.Value, err = formatValue(attr.Name, dataType[0], data, str, res)
+				}
+				e.attr = append(e.attr, attr)
+			}
+			if err == nil {
+				err = attrs.err
+			}
+			if err != nil {
+				break
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, e)
+			} else if root == nil {
+				root = e
+			} else {
+				err = errors.New("several root elements")
+				break
+			}
+			stack = append(stack, e)
+		case headerEndElement:
+			if len(stack) == 0 {
+				err = errors.New("unbalanced end element")
+				break
+			}
+			stack = stack[:len(stack)-1]
+		case headerCharData:
+			var text string
+			if text, err = str(c.uint32()); err != nil || len(stack) == 0 {
+				break
+			}
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, text)
+		}
+		if err == nil {
+			err = c.err
+		}
+		if err != nil {
+			return nil, fmt.Errorf("binary XML chunk of type %#x: %v", typ, err)
+		}
+	}
+	if root == nil || len(stack) > 0 {
+		return nil, errors.New("truncated binary XML file")
+	}
+	return root, nil
+}
+
+// decodeStringPool returns the strings of the string pool chunk, of
+// header size headerSize. They are encoded in UTF-16 or UTF-8.
+func decodeStringPool(chunk []byte, headerSize int) ([]string, error) {
+	r := &binReader{b: chunk[8:]}
+	count, _, flags, stringsStart := int(r.uint32()), r.uint32(), r.uint32(), int(r.uint32())
+	if r.err != nil || stringsStart > len(chunk) || headerSize+4*count > len(chunk) {
+		return nil, errors.New("bad string pool")
+	}
+	const utf8Flag = 1 << 8
+	offsets := &binReader{b: chunk[headerSize:]}
+	data := chunk[stringsStart:]
+	pool := make([]string, count)
+	for i := range pool {
+		off := int(offsets.uint32())
+		if off >= len(data) {
+			return nil, fmt.Errorf("string %d out of range", i)
+		}
+		s := &binReader{b: data[off:]}
+		if flags&utf8Flag != 0 {
+			s.utf8Len() // in UTF-16 units
+			pool[i] = string(s.next(s.utf8Len()))
+		} else {
+			n := s.utf16Len()
+			if 2*n > len(s.b) {
+				return nil, fmt.Errorf("string %d: truncated data", i)
+			}
+			u := make([]uint16, n)
+			for j := range u {
+				u[j] = s.uint16()
+			}
+			pool[i] = string(utf16.Decode(u))
+		}
+		if s.err != nil {
+			return nil, fmt.Errorf("string %d: %v", i, s.err)
+		}
+	}
+	return pool, nil
+}
+
+// formatValue returns the text of the typed value data of the attribute
+// name, of type dataType, such as 0x10 for decimal integers. References
+// to the resources of res are formatted by name.
+func formatValue(name xml.Name, dataType byte, data uint32, str func(uint32) (string, error), res *resTable) (string, error) {
+	switch dataType {
+	case 0x00: // NULL
+		return "", nil
+	case 0x01: // REFERENCE
+		if n, ok := res.name(data); ok {
+			return "@" + n, nil
+		}
+		return fmt.Sprintf("@0x%08x", data), nil
+	case 0x02: // ATTRIBUTE
+		return fmt.Sprintf("?0x%08x", data), nil
+	case 0x03: // STRING
+		return str(data)
+	case 0x04: // FLOAT
+		return strconv.FormatFloat(float64(math.Float32frombits(data)), 'g', -1, 32), nil
+	case 0x05: // DIMENSION
+		units := []string{"px", "dp", "sp", "pt", "in", "mm"}
+		unit := int(data & 0xf)
+		if unit >= len(units) {
+			return "", fmt.Errorf("unknown dimension unit %d", unit)
+		}
+		return strconv.FormatFloat(complexValue(data), 'g', -1, 32) + units[unit], nil
+	case 0x06: // FRACTION
+		units := []string{"%", "%p"}
+		unit := int(data & 0xf)
+		if unit >= len(units) {
+			return "", fmt.Errorf("unknown fraction unit %d", unit)
+		}
+		return strconv.FormatFloat(complexValue(data)*100, 'g', -1, 32) + units[unit], nil
+	case 0x10: // INT_DEC
+		if name.Space == androidNS {
+			for v, i := range attrEnums[name.Local] {
+				if int32(data) == int32(i) {
+					return v, nil
+				}
+			}
+		}
+		return strconv.Itoa(int(int32(data))), nil
+	case 0x11: // INT_HEX
+		if name.Space == androidNS && name.Local == "configChanges" {
+			var flags []string
+			for flag, v := range configChanges {
+				if data&v == v {
+					flags = append(flags, flag)
+					data &^= v
+				}
+			}
+			if data == 0 {
+				sort.Strings(flags)
+				return strings.Join(flags, "|"), nil
+			}
+		}
+		return fmt.Sprintf("0x%x", data), nil
+	case 0x12: // INT_BOOLEAN
+		return strconv.FormatBool(data != 0), nil
+	case 0x1c, 0x1e: // INT_COLOR_ARGB8, INT_COLOR_ARGB4
+		return fmt.Sprintf("#%08x", data), nil
+	case 0x1d, 0x1f: // INT_COLOR_RGB8, INT_COLOR_RGB4
+		return fmt.Sprintf("#%06x", data&0xffffff), nil
+	}
+	return "", fmt.Errorf("unknown value type %#x", dataType)
+}
+
+// complexValue returns the value of a dimension or fraction: a signed
+// 24-bit mantissa and the position of its binary point.
+func complexValue(data uint32) float64 {
+	mantissa := float64(int32(data) >> 8)
+	shift := []uint{0, 7, 15, 23}[data>>4&3]
+	return mantissa / float64(uint32(1)<<shift)
+}
+
+// resourceName returns the name of the android attribute of resource ID
+// id, or its ID in hexadecimal if unknown.
+func resourceName(id uint32) string {
+	for name, v := range resourceCodes {
+		if v == id {
+			return name
+		}
+	}
+	return fmt.Sprintf("0x%08x", id)
+}
+
+// format returns the text of the XML element e, indented by tabs.
+func (e *xmlElement) format() []byte {
+	buf := new(bytes.Buffer)
+	buf.WriteString(xml.Header)
+	e.write(buf, 0, make(map[string]string))
+	return buf.Bytes()
+}
+
+// write writes e at the given depth. The prefixes map the URLs of the
+// namespaces declared by the parents of e to their prefixes.
+func (e *xmlElement) write(buf *bytes.Buffer, depth int, prefixes map[string]string) {
+	if len(e.ns) > 0 {
+		p := make(map[string]string)
+		for url, prefix := range prefixes {
+			p[url] = prefix
+		}
+		for _, ns := range e.ns {
+			p[ns.Value] = ns.Name.Local
+		}
+		prefixes = p
+	}
+	qname := func(n xml.Name) string {
+		if prefix, ok := prefixes[n.Space]; ok {
+			return prefix + ":" + n.Local
+		}
+		return n.Local
+	}
+
+	indent := strings.Repeat("\t", depth)
+	fmt.Fprintf(buf, "%s<%s", indent, qname(e.name))
+	for _, ns := range e.ns {
+		fmt.Fprintf(buf, " xmlns:%s=\"", ns.Name.Local)
+		xml.EscapeText(buf, []byte(ns.Value))
+		buf.WriteString(`"`)
+	}
+	for _, a := range e.attr {
+		fmt.Fprintf(buf, " %s=\"", qname(a.Name))
+		xml.EscapeText(buf, []byte(a.Value))
+		buf.WriteString(`"`)
+	}
+	if len(e.children) == 0 {
+		buf.WriteString("/>\n")
+		return
+	}
+	buf.WriteString(">\n")
+	for _, c := range e.children {
+		switch c := c.(type) {
+		case *xmlElement:
+			c.write(buf, depth+1, prefixes)
+		case string:
+			buf.WriteString(indent + "\t")
+			xml.EscapeText(buf, []byte(strings.TrimSpace(c)))
+			buf.WriteString("\n")
+		}
+	}
+	fmt.Fprintf(buf, "%s</%s>\n", indent, qname(e.name))
+}
+
+// binReader reads the little-endian values of binary XML files. After an
+// error, it returns zero values and keeps the error in err.
+type binReader struct {
+	b   []byte
+	err error
+}
+
+func (r *binReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.b) {
+		r.err = errors.New("truncated data")
+		return nil
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b
+}
+
+func (r *binReader) uint16() uint16 {
+	b := r.next(2)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint16(b)
+}
+
+func (r *binReader) uint32() uint32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(b)
+}
+
+// utf16Len returns the length of a UTF-16 string of a string pool, one
+// or two uint16 with the high bit set in the first.
+func (r *binReader) utf16Len() int {
+	n := int(r.uint16())
+	if n&0x8000 != 0 {
+		n = (n&0x7fff)<<16 | int(r.uint16())
+	}
+	return n
+}
+
+// utf8Len returns a length of a UTF-8 string of a string pool, one or
+// two bytes with the high bit set in the first.
+func (r *binReader) utf8Len() int {
+	b := r.next(1)
+	if b == nil {
+		return 0
+	}
+	n := int(b[0])
+	if n&0x80 != 0 {
+		if b = r.next(1); b == nil {
+			return 0
+		}
+		n = (n&0x7f)<<8 | int(b[0])
+	}
+	return n
+}
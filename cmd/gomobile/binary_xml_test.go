@@ -9,6 +9,7 @@ import (
 	"flag"
 	"io/ioutil"
 	"log"
+	"strings"
 	"testing"
 )
 
@@ -23,7 +24,7 @@ func TestBinaryXML(t *testing.T) {
 	sortPool, sortAttr = sortToMatchTest, sortAttrToMatchTest
 	defer func() { sortPool, sortAttr = origSortPool, origSortAttr }()
 
-	got, err := binaryXML(bytes.NewBufferString(input))
+	got, err := binaryXML(bytes.NewBufferString(input), nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -53,6 +54,64 @@ func TestBinaryXML(t *testing.T) {
 	}
 }
 
+func TestDecodeBinaryXML(t *testing.T) {
+	enc, err := binaryXML(bytes.NewBufferString(input), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// aapt, which wrote output, sorts the attributes by resource ID.
+	aaptWant := strings.NewReplacer(
+		`package="com.zentus.balloon" android:versionCode="1" android:versionName="1.0"`,
+		`android:versionCode="1" android:versionName="1.0" package="com.zentus.balloon"`,
+		`android:name="android.app.NativeActivity" android:label="Balloon"`,
+		`android:label="Balloon" android:name="android.app.NativeActivity"`,
+	).Replace(decodedInput)
+	tests := []struct {
+		b    []byte
+		want string
+	}{
+		{output, aaptWant},
+		{enc, decodedInput},
+	}
+	for _, tc := range tests {
+		root, err := decodeBinaryXML(tc.b, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(root.format()); got != tc.want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, tc.want)
+		}
+		if v, _ := root.attrValue(androidNS, "versionName"); v != "1.0" {
+			t.Errorf("versionName %q, want 1.0", v)
+		}
+	}
+
+	for i := 8; i < len(output); i += 7 {
+		if _, err := decodeBinaryXML(output[:i], nil); err == nil {
+			t.Errorf("no error decoding %d bytes of %d", i, len(output))
+		}
+	}
+}
+
+func TestDecodeStringPoolTruncated(t *testing.T) {
+	// A UTF-16 string pool of one string, whose length of 0x7fffffff
+	// units exceeds the chunk.
+	chunk := []byte{
+		0x01, 0x00, 0x1c, 0x00, // type, header size
+		0x24, 0x00, 0x00, 0x00, // chunk size
+		0x01, 0x00, 0x00, 0x00, // string count
+		0x00, 0x00, 0x00, 0x00, // style count
+		0x00, 0x00, 0x00, 0x00, // flags
+		0x20, 0x00, 0x00, 0x00, // strings start
+		0x00, 0x00, 0x00, 0x00, // styles start
+		0x00, 0x00, 0x00, 0x00, // offset of the string
+		0xff, 0xff, 0xff, 0xff, // length of the string
+	}
+	if _, err := decodeStringPool(chunk, 0x1c); err == nil {
+		t.Error("no error decoding a truncated string pool")
+	}
+}
+
 // The output of the Android encoder seems to be arbitrary. So for testing,
 // we sort the string pool order to match the output we have seen.
 func sortToMatchTest(p *binStringPool) {
@@ -754,3 +813,20 @@ license that can be found in the LICENSE file.
 	</activity>
 	</application>
 </manifest>`
+
+// decodedInput is input, decoded from its binary XML.
+const decodedInput = `<?xml version="1.0" encoding="UTF-8"?>
+<manifest xmlns:android="http://schemas.android.com/apk/res/android" package="com.zentus.balloon" android:versionCode="1" android:versionName="1.0">
+	<uses-sdk android:minSdkVersion="9"/>
+	<application android:label="Balloon世界" android:hasCode="false" android:debuggable="true">
+		<activity android:name="android.app.NativeActivity" android:label="Balloon" android:configChanges="keyboardHidden|orientation">
+			<meta-data android:name="android.app.lib_name" android:value="balloon"/>
+			<intent-filter>
+				here is some text
+				<action android:name="android.intent.action.MAIN"/>
+				<category android:name="android.intent.category.LAUNCHER"/>
+			</intent-filter>
+		</activity>
+	</application>
+</manifest>
+`
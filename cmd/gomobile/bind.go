@@ -5,6 +5,7 @@
 package main
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"go/ast"
@@ -28,21 +29,30 @@ import (
 var cmdBind = &command{
 	run:   runBind,
 	Name:  "bind",
-	Usage: "[-target android|ios] [-o output] [build flags] [package]",
+	Usage: "[-target android|ios|android/arch,...] [-javapkg package] [-prefix name] [-apidiff file] [-skip] [-o output] [build flags] [package...]",
 	Short: "build a shared library for android APK and iOS app",
 	Long: `
-Bind generates language bindings for the package named by the import
-path, and compiles a library for the named target system.
+Bind generates language bindings for the packages named by the import
+paths, and compiles a library for the named target system.
+
+Several packages can be bound into a single library. The bindings of
+each package may refer to the types of the others. The packages must
+have distinct names.
 
 The -target flag takes a target system name, either android (the
-default) or ios.
+default) or ios. For android, it may instead list the architectures
+to build, separated by commas: android/arm, android/arm64, android/386
+and android/amd64. The name android alone builds android/arm. The
+toolchains of the other architectures are installed by 'gomobile init
+-arch'.
 
 For -target android, the bind command produces an AAR (Android ARchive)
 file that archives the precompiled Java API stub classes, the compiled
-shared libraries, and all asset files in the /assets subdirectory under
-the package directory. The output is named '<package_name>.aar' by
-default. This AAR file is commonly used for binary distribution of an
-Android library project and most Android IDEs support AAR import. For
+shared libraries of each architecture, and all asset files in the
+/assets subdirectory under the package directories. The output is
+named '<package_name>.aar' by default, after the first package. This
+AAR file is commonly used for binary distribution of an Android
+library project and most Android IDEs support AAR import. For
 example, in Android Studio (1.2+), an AAR file can be imported using
 the module import wizard (File > New > New Module > Import .JAR or
 .AAR package), and setting it as a new dependency
@@ -54,6 +64,25 @@ to the path to Android SDK.
 For -target ios, gomobile must be run on an OS X machine with Xcode
 installed. Support is not complete.
 
+The -javapkg and -prefix flags set the names of the generated
+declarations, so that several bound libraries can be used by the
+same app. For -target android, -javapkg sets the prefix of the Java
+packages of the bound packages, go by default: with
+-javapkg=com.example.sdk, the class of package api is
+com.example.sdk.api.Api. For -target ios, -prefix sets the prefix of
+the Objective-C names, Go by default: with -prefix=EXS, a function New
+of package api is EXSApiNew, declared in EXSApi.h.
+
+The -apidiff flag names a description of the API of a previous
+version of the bound packages, written by 'gobind -lang=api'. The bind
+command prints the differences with the API of the packages, and
+fails without building the library if they break the API: removed
+declarations, changed types or methods added to interfaces.
+
+The exported declarations that cannot be bound are reported before
+building. The -skip flag omits them with a warning instead, as
+'gobind -skip' does.
+
 The -v flag provides verbose output, including the list of packages built.
 
 The build flags -a, -i, -n, -x, -gcflags, -ldflags, and -tags are shared
@@ -62,83 +91,166 @@ with the build command. For documentation, see 'go help build'.
 }
 
 func runBind(cmd *command) error {
+	targetOS, targetArchs, err := parseBuildTarget(buildTarget)
+	if err != nil {
+		return err
+	}
+
 	cleanup, err := buildEnvInit()
 	if err != nil {
 		return err
 	}
 	defer cleanup()
+	if targetOS == "android" {
+		if err := checkNDKToolchains(targetArchs); err != nil {
+			return err
+		}
+	}
 
 	args := cmd.flag.Args()
 
-	var pkg *build.Package
-	switch len(args) {
-	case 0:
-		pkg, err = ctx.ImportDir(cwd, build.ImportComment)
-	case 1:
-		pkg, err = ctx.Import(args[0], cwd, build.ImportComment)
-	default:
-		cmd.usage()
-		os.Exit(1)
+	var pkgs []*build.Package
+	if len(args) == 0 {
+		pkg, err := ctx.ImportDir(cwd, build.ImportComment)
+		if err != nil {
+			return err
+		}
+		pkgs = append(pkgs, pkg)
 	}
-	if err != nil {
-		return err
+	for _, arg := range args {
+		pkg, err := ctx.Import(arg, cwd, build.ImportComment)
+		if err != nil {
+			return err
+		}
+		pkgs = append(pkgs, pkg)
 	}
 
-	switch buildTarget {
-	case "android":
-		return goAndroidBind(pkg)
-	case "ios":
-		return goIOSBind(pkg)
-	default:
-		return fmt.Errorf(`unknown -target, %q.`, buildTarget)
+	if bindJavaPkg != "" && targetOS != "android" {
+		return fmt.Errorf("-javapkg is supported only for -target android")
+	}
+	if bindPrefix != "" && targetOS != "ios" {
+		return fmt.Errorf("-prefix is supported only for -target ios")
 	}
+
+	if targetOS == "android" {
+		return goAndroidBind(pkgs, targetArchs)
+	}
+	return goIOSBind(pkgs)
+}
+
+// Bind flags.
+var (
+	bindJavaPkg string // -javapkg
+	bindPrefix  string // -prefix
+	bindAPIDiff string // -apidiff
+	bindSkip    bool   // -skip
+)
+
+func init() {
+	cmdBind.flag.StringVar(&bindJavaPkg, "javapkg", "", "")
+	cmdBind.flag.StringVar(&bindPrefix, "prefix", "", "")
+	cmdBind.flag.StringVar(&bindAPIDiff, "apidiff", "", "")
+	cmdBind.flag.BoolVar(&bindSkip, "skip", false, "")
+}
+
+// javaPkgDir returns the directory of the Java sources of the bound
+// package pkg, relative to the source root.
+func javaPkgDir(pkg *types.Package) string {
+	javaPkg := bindJavaPkg
+	if javaPkg == "" {
+		javaPkg = "go"
+	}
+	return filepath.Join(append(strings.Split(javaPkg, "."), pkg.Name())...)
+}
+
+// objcName returns the name prefix of the Objective-C declarations of
+// the bound package pkg, also naming its header and source files.
+func objcName(pkg *types.Package) string {
+	prefix := bindPrefix
+	if prefix == "" {
+		prefix = "Go"
+	}
+	return prefix + strings.Title(pkg.Name())
 }
 
 type binder struct {
 	files []*ast.File
 	fset  *token.FileSet
-	pkg   *types.Package
+	pkgs  []*types.Package // bound together
+}
+
+// checkAPI prints the changes from the API description in the file
+// name to the API of the bound packages, and returns an error if some
+// of them break it.
+func (b *binder) checkAPI(name string) error {
+	old, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer old.Close()
+
+	cur := new(bytes.Buffer)
+	for _, pkg := range b.pkgs {
+		if err := bind.GenAPI(cur, pkg); err != nil {
+			return err
+		}
+	}
+	changes, err := bind.DiffAPI(old, cur)
+	if err != nil {
+		return fmt.Errorf("%s: %v", name, err)
+	}
+	breaking := 0
+	for _, c := range changes {
+		fmt.Fprintln(os.Stderr, c)
+		if c.Breaking {
+			breaking++
+		}
+	}
+	if breaking > 0 {
+		return fmt.Errorf("%d breaking API changes since %s", breaking, name)
+	}
+	return nil
 }
 
-func (b *binder) GenObjc(outdir string) error {
-	name := strings.Title(b.pkg.Name())
-	mfile := filepath.Join(outdir, "Go"+name+".m")
-	hfile := filepath.Join(outdir, "Go"+name+".h")
+func (b *binder) GenObjc(pkg *types.Package, outdir string) error {
+	name := objcName(pkg)
+	mfile := filepath.Join(outdir, name+".m")
+	hfile := filepath.Join(outdir, name+".h")
 
 	if buildX {
-		printcmd("gobind -lang=objc %s > %s", b.pkg.Path(), mfile)
+		flags := ""
+		if bindPrefix != "" {
+			flags = " -prefix=" + bindPrefix
+		}
+		printcmd("gobind -lang=objc%s %s > %s", flags, pkg.Path(), mfile)
 	}
 
 	generate := func(w io.Writer) error {
-		return bind.GenObjc(w, b.fset, b.pkg, false)
+		return bind.GenObjc(w, b.fset, pkg, b.pkgs, bindPrefix, false)
 	}
 	if err := writeFile(mfile, generate); err != nil {
 		return err
 	}
 	generate = func(w io.Writer) error {
-		return bind.GenObjc(w, b.fset, b.pkg, true)
-	}
-	if err := writeFile(hfile, generate); err != nil {
-		return err
-	}
-
-	objcPkg, err := ctx.Import("golang.org/x/mobile/bind/objc", "", build.FindOnly)
-	if err != nil {
-		return err
+		return bind.GenObjc(w, b.fset, pkg, b.pkgs, bindPrefix, true)
 	}
-	return copyFile(filepath.Join(outdir, "seq.h"), filepath.Join(objcPkg.Dir, "seq.h"))
+	return writeFile(hfile, generate)
 }
 
-func (b *binder) GenJava(outdir string) error {
-	className := strings.Title(b.pkg.Name())
+func (b *binder) GenJava(pkg *types.Package, outdir string) error {
+	className := strings.Title(pkg.Name())
 	javaFile := filepath.Join(outdir, className+".java")
 
 	if buildX {
-		printcmd("gobind -lang=java %s > %s", b.pkg.Path(), javaFile)
+		flags := ""
+		if bindJavaPkg != "" {
+			flags = " -javapkg=" + bindJavaPkg
+		}
+		printcmd("gobind -lang=java%s %s > %s", flags, pkg.Path(), javaFile)
 	}
 
 	generate := func(w io.Writer) error {
-		return bind.GenJava(w, b.fset, b.pkg)
+		return bind.GenJava(w, b.fset, pkg, b.pkgs, bindJavaPkg)
 	}
 	if err := writeFile(javaFile, generate); err != nil {
 		return err
@@ -146,16 +258,16 @@ func (b *binder) GenJava(outdir string) error {
 	return nil
 }
 
-func (b *binder) GenGo(outdir string) error {
-	pkgName := "go_" + b.pkg.Name()
+func (b *binder) GenGo(pkg *types.Package, outdir string) error {
+	pkgName := "go_" + pkg.Name()
 	goFile := filepath.Join(outdir, pkgName, pkgName+"main.go")
 
 	if buildX {
-		printcmd("gobind -lang=go %s > %s", b.pkg.Path(), goFile)
+		printcmd("gobind -lang=go %s > %s", pkg.Path(), goFile)
 	}
 
 	generate := func(w io.Writer) error {
-		return bind.GenGo(w, b.fset, b.pkg)
+		return bind.GenGo(w, b.fset, pkg, b.pkgs)
 	}
 	if err := writeFile(goFile, generate); err != nil {
 		return err
@@ -211,47 +323,58 @@ func writeFile(filename string, generate func(io.Writer) error) error {
 	return generate(f)
 }
 
-func newBinder(bindPkg *build.Package) (*binder, error) {
-	if bindPkg.Name == "main" {
-		return nil, fmt.Errorf("package %q: can only bind a library package", bindPkg.Name)
+func newBinder(bindPkgs []*build.Package) (*binder, error) {
+	fset := token.NewFileSet()
+	conf := loader.Config{
+		Fset: fset,
 	}
-
-	if len(bindPkg.CgoFiles) > 0 {
-		return nil, fmt.Errorf("cannot use cgo-dependent package as service definition: %s", bindPkg.CgoFiles[0])
+	conf.TypeChecker.Error = func(err error) {
+		fmt.Fprintln(os.Stderr, err)
 	}
 
-	fset := token.NewFileSet()
-
-	hasErr := false
 	var files []*ast.File
-	for _, filename := range bindPkg.GoFiles {
-		p := filepath.Join(bindPkg.Dir, filename)
-		file, err := parser.ParseFile(fset, p, nil, parser.AllErrors)
-		if err != nil {
-			hasErr = true
-			if list, _ := err.(scanner.ErrorList); len(list) > 0 {
-				for _, err := range list {
+	names := make(map[string]string) // package name -> import path
+	for _, bindPkg := range bindPkgs {
+		if bindPkg.Name == "main" {
+			return nil, fmt.Errorf("package %q: can only bind a library package", bindPkg.Name)
+		}
+
+		if len(bindPkg.CgoFiles) > 0 {
+			return nil, fmt.Errorf("cannot use cgo-dependent package as service definition: %s", bindPkg.CgoFiles[0])
+		}
+
+		// The generated files and classes are named after the package.
+		if path, ok := names[bindPkg.Name]; ok {
+			return nil, fmt.Errorf("packages %s and %s have the same name %s", path, bindPkg.ImportPath, bindPkg.Name)
+		}
+		names[bindPkg.Name] = bindPkg.ImportPath
+
+		hasErr := false
+		var pkgFiles []*ast.File
+		for _, filename := range bindPkg.GoFiles {
+			p := filepath.Join(bindPkg.Dir, filename)
+			file, err := parser.ParseFile(fset, p, nil, parser.AllErrors)
+			if err != nil {
+				hasErr = true
+				if list, _ := err.(scanner.ErrorList); len(list) > 0 {
+					for _, err := range list {
+						fmt.Fprintln(os.Stderr, err)
+					}
+				} else {
 					fmt.Fprintln(os.Stderr, err)
 				}
-			} else {
-				fmt.Fprintln(os.Stderr, err)
 			}
+			pkgFiles = append(pkgFiles, file)
 		}
-		files = append(files, file)
-	}
 
-	if hasErr {
-		return nil, errors.New("package parsing failed.")
-	}
+		if hasErr {
+			return nil, errors.New("package parsing failed.")
+		}
 
-	conf := loader.Config{
-		Fset: fset,
-	}
-	conf.TypeChecker.Error = func(err error) {
-		fmt.Fprintln(os.Stderr, err)
+		conf.CreateFromFiles(bindPkg.ImportPath, pkgFiles...)
+		files = append(files, pkgFiles...)
 	}
 
-	conf.CreateFromFiles(bindPkg.ImportPath, files...)
 	program, err := conf.Load()
 	if err != nil {
 		return nil, err
@@ -259,7 +382,28 @@ func newBinder(bindPkg *build.Package) (*binder, error) {
 	b := &binder{
 		files: files,
 		fset:  fset,
-		pkg:   program.Created[0].Pkg,
+	}
+	for _, info := range program.Created {
+		b.pkgs = append(b.pkgs, info.Pkg)
+	}
+
+	if bindSkip {
+		var probs []*bind.Problem
+		b.pkgs, probs = bind.Omit(fset, b.pkgs)
+		for _, p := range probs {
+			fmt.Fprintf(os.Stderr, "warning: omitted %v\n", p)
+		}
+		return b, nil
+	}
+	n := 0
+	for _, pkg := range b.pkgs {
+		for _, p := range bind.Check(fset, pkg, b.pkgs) {
+			fmt.Fprintln(os.Stderr, p)
+			n++
+		}
+	}
+	if n > 0 {
+		return nil, fmt.Errorf("%d declarations cannot be bound", n)
 	}
 	return b, nil
 }
@@ -18,23 +18,32 @@ import (
 	"text/template"
 )
 
-func goAndroidBind(pkg *build.Package) error {
+// goAndroidBind builds the AAR of the packages pkgs, holding the
+// shared library for each of the architectures archs.
+func goAndroidBind(pkgs []*build.Package, archs []string) error {
 	if sdkDir := os.Getenv("ANDROID_HOME"); sdkDir == "" {
 		return fmt.Errorf("this command requires ANDROID_HOME environment variable (path to the Android SDK)")
 	}
 
-	binder, err := newBinder(pkg)
+	binder, err := newBinder(pkgs)
 	if err != nil {
 		return err
 	}
+	if bindAPIDiff != "" {
+		if err := binder.checkAPI(bindAPIDiff); err != nil {
+			return err
+		}
+	}
 
-	if err := binder.GenGo(tmpdir); err != nil {
-		return err
+	for _, pkg := range binder.pkgs {
+		if err := binder.GenGo(pkg, tmpdir); err != nil {
+			return err
+		}
 	}
 
 	mainFile := filepath.Join(tmpdir, "androidlib/main.go")
 	err = writeFile(mainFile, func(w io.Writer) error {
-		return androidMainTmpl.Execute(w, "../go_"+binder.pkg.Name())
+		return androidMainTmpl.Execute(w, binder.pkgs)
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create the main package for android: %v", err)
@@ -42,14 +51,17 @@ func goAndroidBind(pkg *build.Package) error {
 
 	androidDir := filepath.Join(tmpdir, "android")
 
-	err = goBuild(
-		mainFile,
-		androidArmEnv,
-		"-buildmode=c-shared",
-		"-o="+filepath.Join(androidDir, "src/main/jniLibs/armeabi-v7a/libgojni.so"),
-	)
-	if err != nil {
-		return err
+	for _, arch := range archs {
+		tc, _ := ndkToolchainFor(arch)
+		err = goBuild(
+			mainFile,
+			androidEnv[arch],
+			"-buildmode=c-shared",
+			"-o="+filepath.Join(androidDir, "src/main/jniLibs/"+tc.abi+"/libgojni.so"),
+		)
+		if err != nil {
+			return err
+		}
 	}
 
 	p, err := ctx.Import("golang.org/x/mobile/bind", cwd, build.ImportComment)
@@ -58,9 +70,10 @@ func goAndroidBind(pkg *build.Package) error {
 	}
 	repo := filepath.Clean(filepath.Join(p.Dir, "..")) // golang.org/x/mobile directory.
 
-	// TODO(crawshaw): use a better package path derived from the go package.
-	if err := binder.GenJava(filepath.Join(androidDir, "src/main/java/go/"+binder.pkg.Name())); err != nil {
-		return err
+	for _, pkg := range binder.pkgs {
+		if err := binder.GenJava(pkg, filepath.Join(androidDir, "src/main/java", javaPkgDir(pkg))); err != nil {
+			return err
+		}
 	}
 
 	dst := filepath.Join(androidDir, "src/main/java/go/LoadJNI.java")
@@ -79,7 +92,7 @@ func goAndroidBind(pkg *build.Package) error {
 		return err
 	}
 
-	return buildAAR(androidDir, pkg)
+	return buildAAR(androidDir, pkgs, archs)
 }
 
 var loadSrc = `package go;
@@ -96,8 +109,8 @@ package main
 
 import (
 	_ "golang.org/x/mobile/bind/java"
-	_ "{{.}}"
-)
+{{range .}}	_ "../go_{{.Name}}"
+{{end}})
 
 func main() {}
 `))
@@ -110,7 +123,7 @@ func main() {}
 //
 //	AndroidManifest.xml (mandatory)
 // 	classes.jar (mandatory)
-//	assets/ (optional)
+//	assets/ (optional, merged from the bound packages)
 //	jni/<abi>/libgojni.so
 //	R.txt (mandatory)
 //	res/ (mandatory)
@@ -120,8 +133,9 @@ func main() {}
 //	aidl (optional, not relevant)
 //
 // javac and jar commands are needed to build classes.jar.
-func buildAAR(androidDir string, pkg *build.Package) (err error) {
+func buildAAR(androidDir string, pkgs []*build.Package, archs []string) (err error) {
 	var out io.Writer = ioutil.Discard
+	pkg := pkgs[0] // names the library
 	if buildO == "" {
 		buildO = pkg.Name + ".aar"
 	}
@@ -153,13 +167,20 @@ func buildAAR(androidDir string, pkg *build.Package) (err error) {
 		return err
 	}
 	const manifestFmt = `<manifest xmlns:android="http://schemas.android.com/apk/res/android" package=%q />`
-	fmt.Fprintf(w, manifestFmt, "go."+pkg.Name+".gojni")
+	javaPkg := bindJavaPkg
+	if javaPkg == "" {
+		javaPkg = "go"
+	}
+	fmt.Fprintf(w, manifestFmt, javaPkg+"."+pkg.Name+".gojni")
 
 	w, err = aarwcreate("proguard.txt")
 	if err != nil {
 		return err
 	}
 	fmt.Fprintln(w, `-keep class go.** { *; }`)
+	if javaPkg != "go" {
+		fmt.Fprintf(w, "-keep class %s.** { *; }\n", javaPkg)
+	}
 
 	w, err = aarwcreate("classes.jar")
 	if err != nil {
@@ -170,54 +191,29 @@ func buildAAR(androidDir string, pkg *build.Package) (err error) {
 		return err
 	}
 
-	assetsDir := filepath.Join(pkg.Dir, "assets")
-	assetsDirExists := false
-	if fi, err := os.Stat(assetsDir); err == nil {
-		assetsDirExists = fi.IsDir()
-	} else if !os.IsNotExist(err) {
-		return err
-	}
-
-	if assetsDirExists {
-		err := filepath.Walk(
-			assetsDir, func(path string, info os.FileInfo, err error) error {
-				if err != nil {
-					return err
-				}
-				if info.IsDir() {
-					return nil
-				}
-				f, err := os.Open(path)
-				if err != nil {
-					return err
-				}
-				defer f.Close()
-				name := "assets/" + path[len(assetsDir)+1:]
-				w, err := aarwcreate(name)
-				if err != nil {
-					return nil
-				}
-				_, err = io.Copy(w, f)
-				return err
-			})
-		if err != nil {
+	assets := make(map[string]string) // asset name -> import path
+	for _, pkg := range pkgs {
+		if err := addAssets(aarwcreate, pkg, assets); err != nil {
 			return err
 		}
 	}
 
-	lib := "armeabi-v7a/libgojni.so"
-	w, err = aarwcreate("jni/" + lib)
-	if err != nil {
-		return err
-	}
-	if !buildN {
-		r, err := os.Open(filepath.Join(androidDir, "src/main/jniLibs/"+lib))
+	for _, arch := range archs {
+		tc, _ := ndkToolchainFor(arch)
+		lib := tc.abi + "/libgojni.so"
+		w, err = aarwcreate("jni/" + lib)
 		if err != nil {
 			return err
 		}
-		defer r.Close()
-		if _, err := io.Copy(w, r); err != nil {
-			return err
+		if !buildN {
+			r, err := os.Open(filepath.Join(androidDir, "src/main/jniLibs/"+lib))
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+			if _, err := io.Copy(w, r); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -235,6 +231,47 @@ func buildAAR(androidDir string, pkg *build.Package) (err error) {
 	return aarw.Close()
 }
 
+// addAssets adds the files of the assets directory of pkg, if any,
+// to the archive. The assets of the bound packages share a single
+// directory, so each name can be used by one package only.
+func addAssets(create func(string) (io.Writer, error), pkg *build.Package, assets map[string]string) error {
+	assetsDir := filepath.Join(pkg.Dir, "assets")
+	if fi, err := os.Stat(assetsDir); err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	} else if !fi.IsDir() {
+		return nil
+	}
+
+	return filepath.Walk(
+		assetsDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			name := "assets/" + filepath.ToSlash(path[len(assetsDir)+1:])
+			if other, ok := assets[name]; ok {
+				return fmt.Errorf("%s is in the assets of both %s and %s", name, other, pkg.ImportPath)
+			}
+			assets[name] = pkg.ImportPath
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w, err := create(name)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(w, f)
+			return err
+		})
+}
+
 const (
 	javacTargetVer = "1.7"
 	minAndroidAPI  = 9
@@ -12,14 +12,21 @@ import (
 	"path/filepath"
 	"strings"
 	"text/template"
+
+	"golang.org/x/tools/go/types"
 )
 
-func goIOSBind(pkg *build.Package) error {
-	binder, err := newBinder(pkg)
+func goIOSBind(pkgs []*build.Package) error {
+	binder, err := newBinder(pkgs)
 	if err != nil {
 		return err
 	}
-	name := binder.pkg.Name()
+	if bindAPIDiff != "" {
+		if err := binder.checkAPI(bindAPIDiff); err != nil {
+			return err
+		}
+	}
+	name := binder.pkgs[0].Name() // names the framework
 
 	if buildO != "" && !strings.HasSuffix(buildO, ".framework") {
 		return fmt.Errorf("static framework name %q missing .framework suffix", buildO)
@@ -28,17 +35,28 @@ func goIOSBind(pkg *build.Package) error {
 		buildO = name + ".framework"
 	}
 
-	if err := binder.GenGo(filepath.Join(tmpdir, "src")); err != nil {
-		return err
+	for _, pkg := range binder.pkgs {
+		if err := binder.GenGo(pkg, filepath.Join(tmpdir, "src")); err != nil {
+			return err
+		}
 	}
 	mainFile := filepath.Join(tmpdir, "src/iosbin/main.go")
 	err = writeFile(mainFile, func(w io.Writer) error {
-		return iosBindTmpl.Execute(w, "../go_"+name)
+		return iosBindTmpl.Execute(w, binder.pkgs)
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create the binding package for iOS: %v", err)
 	}
-	if err := binder.GenObjc(filepath.Join(tmpdir, "objc")); err != nil {
+	for _, pkg := range binder.pkgs {
+		if err := binder.GenObjc(pkg, filepath.Join(tmpdir, "objc")); err != nil {
+			return err
+		}
+	}
+	objcPkg, err := ctx.Import("golang.org/x/mobile/bind/objc", "", build.FindOnly)
+	if err != nil {
+		return err
+	}
+	if err := copyFile(filepath.Join(tmpdir, "objc", "seq.h"), filepath.Join(objcPkg.Dir, "seq.h")); err != nil {
 		return err
 	}
 
@@ -47,7 +65,7 @@ func goIOSBind(pkg *build.Package) error {
 	// TODO(crawshaw): Build in parallel.
 	for _, env := range [][]string{darwinArmEnv, darwinArm64Env, darwinAmd64Env} {
 		arch := archClang(getenv(env, "GOARCH"))
-		path, err := goIOSBindArchive(name, mainFile, env)
+		path, err := goIOSBindArchive(name, binder.pkgs, mainFile, env)
 		if err != nil {
 			return fmt.Errorf("darwin-%s: %v", arch, err)
 		}
@@ -78,13 +96,27 @@ func goIOSBind(pkg *build.Package) error {
 	}
 
 	// Copy header file next to output archive.
-	return copyFile(
-		headers+"/"+strings.Title(name)+".h",
-		tmpdir+"/objc/Go"+strings.Title(name)+".h",
-	)
+	if len(binder.pkgs) == 1 {
+		return copyFile(
+			headers+"/"+strings.Title(name)+".h",
+			tmpdir+"/objc/"+objcName(binder.pkgs[0])+".h",
+		)
+	}
+
+	// The headers of several packages include each other, they are
+	// copied as is and included by the framework header.
+	for _, pkg := range binder.pkgs {
+		hname := objcName(pkg) + ".h"
+		if err := copyFile(headers+"/"+hname, tmpdir+"/objc/"+hname); err != nil {
+			return err
+		}
+	}
+	return writeFile(headers+"/"+strings.Title(name)+".h", func(w io.Writer) error {
+		return iosHeaderTmpl.Execute(w, binder.pkgs)
+	})
 }
 
-func goIOSBindArchive(name, path string, env []string) (string, error) {
+func goIOSBindArchive(name string, pkgs []*types.Package, path string, env []string) (string, error) {
 	arch := getenv(env, "GOARCH")
 	archive := filepath.Join(tmpdir, name+"-"+arch+".a")
 	err := goBuild(path, env, "-buildmode=c-archive", "-tags=ios", "-o", archive)
@@ -92,25 +124,27 @@ func goIOSBindArchive(name, path string, env []string) (string, error) {
 		return "", err
 	}
 
-	obj := "gobind-" + name + "-" + arch + ".o"
-	cmd := exec.Command(
-		getenv(env, "CC"),
-		"-I", ".",
-		"-g", "-O2",
-		"-o", obj,
-		"-c", "Go"+strings.Title(name)+".m",
-	)
-	cmd.Args = append(cmd.Args, strings.Split(getenv(env, "CGO_CFLAGS"), " ")...)
-	cmd.Dir = filepath.Join(tmpdir, "objc")
-	cmd.Env = append([]string{}, env...)
-	if err := runCmd(cmd); err != nil {
-		return "", err
-	}
+	for _, pkg := range pkgs {
+		obj := "gobind-" + pkg.Name() + "-" + arch + ".o"
+		cmd := exec.Command(
+			getenv(env, "CC"),
+			"-I", ".",
+			"-g", "-O2",
+			"-o", obj,
+			"-c", objcName(pkg)+".m",
+		)
+		cmd.Args = append(cmd.Args, strings.Split(getenv(env, "CGO_CFLAGS"), " ")...)
+		cmd.Dir = filepath.Join(tmpdir, "objc")
+		cmd.Env = append([]string{}, env...)
+		if err := runCmd(cmd); err != nil {
+			return "", err
+		}
 
-	cmd = exec.Command("ar", "-q", "-s", archive, obj)
-	cmd.Dir = filepath.Join(tmpdir, "objc")
-	if err := runCmd(cmd); err != nil {
-		return "", err
+		cmd = exec.Command("ar", "-q", "-s", archive, obj)
+		cmd.Dir = filepath.Join(tmpdir, "objc")
+		if err := runCmd(cmd); err != nil {
+			return "", err
+		}
 	}
 	return archive, nil
 }
@@ -120,10 +154,20 @@ package main
 
 import (
 	_ "golang.org/x/mobile/bind/objc"
-	_ "{{.}}"
-)
+{{range .}}	_ "../go_{{.Name}}"
+{{end}})
 
 import "C"
 
 func main() {}
 `))
+
+var iosHeaderTmpl = template.Must(template.New("ios.h").Funcs(template.FuncMap{
+	"objcName": objcName,
+}).Parse(`// Objective-C API for talking to the Go packages
+{{range .}}//	{{.Path}}
+{{end}}//
+// File is generated by gomobile bind. Do not edit.
+{{range .}}
+#include "{{objcName .}}.h"{{end}}
+`))
@@ -24,7 +24,7 @@ var tmpdir string
 var cmdBuild = &command{
 	run:   runBuild,
 	Name:  "build",
-	Usage: "[-target android|ios] [-o output] [build flags] [package]",
+	Usage: "[-target android|ios|android/arch,...] [-keystore file [-keyalias name] [-storepass-env var] [-keypass-env var]] [-signschemes list] [-o output] [build flags] [package]",
 	Short: "compile android APK and iOS app",
 	Long: `
 Build compiles and encodes the app named by the import path.
@@ -32,18 +32,54 @@ Build compiles and encodes the app named by the import path.
 The named package must define a main function.
 
 The -target flag takes a target system name, either android (the
-default) or ios.
+default) or ios. For android, it may instead list the architectures
+to build, separated by commas: android/arm, android/arm64, android/386
+and android/amd64. The name android alone builds android/arm. The
+toolchains of the other architectures are installed by 'gomobile init
+-arch'.
 
 For -target android, if an AndroidManifest.xml is defined in the
 package directory, it is added to the APK output. Otherwise, a default
-manifest is generated.
+manifest is generated, with the package name of the app derived from
+the import path, such as org.golang.x.mobile.example.basic for
+golang.org/x/mobile/example/basic. The APK holds a shared library for
+each architecture, in lib/<abi>, such as lib/x86 for android/386 and
+lib/armeabi for android/arm.
 
 For -target ios, gomobile must be run on an OS X machine with Xcode
 installed. Support is not complete.
 
+The APK is signed with a debug key, unless the -keystore flag names
+the keystore holding the release key. The keystore is a JKS or PKCS#12
+keystore, as written by keytool, or a PEM file holding the private key
+and its certificate. The certificate is required: it identifies the app,
+whose updates must be signed with the same one. The -keyalias flag
+chooses the key of a keystore holding several. The -storepass-env flag
+names the environment variable holding the password of the keystore,
+and -keypass-env the one holding the password of the key, the password
+of the keystore by default. The password of a PEM key is its key
+password.
+
+The -signschemes flag lists the signature schemes of the APK, separated
+by commas: v1, the JAR signature checked by all the Android versions,
+v1-sha256, the same with SHA-256 digests for Android 4.3 and later, and
+the APK Signature Schemes v2 and v3, checked instead by Android 7 and 9
+and later. The default is v1,v2.
+
 If the package directory contains an assets subdirectory, its contents
 are copied into the output.
 
+If the package directory contains a res subdirectory, its resources are
+compiled into the resources.arsc table of the APK. The XML files of
+res/values hold strings, colors, dimensions, booleans and integers, such
+as the name of the app, and res/values-fr those in French. The files of
+the other subdirectories, such as res/mipmap-hdpi/ic_launcher.png, are
+resources of their type, for screens of a density. The languages,
+regions, densities and minimum SDK versions are the supported
+qualifiers. AndroidManifest.xml refers to the resources as
+@string/app_name and @mipmap/ic_launcher; the default manifest uses
+those two if they exist.
+
 The -o flag specifies the output file name. If not specified, the
 output file name depends on the package built.
 
@@ -55,11 +91,21 @@ with the build command. For documentation, see 'go help build'.
 }
 
 func runBuild(cmd *command) (err error) {
+	targetOS, targetArchs, err := parseBuildTarget(buildTarget)
+	if err != nil {
+		return err
+	}
+
 	cleanup, err := buildEnvInit()
 	if err != nil {
 		return err
 	}
 	defer cleanup()
+	if targetOS == "android" {
+		if err := checkNDKToolchains(targetArchs); err != nil {
+			return err
+		}
+	}
 
 	args := cmd.flag.Args()
 
@@ -79,13 +125,24 @@ func runBuild(cmd *command) (err error) {
 	if pkg.Name != "main" && buildO != "" {
 		return fmt.Errorf("cannot set -o when building non-main package")
 	}
+	if buildKeystore != "" && targetOS != "android" {
+		return fmt.Errorf("-keystore is supported only for -target android")
+	}
+	if buildSignSchemes != defaultSignSchemes && targetOS != "android" {
+		return fmt.Errorf("-signschemes is supported only for -target android")
+	}
 
-	switch buildTarget {
+	switch targetOS {
 	case "android":
 		if pkg.Name != "main" {
-			return goBuild(pkg.ImportPath, androidArmEnv)
+			for _, arch := range targetArchs {
+				if err := goBuild(pkg.ImportPath, androidEnv[arch]); err != nil {
+					return err
+				}
+			}
+			return nil
 		}
-		if err := goAndroidBuild(pkg); err != nil {
+		if err := goAndroidBuild(pkg, targetArchs); err != nil {
 			return err
 		}
 	case "ios":
@@ -101,8 +158,6 @@ func runBuild(cmd *command) (err error) {
 		if err := goIOSBuild(pkg); err != nil {
 			return err
 		}
-	default:
-		return fmt.Errorf(`unknown -target, %q.`, buildTarget)
 	}
 
 	// TODO(crawshaw): This is an incomplete package scan.
@@ -116,6 +171,51 @@ func runBuild(cmd *command) (err error) {
 	return nil
 }
 
+// parseBuildTarget parses the -target flag, a target system name or a
+// comma-separated list of target systems and architectures, such as
+// android/arm,android/386. It returns the system and the architectures
+// to build for it. The name android alone stands for android/arm. The
+// ios target takes no architecture.
+func parseBuildTarget(target string) (targetOS string, archs []string, err error) {
+	all := false
+	seen := make(map[string]bool)
+	for _, t := range strings.Split(target, ",") {
+		tOS, arch := t, ""
+		if i := strings.IndexByte(t, '/'); i >= 0 {
+			tOS, arch = t[:i], t[i+1:]
+		}
+		if targetOS != "" && tOS != targetOS {
+			return "", nil, fmt.Errorf("-target=%s names both %s and %s", target, targetOS, tOS)
+		}
+		targetOS = tOS
+		switch tOS {
+		case "android":
+		case "ios":
+			if arch != "" {
+				return "", nil, fmt.Errorf("-target=ios takes no architecture, got %q", t)
+			}
+			continue
+		default:
+			return "", nil, fmt.Errorf(`unknown -target, %q.`, target)
+		}
+		if arch == "" {
+			all = true
+			continue
+		}
+		if _, ok := ndkToolchainFor(arch); !ok {
+			return "", nil, fmt.Errorf("unsupported -target architecture %q", t)
+		}
+		if !seen[arch] {
+			seen[arch] = true
+			archs = append(archs, arch)
+		}
+	}
+	if all && !seen["arm"] {
+		archs = append([]string{"arm"}, archs...)
+	}
+	return targetOS, archs, nil
+}
+
 func importsApp(pkg *build.Package) error {
 	// Building a program, make sure it is appropriate for mobile.
 	for _, path := range pkg.Imports {
@@ -165,8 +265,16 @@ var (
 	buildGcflags string // -gcflags
 	buildLdflags string // -ldflags
 	buildTarget  string // -target
+
+	buildKeystore     string // -keystore
+	buildKeyAlias     string // -keyalias
+	buildStorePassEnv string // -storepass-env
+	buildKeyPassEnv   string // -keypass-env
+	buildSignSchemes  string // -signschemes
 )
 
+const defaultSignSchemes = "v1,v2"
+
 func addBuildFlags(cmd *command) {
 	cmd.flag.StringVar(&buildO, "o", "", "")
 	cmd.flag.StringVar(&buildGcflags, "gcflags", "", "")
@@ -178,6 +286,16 @@ func addBuildFlags(cmd *command) {
 	cmd.flag.Var((*stringsFlag)(&ctx.BuildTags), "tags", "")
 }
 
+// addSignFlags adds the flags choosing the key and the signatures of
+// the APKs.
+func addSignFlags(cmd *command) {
+	cmd.flag.StringVar(&buildKeystore, "keystore", "", "")
+	cmd.flag.StringVar(&buildKeyAlias, "keyalias", "", "")
+	cmd.flag.StringVar(&buildStorePassEnv, "storepass-env", "", "")
+	cmd.flag.StringVar(&buildKeyPassEnv, "keypass-env", "", "")
+	cmd.flag.StringVar(&buildSignSchemes, "signschemes", defaultSignSchemes, "")
+}
+
 func addBuildFlagsNVX(cmd *command) {
 	cmd.flag.BoolVar(&buildN, "n", false, "")
 	cmd.flag.BoolVar(&buildV, "v", false, "")
@@ -192,9 +310,11 @@ type binInfo struct {
 func init() {
 	addBuildFlags(cmdBuild)
 	addBuildFlagsNVX(cmdBuild)
+	addSignFlags(cmdBuild)
 
 	addBuildFlags(cmdInstall)
 	addBuildFlagsNVX(cmdInstall)
+	addSignFlags(cmdInstall)
 
 	addBuildFlagsNVX(cmdInit)
 
@@ -6,10 +6,7 @@ package main
 
 import (
 	"bytes"
-	"crypto/x509"
 	"encoding/base64"
-	"encoding/pem"
-	"errors"
 	"fmt"
 	"go/build"
 	"io"
@@ -18,25 +15,58 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
-func goAndroidBuild(pkg *build.Package) error {
+// apkABI returns the directory of the libraries of the Android
+// architecture arch in an APK. The arm libraries stay in lib/armeabi,
+// where APKs have always held them and where the OpenAL library of
+// gomobile init is installed.
+func apkABI(arch string) string {
+	if arch == "arm" {
+		return "armeabi"
+	}
+	tc, _ := ndkToolchainFor(arch)
+	return tc.abi
+}
+
+// goAndroidBuild builds the APK of the main package pkg, holding its
+// shared library for each of the architectures archs.
+func goAndroidBuild(pkg *build.Package, archs []string) error {
+	privKey, cert, err := signingKey()
+	if err != nil {
+		return err
+	}
+	sign, err := parseSigning(buildSignSchemes)
+	if err != nil {
+		return fmt.Errorf("-signschemes: %v", err)
+	}
+	res, err := readResources(filepath.Join(pkg.Dir, "res"))
+	if err != nil {
+		return err
+	}
+
 	libName := path.Base(pkg.ImportPath)
 	manifestData, err := ioutil.ReadFile(filepath.Join(pkg.Dir, "AndroidManifest.xml"))
 	if err != nil {
 		if !os.IsNotExist(err) {
 			return err
 		}
-		buf := new(bytes.Buffer)
-		buf.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
-		err := manifestTmpl.Execute(buf, manifestTmplData{
-			// TODO(crawshaw): a better package path.
-			JavaPkgPath: "org.golang.todo." + libName,
+		data := manifestTmplData{
+			JavaPkgPath: manifestPackageName(pkg.ImportPath),
 			Name:        libName,
 			LibName:     libName,
-		})
-		if err != nil {
+		}
+		if _, ok := res.id("string", "app_name"); ok {
+			data.Name = "@string/app_name"
+		}
+		if _, ok := res.id("mipmap", "ic_launcher"); ok {
+			data.Icon = "@mipmap/ic_launcher"
+		}
+		buf := new(bytes.Buffer)
+		buf.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+		if err := manifestTmpl.Execute(buf, data); err != nil {
 			return err
 		}
 		manifestData = buf.Bytes()
@@ -49,24 +79,26 @@ func goAndroidBuild(pkg *build.Package) error {
 			return err
 		}
 	}
-	libPath := filepath.Join(tmpdir, "lib"+libName+".so")
-
-	err = goBuild(
-		pkg.ImportPath,
-		androidArmEnv,
-		"-buildmode=c-shared",
-		"-o", libPath,
-	)
-	if err != nil {
-		return err
-	}
-	block, _ := pem.Decode([]byte(debugCert))
-	if block == nil {
-		return errors.New("no debug cert")
+	if res != nil {
+		if res.pkg, err = manifestPackage(manifestData); err != nil {
+			return err
+		}
 	}
-	privKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
-	if err != nil {
-		return err
+
+	// The libraries are named by their path in the APK.
+	var libFiles []string
+	for _, arch := range archs {
+		libFile := "lib/" + apkABI(arch) + "/lib" + libName + ".so"
+		err = goBuild(
+			pkg.ImportPath,
+			androidEnv[arch],
+			"-buildmode=c-shared",
+			"-o", filepath.Join(tmpdir, libFile),
+		)
+		if err != nil {
+			return err
+		}
+		libFiles = append(libFiles, libFile)
 	}
 
 	if buildO == "" {
@@ -91,7 +123,9 @@ func goAndroidBuild(pkg *build.Package) error {
 
 	var apkw *Writer
 	if !buildN {
-		apkw = NewWriter(out, privKey)
+		apkw = NewWriter(out, privKey, cert)
+		apkw.sign = sign
+		apkw.res = res
 	}
 	apkwcreate := func(name string) (io.Writer, error) {
 		if buildV {
@@ -123,44 +157,86 @@ func goAndroidBuild(pkg *build.Package) error {
 		return err
 	}
 
-	w, err = apkwcreate("lib/armeabi/lib" + libName + ".so")
-	if err != nil {
-		return err
-	}
-	if !buildN {
-		r, err := os.Open(libPath)
+	apkwcopy := func(name, src string) error {
+		w, err := apkwcreate(name)
 		if err != nil {
 			return err
 		}
-		if _, err := io.Copy(w, r); err != nil {
+		if buildN {
+			return nil
+		}
+		r, err := os.Open(src)
+		if err != nil {
 			return err
 		}
+		defer r.Close()
+		_, err = io.Copy(w, r)
+		return err
 	}
 
-	if pkgImportsAL(pkg) {
-		alDir := filepath.Join(ndkccpath, "openal/lib")
-		filepath.Walk(alDir, func(path string, info os.FileInfo, err error) error {
+	// Add any resources, compiling their XML files but the raw ones.
+	if res != nil {
+		w, err := apkwcreate("resources.arsc")
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(res.encode()); err != nil {
+			return err
+		}
+		var names []string
+		for name := range res.files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			typ := strings.SplitN(path.Base(path.Dir(name)), "-", 2)[0]
+			if path.Ext(name) != ".xml" || typ == "raw" {
+				if err := apkwcopy(name, res.files[name]); err != nil {
+					return err
+				}
+				continue
+			}
+			w, err := apkwcreate(name)
 			if err != nil {
 				return err
 			}
-			if info.IsDir() {
-				return nil
+			if buildN {
+				continue
 			}
-			name := "lib/" + path[len(alDir)+1:]
-			w, err := apkwcreate(name)
+			src, err := os.Open(res.files[name])
 			if err != nil {
 				return err
 			}
+			b, err := binaryXML(src, res)
+			src.Close()
+			if err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+			if _, err := w.Write(b); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, libFile := range libFiles {
+		if err := apkwcopy(libFile, filepath.Join(tmpdir, libFile)); err != nil {
+			return err
+		}
+	}
+
+	if pkgImportsAL(pkg) {
+		for _, arch := range archs {
+			name := "lib/" + apkABI(arch) + "/libopenal.so"
+			src := filepath.Join(ndkccpath, "openal", name)
 			if !buildN {
-				f, err := os.Open(path)
-				if err != nil {
-					return err
+				if _, err := os.Stat(src); err != nil {
+					return fmt.Errorf("OpenAL is not installed for android/%s", arch)
 				}
-				defer f.Close()
-				_, err = io.Copy(w, f)
 			}
-			return err
-		})
+			if err := apkwcopy(name, src); err != nil {
+				return err
+			}
+		}
 	}
 
 	// Add any assets.
@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"text/template"
 )
@@ -23,7 +24,7 @@ func TestAndroidBuild(t *testing.T) {
 	buildN = true
 	buildX = true
 	buildO = "basic.apk"
-	buildTarget = "android"
+	buildTarget = "android/arm,android/arm64,android/386,android/amd64"
 	gopath = filepath.SplitList(os.Getenv("GOPATH"))[0]
 	if goos == "windows" {
 		os.Setenv("HOMEDRIVE", "C:")
@@ -46,5 +47,60 @@ func TestAndroidBuild(t *testing.T) {
 
 var androidBuildTmpl = template.Must(template.New("output").Parse(`GOMOBILE={{.GOPATH}}/pkg/gomobile
 WORK=$WORK
-GOOS=android GOARCH=arm GOARM=7 CC=$GOMOBILE/android-{{.NDK}}/arm/bin/arm-linux-androideabi-gcc{{.EXE}} CXX=$GOMOBILE/android-{{.NDK}}/arm/bin/arm-linux-androideabi-g++{{.EXE}} CGO_ENABLED=1 go build -pkgdir=$GOMOBILE/pkg_android_arm -tags="" -x -buildmode=c-shared -o $WORK/libbasic.so golang.org/x/mobile/example/basic
+GOOS=android GOARCH=arm GOARM=7 CC=$GOMOBILE/android-{{.NDK}}/arm/bin/arm-linux-androideabi-gcc{{.EXE}} CXX=$GOMOBILE/android-{{.NDK}}/arm/bin/arm-linux-androideabi-g++{{.EXE}} CGO_ENABLED=1 go build -pkgdir=$GOMOBILE/pkg_android_arm -tags="" -x -buildmode=c-shared -o $WORK/lib/armeabi/libbasic.so golang.org/x/mobile/example/basic
+GOOS=android GOARCH=arm64 CC=$GOMOBILE/android-{{.NDK}}/arm64/bin/aarch64-linux-android-gcc{{.EXE}} CXX=$GOMOBILE/android-{{.NDK}}/arm64/bin/aarch64-linux-android-g++{{.EXE}} CGO_ENABLED=1 go build -pkgdir=$GOMOBILE/pkg_android_arm64 -tags="" -x -buildmode=c-shared -o $WORK/lib/arm64-v8a/libbasic.so golang.org/x/mobile/example/basic
+GOOS=android GOARCH=386 CC=$GOMOBILE/android-{{.NDK}}/386/bin/i686-linux-android-gcc{{.EXE}} CXX=$GOMOBILE/android-{{.NDK}}/386/bin/i686-linux-android-g++{{.EXE}} CGO_ENABLED=1 go build -pkgdir=$GOMOBILE/pkg_android_386 -tags="" -x -buildmode=c-shared -o $WORK/lib/x86/libbasic.so golang.org/x/mobile/example/basic
+GOOS=android GOARCH=amd64 CC=$GOMOBILE/android-{{.NDK}}/amd64/bin/x86_64-linux-android-gcc{{.EXE}} CXX=$GOMOBILE/android-{{.NDK}}/amd64/bin/x86_64-linux-android-g++{{.EXE}} CGO_ENABLED=1 go build -pkgdir=$GOMOBILE/pkg_android_amd64 -tags="" -x -buildmode=c-shared -o $WORK/lib/x86_64/libbasic.so golang.org/x/mobile/example/basic
 `))
+
+func TestParseBuildTarget(t *testing.T) {
+	tests := []struct {
+		in    string
+		os    string
+		archs string
+		err   bool
+	}{
+		{"android", "android", "arm", false},
+		{"android/arm", "android", "arm", false},
+		{"android/386,android/arm64,android/386", "android", "386,arm64", false},
+		{"android/arm,android", "android", "arm", false},
+		{"android/amd64,android", "android", "arm,amd64", false},
+		{"ios", "ios", "", false},
+		{"", "", "", true},
+		{"windows", "", "", true},
+		{"android/mips", "", "", true},
+		{"ios/arm", "", "", true},
+		{"android/arm,ios", "", "", true},
+	}
+	for _, tc := range tests {
+		gotOS, archs, err := parseBuildTarget(tc.in)
+		if tc.err {
+			if err == nil {
+				t.Errorf("parseBuildTarget(%q) = %s %v, want error", tc.in, gotOS, archs)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseBuildTarget(%q): %v", tc.in, err)
+			continue
+		}
+		if gotOS != tc.os || strings.Join(archs, ",") != tc.archs {
+			t.Errorf("parseBuildTarget(%q) = %s %v, want %s %s", tc.in, gotOS, archs, tc.os, tc.archs)
+		}
+	}
+}
+
+func TestManifestPackageName(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"golang.org/x/mobile/example/basic", "org.golang.x.mobile.example.basic"},
+		{"github.com/a-b/3d/new", "com.github.a_b._3d._new"},
+		{"example.com/app.v2", "com.example.app.v2"},
+		{"hello", "org.golang.app.hello"},
+		{"my/hello-world", "org.golang.app.hello_world"},
+	}
+	for _, tc := range tests {
+		if got := manifestPackageName(tc.in); got != tc.want {
+			t.Errorf("manifestPackageName(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
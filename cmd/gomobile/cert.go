@@ -5,47 +5,44 @@
 package main
 
 import (
+	"bytes"
 	"crypto"
+	"crypto/ecdsa"
 	"crypto/rsa"
-	"crypto/sha1"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/asn1"
+	"errors"
+	"fmt"
 	"io"
 	"math/big"
-	"time"
 )
 
 // signPKCS7 does the minimal amount of work necessary to embed an RSA
 // signature into a PKCS#7 certificate.
 //
-// We prepare the certificate using the x509 package, read it back in
-// to our custom data type and then write it back out with the signature.
-func signPKCS7(rand io.Reader, priv *rsa.PrivateKey, msg []byte) ([]byte, error) {
-	const serialNumber = 0x5462c4dd // arbitrary
-	name := pkix.Name{CommonName: "gomobile"}
-
-	template := &x509.Certificate{
-		SerialNumber:       big.NewInt(serialNumber),
-		SignatureAlgorithm: x509.SHA1WithRSA,
-		Subject:            name,
-	}
-
-	b, err := x509.CreateCertificate(rand, template, template, priv.Public(), priv)
-	if err != nil {
-		return nil, err
+// The signature is made with priv over the hash digest of msg, and
+// carries cert, the certificate of priv. If cert is nil, we prepare a
+// self-signed certificate with the x509 package.
+func signPKCS7(rand io.Reader, priv *rsa.PrivateKey, cert *x509.Certificate, hash crypto.Hash, msg []byte) ([]byte, error) {
+	if cert == nil {
+		var err error
+		if cert, err = selfSignedCert(rand, priv); err != nil {
+			return nil, err
+		}
 	}
-
-	c := certificate{}
-	if _, err := asn1.Unmarshal(b, &c); err != nil {
-		return nil, err
+	oidHash, ok := oidHashes[hash]
+	if !ok {
+		return nil, fmt.Errorf("unsupported digest %v", hash)
 	}
 
-	h := sha1.New()
+	h := hash.New()
 	h.Write(msg)
 	hashed := h.Sum(nil)
 
-	signed, err := rsa.SignPKCS1v15(rand, priv, crypto.SHA1, hashed)
+	signed, err := rsa.SignPKCS1v15(rand, priv, hash, hashed)
 	if err != nil {
 		return nil, err
 	}
@@ -55,19 +52,24 @@ func signPKCS7(rand io.Reader, priv *rsa.PrivateKey, msg []byte) ([]byte, error)
 		Content: signedData{
 			Version: 1,
 			DigestAlgorithms: []pkix.AlgorithmIdentifier{{
-				Algorithm:  oidSHA1,
+				Algorithm:  oidHash,
 				Parameters: asn1.RawValue{Tag: 5},
 			}},
-			ContentInfo:  contentInfo{Type: oidData},
-			Certificates: c,
+			ContentInfo: contentInfo{Type: oidData},
+			Certificates: asn1.RawValue{
+				Class:      asn1.ClassContextSpecific,
+				Tag:        0,
+				IsCompound: true,
+				Bytes:      cert.Raw,
+			},
 			SignerInfos: []signerInfo{{
 				Version: 1,
 				IssuerAndSerialNumber: issuerAndSerialNumber{
-					Issuer:       name.ToRDNSequence(),
-					SerialNumber: serialNumber,
+					Issuer:       asn1.RawValue{FullBytes: cert.RawIssuer},
+					SerialNumber: cert.SerialNumber,
 				},
 				DigestAlgorithm: pkix.AlgorithmIdentifier{
-					Algorithm:  oidSHA1,
+					Algorithm:  oidHash,
 					Parameters: asn1.RawValue{Tag: 5},
 				},
 				DigestEncryptionAlgorithm: pkix.AlgorithmIdentifier{
@@ -82,6 +84,117 @@ func signPKCS7(rand io.Reader, priv *rsa.PrivateKey, msg []byte) ([]byte, error)
 	return asn1.Marshal(content)
 }
 
+// selfSignedCert returns a self-signed certificate of priv, carried by
+// the signatures of the debug key.
+func selfSignedCert(rand io.Reader, priv *rsa.PrivateKey) (*x509.Certificate, error) {
+	const serialNumber = 0x5462c4dd // arbitrary
+	template := &x509.Certificate{
+		SerialNumber:       big.NewInt(serialNumber),
+		SignatureAlgorithm: x509.SHA1WithRSA,
+		Subject:            pkix.Name{CommonName: "gomobile"},
+	}
+	b, err := x509.CreateCertificate(rand, template, template, priv.Public(), priv)
+	if err != nil {
+		return nil, err
+	}
+	return x509.ParseCertificate(b)
+}
+
+// verifyPKCS7 verifies the PKCS#7 signature sig of the detached content
+// msg, as written by signPKCS7, jarsigner or apksigner, and returns the
+// certificate of the signer and the digest of the signature.
+func verifyPKCS7(sig, msg []byte) (*x509.Certificate, crypto.Hash, error) {
+	var p pkcs7SignedDataIn
+	if err := unmarshalDER(sig, &p); err != nil {
+		return nil, 0, fmt.Errorf("bad PKCS#7 signature: %v", err)
+	}
+	if !p.ContentType.Equal(oidSignedData) {
+		return nil, 0, fmt.Errorf("PKCS#7 content type %v, want signed data", p.ContentType)
+	}
+	certs, err := x509.ParseCertificates(p.Content.Certificates.Bytes)
+	if err != nil {
+		return nil, 0, fmt.Errorf("bad certificate: %v", err)
+	}
+	if len(p.Content.SignerInfos) != 1 {
+		return nil, 0, fmt.Errorf("%d signers, want 1", len(p.Content.SignerInfos))
+	}
+	si := p.Content.SignerInfos[0]
+
+	var cert *x509.Certificate
+	for _, c := range certs {
+		if bytes.Equal(c.RawIssuer, si.IssuerAndSerialNumber.Issuer.FullBytes) && c.SerialNumber.Cmp(si.IssuerAndSerialNumber.SerialNumber) == 0 {
+			cert = c
+		}
+	}
+	if cert == nil {
+		return nil, 0, errors.New("no certificate of the signer")
+	}
+	var hash crypto.Hash
+	for h, oid := range oidHashes {
+		if si.DigestAlgorithm.Algorithm.Equal(oid) {
+			hash = h
+		}
+	}
+	if hash == 0 {
+		return nil, 0, fmt.Errorf("unsupported digest %v", si.DigestAlgorithm.Algorithm)
+	}
+
+	h := hash.New()
+	h.Write(msg)
+	digest := h.Sum(nil)
+	if len(si.AuthenticatedAttributes.Bytes) > 0 {
+		// The signature is made of the attributes, as a SET, which
+		// hold the digest of msg.
+		var attrs []pkcs7Attribute
+		if _, err := asn1.UnmarshalWithParams(si.AuthenticatedAttributes.FullBytes, &attrs, "set,tag:0"); err != nil {
+			return nil, 0, fmt.Errorf("bad signed attributes: %v", err)
+		}
+		found := false
+		for _, a := range attrs {
+			if a.Type.Equal(oidMessageDigest) {
+				var d []byte
+				if _, err := asn1.Unmarshal(a.Values.Bytes, &d); err != nil || !bytes.Equal(d, digest) {
+					return nil, 0, errors.New("digest mismatch")
+				}
+				found = true
+			}
+		}
+		if !found {
+			return nil, 0, errors.New("no message digest in signed attributes")
+		}
+		set := append([]byte(nil), si.AuthenticatedAttributes.FullBytes...)
+		set[0] = 0x31 // SET
+		h := hash.New()
+		h.Write(set)
+		digest = h.Sum(nil)
+	}
+
+	switch pub := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		err = rsa.VerifyPKCS1v15(pub, hash, digest, si.EncryptedDigest)
+	case *ecdsa.PublicKey:
+		if !verifyECDSA(pub, digest, si.EncryptedDigest) {
+			err = errors.New("verification error")
+		}
+	default:
+		err = fmt.Errorf("unsupported %T key", pub)
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("bad signature: %v", err)
+	}
+	return cert, hash, nil
+}
+
+// verifyECDSA reports whether sig, an ASN.1 encoded ECDSA signature,
+// is a valid signature of hashed by the key pub.
+func verifyECDSA(pub *ecdsa.PublicKey, hashed, sig []byte) bool {
+	var rs struct{ R, S *big.Int }
+	if rest, err := asn1.Unmarshal(sig, &rs); err != nil || len(rest) > 0 {
+		return false
+	}
+	return ecdsa.Verify(pub, hashed, rs.R, rs.S)
+}
+
 type pkcs7SignedData struct {
 	ContentType asn1.ObjectIdentifier
 	Content     signedData `asn1:"tag:0,explicit"`
@@ -92,8 +205,8 @@ type signedData struct {
 	Version          int
 	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
 	ContentInfo      contentInfo
-	Certificates     certificate  `asn1:"tag0,explicit"`
-	SignerInfos      []signerInfo `asn1:"set"`
+	Certificates     asn1.RawValue // [0] IMPLICIT, a single certificate
+	SignerInfos      []signerInfo  `asn1:"set"`
 }
 
 type contentInfo struct {
@@ -101,47 +214,46 @@ type contentInfo struct {
 	// Content is optional in PKCS#7 and not provided here.
 }
 
-// certificate is defined in rfc2459, section 4.1.
-type certificate struct {
-	TBSCertificate     tbsCertificate
-	SignatureAlgorithm pkix.AlgorithmIdentifier
-	SignatureValue     asn1.BitString
-}
-
-// tbsCertificate is defined in rfc2459, section 4.1.
-type tbsCertificate struct {
-	Version      int `asn1:"tag:0,default:2,explicit"`
-	SerialNumber int
-	Signature    pkix.AlgorithmIdentifier
-	Issuer       pkix.RDNSequence // pkix.Name
-	Validity     validity
-	Subject      pkix.RDNSequence // pkix.Name
-	SubjectPKI   subjectPublicKeyInfo
-}
-
-// validity is defined in rfc2459, section 4.1.
-type validity struct {
-	NotBefore time.Time
-	NotAfter  time.Time
+type signerInfo struct {
+	Version                   int
+	IssuerAndSerialNumber     issuerAndSerialNumber
+	DigestAlgorithm           pkix.AlgorithmIdentifier
+	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedDigest           []byte
 }
 
-// subjectPublicKeyInfo is defined in rfc2459, section 4.1.
-type subjectPublicKeyInfo struct {
-	Algorithm        pkix.AlgorithmIdentifier
-	SubjectPublicKey asn1.BitString
+// pkcs7SignedDataIn is the signed data read by verifyPKCS7, which may
+// have more than the one certificate and no signed attributes written
+// by signPKCS7.
+type pkcs7SignedDataIn struct {
+	ContentType asn1.ObjectIdentifier
+	Content     struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		ContentInfo      asn1.RawValue
+		Certificates     asn1.RawValue  `asn1:"optional,tag:0"`
+		CRLs             asn1.RawValue  `asn1:"optional,tag:1"`
+		SignerInfos      []signerInfoIn `asn1:"set"`
+	} `asn1:"tag:0,explicit"`
 }
 
-type signerInfo struct {
+type signerInfoIn struct {
 	Version                   int
 	IssuerAndSerialNumber     issuerAndSerialNumber
 	DigestAlgorithm           pkix.AlgorithmIdentifier
+	AuthenticatedAttributes   asn1.RawValue `asn1:"optional,tag:0"`
 	DigestEncryptionAlgorithm pkix.AlgorithmIdentifier
 	EncryptedDigest           []byte
 }
 
+type pkcs7Attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue `asn1:"set"`
+}
+
 type issuerAndSerialNumber struct {
-	Issuer       pkix.RDNSequence // pkix.Name
-	SerialNumber int
+	Issuer       asn1.RawValue // pkix.Name
+	SerialNumber *big.Int
 }
 
 // Various ASN.1 Object Identifies, mostly from rfc3852.
@@ -151,4 +263,11 @@ var (
 	oidSignedData    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
 	oidSHA1          = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
 	oidRSAEncryption = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
 )
+
+// oidHashes are the digest algorithms of the PKCS#7 signatures.
+var oidHashes = map[crypto.Hash]asn1.ObjectIdentifier{
+	crypto.SHA1:   oidSHA1,
+	crypto.SHA256: oidSHA256,
+}
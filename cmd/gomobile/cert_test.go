@@ -5,9 +5,15 @@
 package main
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/asn1"
 	"encoding/pem"
+	"math/big"
 	"io/ioutil"
 	"os"
 	"os/exec"
@@ -26,7 +32,7 @@ func TestSignPKCS7(t *testing.T) {
 	}
 
 	content := "Hello world,\nThis is signed."
-	cert, err := signPKCS7(rand.Reader, privKey, []byte(content))
+	cert, err := signPKCS7(rand.Reader, privKey, nil, crypto.SHA1, []byte(content))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -100,3 +106,30 @@ cSL5bhq0N5XHK77sscxW9vXjG0LJMXmFZPp9F6aV6ejkMIXyJ/Yz/EqeaJFwilTq
 Mc6xR47qkdzu0dQ1aPm4XD7AWDtIvPo/GG2DKOucLBbQc2cOWtKS
 -----END RSA PRIVATE KEY-----
 `
+
+func TestVerifyECDSA(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := sha256.Sum256([]byte("Hello world"))
+	hashed := h[:]
+	r, s, err := ecdsa.Sign(rand.Reader, priv, hashed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verifyECDSA(&priv.PublicKey, hashed, sig) {
+		t.Error("valid signature not verified")
+	}
+	bad := append([]byte(nil), sig...)
+	bad[len(bad)-1] ^= 1
+	for _, sig := range [][]byte{bad, append(sig, 0), sig[:len(sig)-1], nil} {
+		if verifyECDSA(&priv.PublicKey, hashed, sig) {
+			t.Errorf("bad signature %x verified", sig)
+		}
+	}
+}
@@ -28,6 +28,7 @@ Commands:
 	build       compile android APK and iOS app
 	init        install android compiler toolchain
 	install     compile android APK and install on device
+	verify      verify android APKs and AARs
 
 Use 'gomobile help [command]' for more information about that command.
 
@@ -38,20 +39,29 @@ Build a shared library for android APK and iOS app
 
 Usage:
 
-	gomobile bind [-target android|ios] [-o output] [build flags] [package]
+	gomobile bind [-target android|ios|android/arch,...] [-javapkg package] [-prefix name] [-apidiff file] [-skip] [-o output] [build flags] [package...]
 
-Bind generates language bindings for the package named by the import
-path, and compiles a library for the named target system.
+Bind generates language bindings for the packages named by the import
+paths, and compiles a library for the named target system.
+
+Several packages can be bound into a single library. The bindings of
+each package may refer to the types of the others. The packages must
+have distinct names.
 
 The -target flag takes a target system name, either android (the
-default) or ios.
+default) or ios. For android, it may instead list the architectures
+to build, separated by commas: android/arm, android/arm64, android/386
+and android/amd64. The name android alone builds android/arm. The
+toolchains of the other architectures are installed by 'gomobile init
+-arch'.
 
 For -target android, the bind command produces an AAR (Android ARchive)
 file that archives the precompiled Java API stub classes, the compiled
-shared libraries, and all asset files in the /assets subdirectory under
-the package directory. The output is named '<package_name>.aar' by
-default. This AAR file is commonly used for binary distribution of an
-Android library project and most Android IDEs support AAR import. For
+shared libraries of each architecture, and all asset files in the
+/assets subdirectory under the package directories. The output is
+named '<package_name>.aar' by default, after the first package. This
+AAR file is commonly used for binary distribution of an Android
+library project and most Android IDEs support AAR import. For
 example, in Android Studio (1.2+), an AAR file can be imported using
 the module import wizard (File > New > New Module > Import .JAR or
 .AAR package), and setting it as a new dependency
@@ -63,6 +73,25 @@ to the path to Android SDK.
 For -target ios, gomobile must be run on an OS X machine with Xcode
 installed. Support is not complete.
 
+The -javapkg and -prefix flags set the names of the generated
+declarations, so that several bound libraries can be used by the
+same app. For -target android, -javapkg sets the prefix of the Java
+packages of the bound packages, go by default: with
+-javapkg=com.example.sdk, the class of package api is
+com.example.sdk.api.Api. For -target ios, -prefix sets the prefix of
+the Objective-C names, Go by default: with -prefix=EXS, a function New
+of package api is EXSApiNew, declared in EXSApi.h.
+
+The -apidiff flag names a description of the API of a previous
+version of the bound packages, written by 'gobind -lang=api'. The bind
+command prints the differences with the API of the packages, and
+fails without building the library if they break the API: removed
+declarations, changed types or methods added to interfaces.
+
+The exported declarations that cannot be bound are reported before
+building. The -skip flag omits them with a warning instead, as
+'gobind -skip' does.
+
 The -v flag provides verbose output, including the list of packages built.
 
 The build flags -a, -i, -n, -x, and -tags are shared with the build command.
@@ -73,25 +102,61 @@ Compile android APK and iOS app
 
 Usage:
 
-	gomobile build [-target android|ios] [-o output] [build flags] [package]
+	gomobile build [-target android|ios|android/arch,...] [-keystore file [-keyalias name] [-storepass-env var] [-keypass-env var]] [-signschemes list] [-o output] [build flags] [package]
 
 Build compiles and encodes the app named by the import path.
 
 The named package must define a main function.
 
 The -target flag takes a target system name, either android (the
-default) or ios.
+default) or ios. For android, it may instead list the architectures
+to build, separated by commas: android/arm, android/arm64, android/386
+and android/amd64. The name android alone builds android/arm. The
+toolchains of the other architectures are installed by 'gomobile init
+-arch'.
 
 For -target android, if an AndroidManifest.xml is defined in the
 package directory, it is added to the APK output. Otherwise, a default
-manifest is generated.
+manifest is generated, with the package name of the app derived from
+the import path, such as org.golang.x.mobile.example.basic for
+golang.org/x/mobile/example/basic. The APK holds a shared library for
+each architecture, in lib/<abi>, such as lib/x86 for android/386 and
+lib/armeabi for android/arm.
 
 For -target ios, gomobile must be run on an OS X machine with Xcode
 installed. Support is not complete.
 
+The APK is signed with a debug key, unless the -keystore flag names
+the keystore holding the release key. The keystore is a JKS or PKCS#12
+keystore, as written by keytool, or a PEM file holding the private key
+and its certificate. The certificate is required: it identifies the app,
+whose updates must be signed with the same one. The -keyalias flag
+chooses the key of a keystore holding several. The -storepass-env flag
+names the environment variable holding the password of the keystore,
+and -keypass-env the one holding the password of the key, the password
+of the keystore by default. The password of a PEM key is its key
+password.
+
+The -signschemes flag lists the signature schemes of the APK, separated
+by commas: v1, the JAR signature checked by all the Android versions,
+v1-sha256, the same with SHA-256 digests for Android 4.3 and later, and
+the APK Signature Schemes v2 and v3, checked instead by Android 7 and 9
+and later. The default is v1,v2.
+
 If the package directory contains an assets subdirectory, its contents
 are copied into the output.
 
+If the package directory contains a res subdirectory, its resources are
+compiled into the resources.arsc table of the APK. The XML files of
+res/values hold strings, colors, dimensions, booleans and integers, such
+as the name of the app, and res/values-fr those in French. The files of
+the other subdirectories, such as res/mipmap-hdpi/ic_launcher.png, are
+resources of their type, for screens of a density. The languages,
+regions, densities and minimum SDK versions are the supported
+qualifiers. AndroidManifest.xml refers to the resources as
+@string/app_name and @mipmap/ic_launcher; the default manifest uses
+those two if they exist.
+
 The -o flag specifies the output file name. If not specified, the
 output file name depends on the package built.
 
@@ -105,7 +170,7 @@ Install android compiler toolchain
 
 Usage:
 
-	gomobile init [-u]
+	gomobile init [-u] [-arch list]
 
 Init downloads and installs the Android C++ compiler toolchain.
 
@@ -116,19 +181,56 @@ it skips download and uses the existing toolchain.
 The -u option forces download and installation of the new toolchain
 even when the toolchain exists.
 
+The -arch option lists the Android architectures to install the
+toolchain for, separated by commas: arm (the default), arm64, 386 and
+amd64. The stripped-down NDK hosted for gomobile only holds the arm
+toolchain, so for the others init downloads the full Android NDK,
+about 400MB.
+
 
 Compile android APK and install on device
 
 Usage:
 
-	gomobile install [-target android] [build flags] [package]
+	gomobile install [-target android|android/arch,...] [-keystore file [-keyalias name] [-storepass-env var] [-keypass-env var]] [-signschemes list] [build flags] [package]
 
 Install compiles and installs the app named by the import path on the
 attached mobile device.
 
-Only -target android is supported. The 'adb' tool must be on the PATH.
+Only -target android is supported, optionally limited to some
+architectures as for the build command. The 'adb' tool must be on
+the PATH. The -keystore, -keyalias, -storepass-env, -keypass-env and
+-signschemes flags choose the signing key and signatures as for the
+build command.
 
 The build flags -a, -i, -n, -x, and -tags are shared with the build command.
 For documentation, see 'go help build'.
+
+
+Verify android APKs and AARs
+
+Usage:
+
+	gomobile verify [-json] [-v] file.apk|file.aar...
+
+Verify checks the APKs and AARs built by the build and bind commands,
+without the Android SDK.
+
+For an APK, it checks that the ZIP entries are intact and that the
+uncompressed ones are 4-byte aligned. It recomputes the digests of the
+JAR signature (v1) in META-INF/MANIFEST.MF and the .SF file and verifies
+its PKCS#7 signature with the embedded certificate. It verifies the APK
+Signature Schemes v2 and v3 if present, decodes the resource table
+resources.arsc, checking that the files of its resources are present,
+and decodes the binary AndroidManifest.xml, naming the resources it
+refers to.
+
+For an AAR, it checks that the ZIP entries are intact and that the
+archive holds an AndroidManifest.xml, a classes.jar and the shared
+libraries of the architectures.
+
+Verify prints a report of the checks of each file, in JSON with the
+-json flag. The -v flag adds the decoded AndroidManifest.xml. Verify
+exits with a non-zero status if a check fails.
 */
 package main
@@ -18,7 +18,7 @@ var (
 	gomobilepath string // $GOPATH/pkg/gomobile
 	ndkccpath    string // $GOPATH/pkg/gomobile/android-{{.NDK}}
 
-	androidArmEnv  []string
+	androidEnv     map[string][]string // GOARCH -> environment
 	darwinArmEnv   []string
 	darwinArm64Env []string
 	darwin386Env   []string
@@ -90,19 +90,26 @@ func envInit() (err error) {
 
 	// TODO(crawshaw): Remove ndkccpath global.
 	ndkccpath = filepath.Join(gomobilepath, "android-"+ndkVersion)
-	ndkccbin := filepath.Join(ndkccpath, "arm", "bin")
 
 	exe := ""
 	if goos == "windows" {
 		exe = ".exe"
 	}
-	androidArmEnv = []string{
-		"GOOS=android",
-		"GOARCH=arm",
-		"GOARM=7",
-		"CC=" + filepath.Join(ndkccbin, "arm-linux-androideabi-gcc"+exe),
-		"CXX=" + filepath.Join(ndkccbin, "arm-linux-androideabi-g++"+exe),
-		"CGO_ENABLED=1",
+	androidEnv = make(map[string][]string)
+	for _, tc := range ndkToolchains {
+		ndkccbin := filepath.Join(ndkccpath, tc.arch, "bin")
+		env := []string{
+			"GOOS=android",
+			"GOARCH=" + tc.arch,
+		}
+		if tc.arch == "arm" {
+			env = append(env, "GOARM=7")
+		}
+		androidEnv[tc.arch] = append(env,
+			"CC="+filepath.Join(ndkccbin, tc.toolPrefix+"-gcc"+exe),
+			"CXX="+filepath.Join(ndkccbin, tc.toolPrefix+"-g++"+exe),
+			"CGO_ENABLED=1",
+		)
 	}
 
 	if runtime.GOOS != "darwin" {
@@ -159,6 +166,80 @@ func envInit() (err error) {
 	return nil
 }
 
+// An ndkToolchain is the NDK C compiler toolchain of an Android
+// architecture. gomobile init installs it in
+// $GOPATH/pkg/gomobile/android-{{.NDK}}/<arch>.
+type ndkToolchain struct {
+	arch       string // GOARCH
+	abi        string // Android ABI, naming the directories of its libraries
+	platform   string // NDK platform of the sysroot, such as android-15
+	ndkArch    string // NDK name of the architecture, such as x86
+	gcc        string // NDK directory of the toolchain
+	toolPrefix string // prefix of the names of the tools
+}
+
+// ndkToolchains are the toolchains of the supported Android
+// architectures. The 64-bit architectures need platform android-21.
+var ndkToolchains = []ndkToolchain{
+	{
+		arch:       "arm",
+		abi:        "armeabi-v7a",
+		platform:   "android-15",
+		ndkArch:    "arm",
+		gcc:        "arm-linux-androideabi-4.8",
+		toolPrefix: "arm-linux-androideabi",
+	},
+	{
+		arch:       "arm64",
+		abi:        "arm64-v8a",
+		platform:   "android-21",
+		ndkArch:    "arm64",
+		gcc:        "aarch64-linux-android-4.9",
+		toolPrefix: "aarch64-linux-android",
+	},
+	{
+		arch:       "386",
+		abi:        "x86",
+		platform:   "android-15",
+		ndkArch:    "x86",
+		gcc:        "x86-4.8",
+		toolPrefix: "i686-linux-android",
+	},
+	{
+		arch:       "amd64",
+		abi:        "x86_64",
+		platform:   "android-21",
+		ndkArch:    "x86_64",
+		gcc:        "x86_64-4.9",
+		toolPrefix: "x86_64-linux-android",
+	},
+}
+
+// checkNDKToolchains reports an error if the toolchain of one of the
+// Android architectures archs is not installed.
+func checkNDKToolchains(archs []string) error {
+	if buildN {
+		return nil
+	}
+	for _, arch := range archs {
+		if _, err := os.Stat(filepath.Join(ndkccpath, arch)); err != nil {
+			return fmt.Errorf("toolchain for android/%s not installed, run `gomobile init -arch=%s`", arch, strings.Join(archs, ","))
+		}
+	}
+	return nil
+}
+
+// ndkToolchainFor returns the toolchain of the Android architecture
+// goarch.
+func ndkToolchainFor(goarch string) (ndkToolchain, bool) {
+	for _, tc := range ndkToolchains {
+		if tc.arch == goarch {
+			return tc, true
+		}
+	}
+	return ndkToolchain{}, false
+}
+
 func envClang(sdkName string) (clang, cflags string, err error) {
 	if buildN {
 		return "clang-" + sdkName, "-isysroot=" + sdkName, nil
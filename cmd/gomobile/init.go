@@ -4,8 +4,6 @@
 
 package main
 
-// TODO(crawshaw): android/{386,arm64}
-
 import (
 	"archive/tar"
 	"bytes"
@@ -53,7 +51,7 @@ func init() {
 var cmdInit = &command{
 	run:   runInit,
 	Name:  "init",
-	Usage: "[-u]",
+	Usage: "[-u] [-arch list]",
 	Short: "install android compiler toolchain",
 	Long: `
 Init downloads and installs the Android C++ compiler toolchain.
@@ -64,16 +62,52 @@ it skips download and uses the existing toolchain.
 
 The -u option forces download and installation of the new toolchain
 even when the toolchain exists.
+
+The -arch option lists the Android architectures to install the
+toolchain for, separated by commas: arm (the default), arm64, 386 and
+amd64. The stripped-down NDK hosted for gomobile only holds the arm
+toolchain, so for the others init downloads the full Android NDK,
+about 400MB.
 `,
 }
 
-var initU bool // -u
+var (
+	initU     bool   // -u
+	initArchs string // -arch
+)
 
 func init() {
 	cmdInit.flag.BoolVar(&initU, "u", false, "force toolchain download")
+	cmdInit.flag.StringVar(&initArchs, "arch", "arm", "Android architectures to install")
+}
+
+// initToolchains are the NDK toolchains installed by gomobile init,
+// set from the -arch flag.
+var initToolchains []ndkToolchain
+
+func parseInitArchs(archs string) ([]ndkToolchain, error) {
+	var tcs []ndkToolchain
+	seen := make(map[string]bool)
+	for _, arch := range strings.Split(archs, ",") {
+		tc, ok := ndkToolchainFor(arch)
+		if !ok {
+			return nil, fmt.Errorf("unsupported -arch %q", arch)
+		}
+		if !seen[arch] {
+			seen[arch] = true
+			tcs = append(tcs, tc)
+		}
+	}
+	return tcs, nil
 }
 
 func runInit(cmd *command) error {
+	var err error
+	initToolchains, err = parseInitArchs(initArchs)
+	if err != nil {
+		return err
+	}
+
 	version, err := goVersion()
 	if err != nil {
 		return fmt.Errorf("%v: %s", err, version)
@@ -122,8 +156,10 @@ func runInit(cmd *command) error {
 
 	// Install standard libraries for cross compilers.
 	start := time.Now()
-	if err := installStd(androidArmEnv); err != nil {
-		return err
+	for _, tc := range initToolchains {
+		if err := installStd(androidEnv[tc.arch]); err != nil {
+			return err
+		}
 	}
 	if err := installDarwin(); err != nil {
 		return err
@@ -242,6 +278,16 @@ func symlink(src, dst string) error {
 	return os.Symlink(src, dst)
 }
 
+func copyAll(dst, src string) error {
+	if buildX {
+		printcmd("cp -R %s %s", src, dst)
+	}
+	if buildN {
+		return nil
+	}
+	return doCopyAll(dst, src)
+}
+
 func rm(name string) error {
 	if buildX {
 		printcmd("rm %s", name)
@@ -278,10 +324,13 @@ func fetchOpenAL() error {
 	if err := extract("openal", archive); err != nil {
 		return err
 	}
-	dst := filepath.Join(ndkccpath, "arm", "sysroot", "usr", "include")
-	src := filepath.Join(tmpdir, "openal", "include")
-	if err := move(dst, src, "AL"); err != nil {
-		return err
+	// The headers are installed in the sysroot of each toolchain.
+	src := filepath.Join(tmpdir, "openal", "include", "AL")
+	for _, tc := range initToolchains {
+		dst := filepath.Join(ndkccpath, tc.arch, "sysroot", "usr", "include", "AL")
+		if err := copyAll(dst, src); err != nil {
+			return err
+		}
 	}
 	libDst := filepath.Join(ndkccpath, "openal")
 	libSrc := filepath.Join(tmpdir, "openal")
@@ -344,7 +393,7 @@ func extract(dst, src string) error {
 }
 
 func fetchNDK() error {
-	if useStrippedNDK {
+	if useStrippedNDK && strippedNDKHolds(initToolchains) {
 		if err := fetchStrippedNDK(); err != nil {
 			return err
 		}
@@ -354,18 +403,29 @@ func fetchNDK() error {
 		}
 	}
 
-	dst := filepath.Join(ndkccpath, "arm")
+	for _, tc := range initToolchains {
+		if err := installNDKToolchain(tc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// installNDKToolchain moves the compiler and the sysroot of tc from the
+// extracted NDK to its directory in ndkccpath.
+func installNDKToolchain(tc ndkToolchain) error {
+	dst := filepath.Join(ndkccpath, tc.arch)
 	dstSysroot := filepath.Join(dst, "sysroot/usr")
 	if err := mkdir(dstSysroot); err != nil {
 		return err
 	}
 
-	srcSysroot := filepath.Join(tmpdir, "android-"+ndkVersion+"/platforms/android-15/arch-arm/usr")
+	srcSysroot := filepath.Join(tmpdir, "android-"+ndkVersion+"/platforms/"+tc.platform+"/arch-"+tc.ndkArch+"/usr")
 	if err := move(dstSysroot, srcSysroot, "include", "lib"); err != nil {
 		return err
 	}
 
-	ndkpath := filepath.Join(tmpdir, "android-"+ndkVersion+"/toolchains/arm-linux-androideabi-4.8/prebuilt")
+	ndkpath := filepath.Join(tmpdir, "android-"+ndkVersion+"/toolchains/"+tc.gcc+"/prebuilt")
 	if goos == "windows" && ndkarch == "x86" {
 		ndkpath = filepath.Join(ndkpath, "windows")
 	} else {
@@ -375,7 +435,7 @@ func fetchNDK() error {
 		return err
 	}
 
-	linkpath := filepath.Join(dst, "arm-linux-androideabi/bin")
+	linkpath := filepath.Join(dst, tc.toolPrefix+"/bin")
 	if err := mkdir(linkpath); err != nil {
 		return err
 	}
@@ -383,13 +443,24 @@ func fetchNDK() error {
 		if goos == "windows" {
 			name += ".exe"
 		}
-		if err := symlink(filepath.Join(dst, "bin", "arm-linux-androideabi-"+name), filepath.Join(linkpath, name)); err != nil {
+		if err := symlink(filepath.Join(dst, "bin", tc.toolPrefix+"-"+name), filepath.Join(linkpath, name)); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// strippedNDKHolds reports whether the stripped-down NDK holds the
+// toolchains tcs. It only holds the arm toolchain.
+func strippedNDKHolds(tcs []ndkToolchain) bool {
+	for _, tc := range tcs {
+		if tc.arch != "arm" {
+			return false
+		}
+	}
+	return true
+}
+
 func fetchStrippedNDK() error {
 	url := "https://dl.google.com/go/mobile/gomobile-" + ndkVersion + "-" + goos + "-" + ndkarch + ".tar.gz"
 	archive, err := fetch(url)
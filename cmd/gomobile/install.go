@@ -15,13 +15,17 @@ import (
 var cmdInstall = &command{
 	run:   runInstall,
 	Name:  "install",
-	Usage: "[-target android] [build flags] [package]",
+	Usage: "[-target android|android/arch,...] [-keystore file [-keyalias name] [-storepass-env var] [-keypass-env var]] [-signschemes list] [build flags] [package]",
 	Short: "compile android APK and install on device",
 	Long: `
 Install compiles and installs the app named by the import path on the
 attached mobile device.
 
-Only -target android is supported. The 'adb' tool must be on the PATH.
+Only -target android is supported, optionally limited to some
+architectures as for the build command. The 'adb' tool must be on
+the PATH. The -keystore, -keyalias, -storepass-env, -keypass-env and
+-signschemes flags choose the signing key and signatures as for the
+build command.
 
 The build flags -a, -i, -n, -x, and -tags are shared with the build command.
 For documentation, see 'go help build'.
@@ -29,7 +33,9 @@ For documentation, see 'go help build'.
 }
 
 func runInstall(cmd *command) error {
-	if buildTarget != "android" {
+	if targetOS, _, err := parseBuildTarget(buildTarget); err != nil {
+		return err
+	} else if targetOS != "android" {
 		return fmt.Errorf("install is not supported for -target=%s", buildTarget)
 	}
 	if err := runBuild(cmd); err != nil {
@@ -0,0 +1,373 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+// signingKey returns the key signing the APKs and its certificate.
+// The certificate is nil for the debug key, signed with a self-signed
+// certificate.
+func signingKey() (*rsa.PrivateKey, *x509.Certificate, error) {
+	if buildKeystore == "" {
+		if buildKeyAlias != "" || buildStorePassEnv != "" || buildKeyPassEnv != "" {
+			return nil, nil, errors.New("-keyalias, -storepass-env and -keypass-env require -keystore")
+		}
+		block, _ := pem.Decode([]byte(debugCert))
+		if block == nil {
+			return nil, nil, errors.New("no debug cert")
+		}
+		priv, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		return priv, nil, err
+	}
+
+	var storePass string
+	if buildStorePassEnv != "" {
+		storePass = os.Getenv(buildStorePassEnv)
+		if storePass == "" {
+			return nil, nil, fmt.Errorf("-storepass-env: environment variable %s is not set", buildStorePassEnv)
+		}
+	}
+	keyPass := storePass
+	if buildKeyPassEnv != "" {
+		keyPass = os.Getenv(buildKeyPassEnv)
+		if keyPass == "" {
+			return nil, nil, fmt.Errorf("-keypass-env: environment variable %s is not set", buildKeyPassEnv)
+		}
+	}
+	data, err := ioutil.ReadFile(buildKeystore)
+	if err != nil {
+		return nil, nil, err
+	}
+	priv, cert, err := readKeystore(data, buildKeyAlias, storePass, keyPass)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", buildKeystore, err)
+	}
+	return priv, cert, nil
+}
+
+// readKeystore returns the private key named alias in the keystore
+// data, and its certificate. The keystore is in the JKS, PKCS#12 or
+// PEM format. It is checked with the password storePass, and the key
+// is decrypted with keyPass; a PEM file only has the latter. The alias
+// may be empty if the keystore holds a single key; PEM keys have none.
+func readKeystore(data []byte, alias, storePass, keyPass string) (*rsa.PrivateKey, *x509.Certificate, error) {
+	switch {
+	case bytes.HasPrefix(data, jksMagic):
+		return readJKS(data, alias, storePass, keyPass)
+	case bytes.Contains(data, []byte("-----BEGIN ")):
+		if alias != "" {
+			return nil, nil, errors.New("PEM keys have no alias")
+		}
+		return readPEMKey(data, keyPass)
+	default:
+		return readPKCS12(data, alias, storePass, keyPass)
+	}
+}
+
+// keyAndCert returns the RSA key of the PKCS#8 or PKCS#1 key der, and
+// the certificate of its public key among the DER certificates certs.
+func keyAndCert(der []byte, certs [][]byte) (*rsa.PrivateKey, *x509.Certificate, error) {
+	var priv *rsa.PrivateKey
+	if k, err := x509.ParsePKCS8PrivateKey(der); err == nil {
+		var ok bool
+		if priv, ok = k.(*rsa.PrivateKey); !ok {
+			return nil, nil, fmt.Errorf("unsupported %T key, APKs are signed with RSA keys", k)
+		}
+	} else if priv, err = x509.ParsePKCS1PrivateKey(der); err != nil {
+		return nil, nil, fmt.Errorf("bad private key: %v", err)
+	}
+	for _, der := range certs {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, nil, fmt.Errorf("bad certificate: %v", err)
+		}
+		if pub, ok := cert.PublicKey.(*rsa.PublicKey); ok && pub.N.Cmp(priv.N) == 0 && pub.E == priv.E {
+			return priv, cert, nil
+		}
+	}
+	// A certificate made here would change with each build, and
+	// Android requires the updates of an app to keep its certificate.
+	return nil, nil, errors.New("no certificate of the private key, the keystore must hold it")
+}
+
+// readPEMKey reads a private key and its certificate from PEM blocks.
+// The key may be encrypted with password, in the PKCS#8 or in the
+// legacy OpenSSL format.
+func readPEMKey(data []byte, password string) (*rsa.PrivateKey, *x509.Certificate, error) {
+	var key []byte
+	var certs [][]byte
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "CERTIFICATE":
+			certs = append(certs, block.Bytes)
+		case "RSA PRIVATE KEY", "PRIVATE KEY":
+			key = block.Bytes
+			if x509.IsEncryptedPEMBlock(block) {
+				b, err := x509.DecryptPEMBlock(block, []byte(password))
+				if err != nil {
+					return nil, nil, fmt.Errorf("cannot decrypt private key: %v", err)
+				}
+				key = b
+			}
+		case "ENCRYPTED PRIVATE KEY":
+			var info encryptedPrivateKeyInfo
+			if err := unmarshalDER(block.Bytes, &info); err != nil {
+				return nil, nil, fmt.Errorf("bad encrypted private key: %v", err)
+			}
+			b, err := pbDecrypt(info.Algorithm, info.EncryptedData, password)
+			if err != nil {
+				return nil, nil, fmt.Errorf("cannot decrypt private key: %v", err)
+			}
+			key = b
+		}
+	}
+	if key == nil {
+		return nil, nil, errors.New("no private key")
+	}
+	return keyAndCert(key, certs)
+}
+
+// encryptedPrivateKeyInfo is defined in rfc5208, section 6.
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+// unmarshalDER unmarshals the single DER value der into val.
+func unmarshalDER(der []byte, val interface{}) error {
+	rest, err := asn1.Unmarshal(der, val)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return errors.New("trailing data")
+	}
+	return nil
+}
+
+// A JKS keystore is the proprietary keystore format of the Java
+// keytool, made of big-endian values:
+//
+//	magic, version, number of entries (uint32)
+//	entries
+//	SHA-1 digest of the password, "Mighty Aphrodite" and the above
+//
+// Strings are written with a uint16 length, and are UTF-8 for the
+// characters used in aliases. An entry is:
+//
+//	tag (uint32): 1 for a private key, 2 for a trusted certificate
+//	alias (string)
+//	creation time (uint64)
+//	private key: the length (uint32) and the DER encoding of its
+//	    EncryptedPrivateKeyInfo, then the number of certificates
+//	    of its chain (uint32)
+//	each certificate: its type (string, version 2 only), and the
+//	    length (uint32) and DER encoding of the certificate
+//
+// The password is used as UTF-16 for the digest and the keys.
+var jksMagic = []byte{0xfe, 0xed, 0xfe, 0xed}
+
+// oidJKSKeyProtector identifies the encryption of the JKS keys.
+var oidJKSKeyProtector = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 42, 2, 17, 1, 1}
+
+// readJKS reads the private key named alias, and its certificate, from
+// a JKS keystore of password storePass. The key is decrypted with
+// keyPass.
+func readJKS(data []byte, alias, storePass, keyPass string) (*rsa.PrivateKey, *x509.Certificate, error) {
+	pw := utf16BE(storePass)
+	if len(data) < 12+sha1.Size {
+		return nil, nil, errors.New("truncated JKS keystore")
+	}
+	body, digest := data[:len(data)-sha1.Size], data[len(data)-sha1.Size:]
+	h := sha1.New()
+	h.Write(pw)
+	h.Write([]byte("Mighty Aphrodite"))
+	h.Write(body)
+	if !bytes.Equal(h.Sum(nil), digest) {
+		return nil, nil, errors.New("keystore was tampered with, or password was incorrect")
+	}
+
+	r := &jksReader{b: body[4:]}
+	version := r.uint32()
+	if version != 1 && version != 2 {
+		return nil, nil, fmt.Errorf("unsupported JKS version %d", version)
+	}
+	type keyEntry struct {
+		alias string
+		key   []byte
+		certs [][]byte
+	}
+	var keys []keyEntry
+	for n := r.uint32(); n > 0 && r.err == nil; n-- {
+		tag := r.uint32()
+		e := keyEntry{alias: r.string()}
+		r.next(8) // creation time
+		switch tag {
+		case 1:
+			e.key = r.bytes()
+			for n := r.uint32(); n > 0 && r.err == nil; n-- {
+				if version == 2 {
+					r.string()
+				}
+				e.certs = append(e.certs, r.bytes())
+			}
+			keys = append(keys, e)
+		case 2:
+			if version == 2 {
+				r.string()
+			}
+			r.bytes()
+		default:
+			return nil, nil, fmt.Errorf("unsupported JKS entry type %d", tag)
+		}
+	}
+	if r.err != nil {
+		return nil, nil, r.err
+	}
+
+	var aliases []string
+	for _, e := range keys {
+		aliases = append(aliases, e.alias)
+	}
+	i, err := chooseAlias(aliases, alias)
+	if err != nil {
+		return nil, nil, err
+	}
+	e := keys[i]
+	var info encryptedPrivateKeyInfo
+	if err := unmarshalDER(e.key, &info); err != nil {
+		return nil, nil, fmt.Errorf("bad private key %q: %v", e.alias, err)
+	}
+	if !info.Algorithm.Algorithm.Equal(oidJKSKeyProtector) {
+		return nil, nil, fmt.Errorf("unsupported encryption %v of private key %q", info.Algorithm.Algorithm, e.alias)
+	}
+	key, err := jksDecryptKey(info.EncryptedData, utf16BE(keyPass))
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot decrypt private key %q: %v", e.alias, err)
+	}
+	return keyAndCert(key, e.certs)
+}
+
+// jksDecryptKey decrypts a private key encrypted by the JKS key
+// protector: a salt, the key XORed with a stream of SHA-1 digests of
+// the password and the previous digest, starting with the salt, and
+// the SHA-1 digest of the password and the key.
+func jksDecryptKey(data, pw []byte) ([]byte, error) {
+	if len(data) < 2*sha1.Size {
+		return nil, errors.New("truncated key")
+	}
+	salt := data[:sha1.Size]
+	enc := data[sha1.Size : len(data)-sha1.Size]
+	check := data[len(data)-sha1.Size:]
+
+	key := make([]byte, len(enc))
+	digest := salt
+	for i := range enc {
+		if i%sha1.Size == 0 {
+			h := sha1.New()
+			h.Write(pw)
+			h.Write(digest)
+			digest = h.Sum(nil)
+		}
+		key[i] = enc[i] ^ digest[i%sha1.Size]
+	}
+
+	h := sha1.New()
+	h.Write(pw)
+	h.Write(key)
+	if !bytes.Equal(h.Sum(nil), check) {
+		return nil, errors.New("wrong password")
+	}
+	return key, nil
+}
+
+// jksReader reads the values of a JKS keystore. After an error, it
+// returns zero values and keeps the error in err.
+type jksReader struct {
+	b   []byte
+	err error
+}
+
+func (r *jksReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(r.b) {
+		r.err = errors.New("truncated JKS keystore")
+		return nil
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b
+}
+
+func (r *jksReader) uint32() uint32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (r *jksReader) string() string {
+	b := r.next(2)
+	if b == nil {
+		return ""
+	}
+	return string(r.next(int(binary.BigEndian.Uint16(b))))
+}
+
+func (r *jksReader) bytes() []byte {
+	return r.next(int(r.uint32()))
+}
+
+// chooseAlias returns the index of alias in the aliases of the keys of
+// a keystore. An empty alias chooses the only key. Aliases are case
+// insensitive, as keytool lowers them.
+func chooseAlias(aliases []string, alias string) (int, error) {
+	switch {
+	case len(aliases) == 0:
+		return 0, errors.New("no private key")
+	case alias == "" && len(aliases) == 1:
+		return 0, nil
+	case alias == "":
+		return 0, fmt.Errorf("several private keys, choose one with -keyalias: %s", strings.Join(aliases, ", "))
+	}
+	for i, a := range aliases {
+		if strings.EqualFold(a, alias) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("no private key %q, the keys are: %s", alias, strings.Join(aliases, ", "))
+}
+
+// utf16BE returns s in UTF-16, big-endian.
+func utf16BE(s string) []byte {
+	var b []byte
+	for _, c := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(c>>8), byte(c))
+	}
+	return b
+}
@@ -0,0 +1,227 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The keystores of testdata hold the same RSA key and certificate,
+// made by:
+//
+//	openssl req -x509 -newkey rsa:1024 -nodes -keyout key.pem -out cert.pem \
+//		-days 36500 -subj "/CN=Example Release/O=Example"
+//
+// release.pem holds the key encrypted with PBES2 and AES-256 and the
+// certificate. release.p12 and release-legacy.p12 were exported by
+// openssl pkcs12 -export -name release, with and without -legacy.
+// release.jks holds the key twice, as release and upload. The password
+// of all is storepass.
+var keystoreTests = []struct {
+	file  string
+	alias string
+	err   string
+}{
+	{file: "release.pem"},
+	{file: "release.p12"},
+	{file: "release.p12", alias: "release"},
+	{file: "release-legacy.p12"},
+	{file: "release.jks", alias: "release"},
+	{file: "release.jks", alias: "Upload"},
+	{file: "release.jks", err: "several private keys"},
+	{file: "release.jks", alias: "debug", err: `no private key "debug"`},
+	{file: "release.p12", alias: "debug", err: `no private key "debug"`},
+	{file: "release.pem", alias: "release", err: "PEM keys have no alias"},
+}
+
+func TestReadKeystore(t *testing.T) {
+	for _, tc := range keystoreTests {
+		data, err := ioutil.ReadFile(filepath.Join("testdata", tc.file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		priv, cert, err := readKeystore(data, tc.alias, "storepass", "storepass")
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%s %q: got error %v, want %q", tc.file, tc.alias, err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s %q: %v", tc.file, tc.alias, err)
+			continue
+		}
+		if got, want := cert.Subject.CommonName, "Example Release"; got != want {
+			t.Errorf("%s %q: certificate subject %q, want %q", tc.file, tc.alias, got, want)
+		}
+		if err := priv.Validate(); err != nil {
+			t.Errorf("%s %q: %v", tc.file, tc.alias, err)
+		}
+
+		// The other passwords are wrong.
+		if _, _, err := readKeystore(data, tc.alias, "password", "password"); err == nil {
+			t.Errorf("%s %q: no error with a wrong password", tc.file, tc.alias)
+		}
+	}
+}
+
+// jksWithKeyPass returns the JKS keystore data of password storePass
+// with its keys encrypted with keyPass, as by keytool -keypass.
+func jksWithKeyPass(t *testing.T, data []byte, storePass, keyPass string) []byte {
+	body := append([]byte(nil), data[:len(data)-sha1.Size]...)
+	r := &jksReader{b: body[8:]} // after the magic and version 2
+	for n := r.uint32(); n > 0 && r.err == nil; n-- {
+		if tag := r.uint32(); tag != 1 {
+			t.Fatalf("JKS entry type %d, want private keys", tag)
+		}
+		r.string() // alias
+		r.next(8)  // creation time
+		key := r.bytes()
+		var info encryptedPrivateKeyInfo
+		if err := unmarshalDER(key, &info); err != nil {
+			t.Fatal(err)
+		}
+		enc := info.EncryptedData
+		plain, err := jksDecryptKey(enc, utf16BE(storePass))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The key protector XORs the key with a stream depending
+		// on the salt and the password only.
+		pw := utf16BE(keyPass)
+		salt, reenc := enc[:sha1.Size], make([]byte, 0, len(enc))
+		reenc = append(reenc, salt...)
+		digest := salt
+		for i := range plain {
+			if i%sha1.Size == 0 {
+				h := sha1.New()
+				h.Write(pw)
+				h.Write(digest)
+				digest = h.Sum(nil)
+			}
+			reenc = append(reenc, plain[i]^digest[i%sha1.Size])
+		}
+		h := sha1.New()
+		h.Write(pw)
+		h.Write(plain)
+		reenc = h.Sum(reenc)
+		copy(key[bytes.Index(key, enc):], reenc)
+		for n := r.uint32(); n > 0 && r.err == nil; n-- {
+			r.string() // certificate type
+			r.bytes()
+		}
+	}
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	h := sha1.New()
+	h.Write(utf16BE(storePass))
+	h.Write([]byte("Mighty Aphrodite"))
+	h.Write(body)
+	return h.Sum(body)
+}
+
+func TestReadKeystoreKeyPass(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/release.jks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = jksWithKeyPass(t, data, "storepass", "keypass")
+	if _, _, err := readKeystore(data, "release", "storepass", "keypass"); err != nil {
+		t.Errorf("key password: %v", err)
+	}
+	if _, _, err := readKeystore(data, "release", "storepass", "storepass"); err == nil || !strings.Contains(err.Error(), "wrong password") {
+		t.Errorf("store password for the key: got error %v, want wrong password", err)
+	}
+	if _, _, err := readKeystore(data, "release", "keypass", "keypass"); err == nil {
+		t.Error("key password for the keystore: no error")
+	}
+
+	// A PEM file has only a key password, and must hold the
+	// certificate of its key.
+	data, err = ioutil.ReadFile("testdata/release.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := readKeystore(data, "", "", "storepass"); err != nil {
+		t.Errorf("PEM key password: %v", err)
+	}
+	var key []byte
+	for rest := data; ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			key = append(key, pem.EncodeToMemory(block)...)
+		}
+	}
+	if _, _, err := readKeystore(key, "", "", "storepass"); err == nil || !strings.Contains(err.Error(), "no certificate") {
+		t.Errorf("PEM key without certificate: got error %v, want no certificate", err)
+	}
+}
+
+func TestSignPKCS7Cert(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/release.p12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	priv, cert, err := readKeystore(data, "", "storepass", "storepass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := signPKCS7(rand.Reader, priv, cert, crypto.SHA256, []byte("Hello world,\nThis is signed."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(sig, cert.Raw) {
+		t.Error("signature does not carry the certificate of the key")
+	}
+	if !bytes.Contains(sig, cert.RawIssuer) {
+		t.Error("signature does not name the issuer of the certificate")
+	}
+}
+
+// The test vectors of rfc2268, section 5.
+var rc2Tests = []struct {
+	key    string
+	bits   int
+	plain  string
+	cipher string
+}{
+	{"0000000000000000", 63, "0000000000000000", "ebb773f993278eff"},
+	{"ffffffffffffffff", 64, "ffffffffffffffff", "278b27e42e2f0d49"},
+	{"3000000000000000", 64, "1000000000000001", "30649edf9be7d2c2"},
+	{"88", 64, "0000000000000000", "61a8a244adacccf0"},
+	{"88bca90e90875a", 64, "0000000000000000", "6ccf4308974c267f"},
+	{"88bca90e90875a7f0f79c384627bafb2", 64, "0000000000000000", "1a807d272bbe5db1"},
+	{"88bca90e90875a7f0f79c384627bafb2", 128, "0000000000000000", "2269552ab0f85ca6"},
+}
+
+func TestRC2(t *testing.T) {
+	for _, tc := range rc2Tests {
+		key, _ := hex.DecodeString(tc.key)
+		plain, _ := hex.DecodeString(tc.plain)
+		c := newRC2Cipher(key, tc.bits)
+		got := make([]byte, 8)
+		c.Encrypt(got, plain)
+		if hex.EncodeToString(got) != tc.cipher {
+			t.Errorf("key %s/%d: encrypted %x, want %s", tc.key, tc.bits, got, tc.cipher)
+		}
+		c.Decrypt(got, got)
+		if !bytes.Equal(got, plain) {
+			t.Errorf("key %s/%d: decrypted %x, want %s", tc.key, tc.bits, got, tc.plain)
+		}
+	}
+}
@@ -9,9 +9,13 @@ import (
 	"errors"
 	"fmt"
 	"html/template"
+	"path"
+	"strings"
+	"unicode"
 )
 
 type manifestXML struct {
+	Package  string      `xml:"package,attr"`
 	Activity activityXML `xml:"application>activity"`
 }
 
@@ -48,9 +52,80 @@ func manifestLibName(data []byte) (string, error) {
 	return libName, nil
 }
 
+// manifestPackage parses the AndroidManifest.xml and finds the package
+// name of the app.
+func manifestPackage(data []byte) (string, error) {
+	manifest := new(manifestXML)
+	if err := xml.Unmarshal(data, manifest); err != nil {
+		return "", err
+	}
+	if manifest.Package == "" {
+		return "", errors.New("AndroidManifest.xml missing package")
+	}
+	return manifest.Package, nil
+}
+
+// manifestPackageName returns the package name of the default manifest
+// of the main package importPath: its domain reversed, followed by the
+// rest of its path, as org.golang.x.mobile.example.basic for
+// golang.org/x/mobile/example/basic. Without a domain, it is
+// org.golang.app.<name>. The characters that cannot be in Java
+// identifiers become underscores, and an underscore is put before
+// leading digits and keywords.
+func manifestPackageName(importPath string) string {
+	elem := strings.Split(importPath, "/")
+	var ids []string
+	if len(elem) > 1 && strings.Contains(elem[0], ".") {
+		domain := strings.Split(elem[0], ".")
+		for i := len(domain) - 1; i >= 0; i-- {
+			ids = append(ids, domain[i])
+		}
+		elem = elem[1:]
+	} else {
+		ids = []string{"org", "golang", "app"}
+		elem = []string{path.Base(importPath)}
+	}
+	for _, e := range elem {
+		ids = append(ids, strings.Split(e, ".")...)
+	}
+	for i, id := range ids {
+		id = strings.Map(func(r rune) rune {
+			if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+				return r
+			}
+			return '_'
+		}, id)
+		if id == "" || unicode.IsDigit(rune(id[0])) || javaKeywords[id] {
+			id = "_" + id
+		}
+		ids[i] = id
+	}
+	return strings.Join(ids, ".")
+}
+
+// javaKeywords are the Java keywords and literals, which cannot be in
+// package names.
+var javaKeywords = map[string]bool{
+	"abstract": true, "assert": true, "boolean": true, "break": true,
+	"byte": true, "case": true, "catch": true, "char": true,
+	"class": true, "const": true, "continue": true, "default": true,
+	"do": true, "double": true, "else": true, "enum": true,
+	"extends": true, "false": true, "final": true, "finally": true,
+	"float": true, "for": true, "goto": true, "if": true,
+	"implements": true, "import": true, "instanceof": true, "int": true,
+	"interface": true, "long": true, "native": true, "new": true,
+	"null": true, "package": true, "private": true, "protected": true,
+	"public": true, "return": true, "short": true, "static": true,
+	"strictfp": true, "super": true, "switch": true, "synchronized": true,
+	"this": true, "throw": true, "throws": true, "transient": true,
+	"true": true, "try": true, "void": true, "volatile": true,
+	"while": true,
+}
+
 type manifestTmplData struct {
 	JavaPkgPath string
 	Name        string
+	Icon        string
 	LibName     string
 }
 
@@ -62,7 +137,7 @@ var manifestTmpl = template.Must(template.New("manifest").Parse(`
 	android:versionName="1.0">
 
 	<uses-sdk android:minSdkVersion="9" />
-	<application android:label="{{.Name}}" android:debuggable="true">
+	<application android:label="{{.Name}}"{{if .Icon}} android:icon="{{.Icon}}"{{end}} android:debuggable="true">
 	<activity android:name="org.golang.app.GoNativeActivity"
 		android:label="{{.Name}}"
 		android:configChanges="orientation|keyboardHidden">
@@ -0,0 +1,431 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"unicode/utf16"
+)
+
+// PKCS#12 is defined in rfc7292. A PKCS#12 file holds bags of keys
+// and certificates, in contents that are either plain or encrypted
+// with a password, and it is authenticated by a MAC computed with
+// the password. keytool and openssl encrypt the keys and certificates
+// with the PKCS#12 PBE algorithms, or with PBES2 in recent versions.
+
+type pfxPdu struct {
+	Version  int
+	AuthSafe pkcs12ContentInfo
+	MacData  macData `asn1:"optional"`
+}
+
+type pkcs12ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"tag:0,explicit,optional"`
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type digestInfo struct {
+	Algorithm pkix.AlgorithmIdentifier
+	Digest    []byte
+}
+
+type encryptedData struct {
+	Version              int
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm pkix.AlgorithmIdentifier
+	EncryptedContent           []byte `asn1:"tag:0,optional"`
+}
+
+type safeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue     `asn1:"tag:0,explicit"`
+	Attributes []pkcs12Attribute `asn1:"set,optional"`
+}
+
+type pkcs12Attribute struct {
+	ID    asn1.ObjectIdentifier
+	Value asn1.RawValue `asn1:"set"`
+}
+
+type certBag struct {
+	ID   asn1.ObjectIdentifier
+	Data []byte `asn1:"tag:0,explicit"`
+}
+
+type pbeParams struct {
+	Salt       []byte
+	Iterations int
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt       []byte
+	Iterations int
+	KeyLength  int                      `asn1:"optional"`
+	PRF        pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+var (
+	oidDataContent          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEncryptedDataContent = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+	oidKeyBag               = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 1}
+	oidShroudedKeyBag       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidCertBag              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidX509Certificate      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+	oidFriendlyName         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 20}
+
+	oidPBEWithSHAAnd3KeyTripleDESCBC = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 3}
+	oidPBEWithSHAAnd128BitRC2CBC     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 5}
+	oidPBEWithSHAAnd40BitRC2CBC      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 1, 6}
+	oidPBES2                         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2                        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1                  = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256                = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidAES128CBC                     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC                     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC                     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+	oidDESEDE3CBC                    = asn1.ObjectIdentifier{1, 2, 840, 113549, 3, 7}
+	oidSHA256                        = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+)
+
+// readPKCS12 reads the private key named alias, and its certificate,
+// from a PKCS#12 file. The alias of a key is its friendly name. The MAC
+// and the encrypted contents are checked and decrypted with storePass,
+// and the key with keyPass, as keytool does. openssl encrypts the
+// contents with the key password instead, which is tried next.
+func readPKCS12(data []byte, alias, storePass, keyPass string) (*rsa.PrivateKey, *x509.Certificate, error) {
+	var pfx pfxPdu
+	if err := unmarshalDER(data, &pfx); err != nil {
+		return nil, nil, fmt.Errorf("unknown keystore format, want JKS, PKCS#12 or PEM: %v", err)
+	}
+	if pfx.Version != 3 {
+		return nil, nil, fmt.Errorf("unsupported PKCS#12 version %d", pfx.Version)
+	}
+	if !pfx.AuthSafe.ContentType.Equal(oidDataContent) {
+		return nil, nil, errors.New("unsupported PKCS#12 file, not protected by a password")
+	}
+	var authSafe []byte
+	if err := unmarshalDER(pfx.AuthSafe.Content.Bytes, &authSafe); err != nil {
+		return nil, nil, fmt.Errorf("bad PKCS#12 file: %v", err)
+	}
+	if pfx.MacData.Mac.Algorithm.Algorithm != nil {
+		if err := verifyPKCS12MAC(&pfx.MacData, authSafe, storePass); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var contents []pkcs12ContentInfo
+	if err := unmarshalDER(authSafe, &contents); err != nil {
+		return nil, nil, fmt.Errorf("bad PKCS#12 file: %v", err)
+	}
+	var keys []safeBag
+	var aliases []string
+	var certs [][]byte
+	for _, ci := range contents {
+		var b []byte
+		switch {
+		case ci.ContentType.Equal(oidDataContent):
+			if err := unmarshalDER(ci.Content.Bytes, &b); err != nil {
+				return nil, nil, fmt.Errorf("bad PKCS#12 file: %v", err)
+			}
+		case ci.ContentType.Equal(oidEncryptedDataContent):
+			var ed encryptedData
+			if err := unmarshalDER(ci.Content.Bytes, &ed); err != nil {
+				return nil, nil, fmt.Errorf("bad PKCS#12 file: %v", err)
+			}
+			eci := ed.EncryptedContentInfo
+			var err error
+			b, err = pbDecrypt(eci.ContentEncryptionAlgorithm, eci.EncryptedContent, storePass)
+			if err != nil && keyPass != storePass {
+				b, err = pbDecrypt(eci.ContentEncryptionAlgorithm, eci.EncryptedContent, keyPass)
+			}
+			if err != nil {
+				return nil, nil, fmt.Errorf("cannot decrypt PKCS#12 file: %v", err)
+			}
+		default:
+			return nil, nil, fmt.Errorf("unsupported PKCS#12 content type %v", ci.ContentType)
+		}
+		var bags []safeBag
+		if err := unmarshalDER(b, &bags); err != nil {
+			return nil, nil, fmt.Errorf("bad PKCS#12 file: %v", err)
+		}
+		for _, bag := range bags {
+			switch {
+			case bag.ID.Equal(oidKeyBag), bag.ID.Equal(oidShroudedKeyBag):
+				keys = append(keys, bag)
+				aliases = append(aliases, friendlyName(bag))
+			case bag.ID.Equal(oidCertBag):
+				var cb certBag
+				if err := unmarshalDER(bag.Value.Bytes, &cb); err != nil {
+					return nil, nil, fmt.Errorf("bad PKCS#12 certificate: %v", err)
+				}
+				if cb.ID.Equal(oidX509Certificate) {
+					certs = append(certs, cb.Data)
+				}
+			}
+		}
+	}
+
+	i, err := chooseAlias(aliases, alias)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Only the chosen key is decrypted, as the keys may have
+	// passwords of their own.
+	key := keys[i].Value.Bytes
+	if keys[i].ID.Equal(oidShroudedKeyBag) {
+		var info encryptedPrivateKeyInfo
+		if err := unmarshalDER(key, &info); err != nil {
+			return nil, nil, fmt.Errorf("bad PKCS#12 private key: %v", err)
+		}
+		if key, err = pbDecrypt(info.Algorithm, info.EncryptedData, keyPass); err != nil {
+			return nil, nil, fmt.Errorf("cannot decrypt private key: %v", err)
+		}
+	}
+	return keyAndCert(key, certs)
+}
+
+// friendlyName returns the friendly name of bag, or "" if it has none.
+func friendlyName(bag safeBag) string {
+	for _, attr := range bag.Attributes {
+		if !attr.ID.Equal(oidFriendlyName) {
+			continue
+		}
+		var raw asn1.RawValue // BMPString, not supported by encoding/asn1
+		if _, err := asn1.Unmarshal(attr.Value.Bytes, &raw); err != nil || len(raw.Bytes)%2 != 0 {
+			return ""
+		}
+		s := make([]uint16, len(raw.Bytes)/2)
+		for i := range s {
+			s[i] = uint16(raw.Bytes[2*i])<<8 | uint16(raw.Bytes[2*i+1])
+		}
+		return string(utf16.Decode(s))
+	}
+	return ""
+}
+
+// verifyPKCS12MAC checks the MAC of the authenticated contents of a
+// PKCS#12 file, to report a wrong password.
+func verifyPKCS12MAC(md *macData, content []byte, password string) error {
+	var h func() hash.Hash
+	switch alg := md.Mac.Algorithm.Algorithm; {
+	case alg.Equal(oidSHA1):
+		h = sha1.New
+	case alg.Equal(oidSHA256):
+		h = sha256.New
+	default:
+		return fmt.Errorf("unsupported PKCS#12 MAC algorithm %v", alg)
+	}
+	key := pkcs12KDF(h, md.MacSalt, bmpPassword(password), 3, md.Iterations, h().Size())
+	mac := hmac.New(h, key)
+	mac.Write(content)
+	if !hmac.Equal(mac.Sum(nil), md.Mac.Digest) {
+		return errors.New("wrong password")
+	}
+	return nil
+}
+
+// pbDecrypt decrypts data encrypted with the password-based encryption
+// alg, one of the PKCS#12 PBE algorithms or PBES2.
+func pbDecrypt(alg pkix.AlgorithmIdentifier, data []byte, password string) ([]byte, error) {
+	var block cipher.Block
+	var iv []byte
+	switch oid := alg.Algorithm; {
+	case oid.Equal(oidPBEWithSHAAnd3KeyTripleDESCBC),
+		oid.Equal(oidPBEWithSHAAnd128BitRC2CBC),
+		oid.Equal(oidPBEWithSHAAnd40BitRC2CBC):
+		var params pbeParams
+		if err := unmarshalDER(alg.Parameters.FullBytes, &params); err != nil {
+			return nil, err
+		}
+		kdf := func(id byte, size int) []byte {
+			return pkcs12KDF(sha1.New, params.Salt, bmpPassword(password), id, params.Iterations, size)
+		}
+		iv = kdf(2, 8)
+		var err error
+		switch {
+		case oid.Equal(oidPBEWithSHAAnd3KeyTripleDESCBC):
+			block, err = des.NewTripleDESCipher(kdf(1, 24))
+		case oid.Equal(oidPBEWithSHAAnd128BitRC2CBC):
+			block = newRC2Cipher(kdf(1, 16), 128)
+		default:
+			block = newRC2Cipher(kdf(1, 5), 40)
+		}
+		if err != nil {
+			return nil, err
+		}
+	case oid.Equal(oidPBES2):
+		var params pbes2Params
+		if err := unmarshalDER(alg.Parameters.FullBytes, &params); err != nil {
+			return nil, err
+		}
+		if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+			return nil, fmt.Errorf("unsupported key derivation function %v", params.KeyDerivationFunc.Algorithm)
+		}
+		var kdfParams pbkdf2Params
+		if err := unmarshalDER(params.KeyDerivationFunc.Parameters.FullBytes, &kdfParams); err != nil {
+			return nil, err
+		}
+		h := sha1.New
+		switch prf := kdfParams.PRF.Algorithm; {
+		case prf == nil, prf.Equal(oidHMACWithSHA1):
+		case prf.Equal(oidHMACWithSHA256):
+			h = sha256.New
+		default:
+			return nil, fmt.Errorf("unsupported pseudorandom function %v", prf)
+		}
+		enc := params.EncryptionScheme
+		if err := unmarshalDER(enc.Parameters.FullBytes, &iv); err != nil {
+			return nil, err
+		}
+		var keyLen int
+		switch {
+		case enc.Algorithm.Equal(oidAES128CBC):
+			keyLen = 16
+		case enc.Algorithm.Equal(oidAES192CBC):
+			keyLen = 24
+		case enc.Algorithm.Equal(oidAES256CBC):
+			keyLen = 32
+		case enc.Algorithm.Equal(oidDESEDE3CBC):
+			keyLen = 24
+		default:
+			return nil, fmt.Errorf("unsupported encryption scheme %v", enc.Algorithm)
+		}
+		key := pbkdf2(h, []byte(password), kdfParams.Salt, kdfParams.Iterations, keyLen)
+		var err error
+		if enc.Algorithm.Equal(oidDESEDE3CBC) {
+			block, err = des.NewTripleDESCipher(key)
+		} else {
+			block, err = aes.NewCipher(key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported encryption algorithm %v", oid)
+	}
+
+	bs := block.BlockSize()
+	if len(data) == 0 || len(data)%bs != 0 || len(iv) != bs {
+		return nil, errors.New("bad encrypted data")
+	}
+	out := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, data)
+
+	// Remove the PKCS#7 padding; a wrong password usually breaks it.
+	n := int(out[len(out)-1])
+	if n == 0 || n > bs || !bytes.Equal(out[len(out)-n:], bytes.Repeat([]byte{byte(n)}, n)) {
+		return nil, errors.New("wrong password")
+	}
+	return out[:len(out)-n], nil
+}
+
+// bmpPassword returns password as a null-terminated BMPString, as
+// PKCS#12 uses it.
+func bmpPassword(password string) []byte {
+	return append(utf16BE(password), 0, 0)
+}
+
+// pkcs12KDF derives size bytes of key material for the purpose id from
+// a password and a salt, as defined in rfc7292, appendix B.2.
+func pkcs12KDF(h func() hash.Hash, salt, password []byte, id byte, iterations, size int) []byte {
+	u := h().Size()
+	const v = 64 // block size of SHA-1 and SHA-256
+
+	fill := func(b []byte) []byte {
+		if len(b) == 0 {
+			return nil
+		}
+		out := make([]byte, v*((len(b)+v-1)/v))
+		for i := range out {
+			out[i] = b[i%len(b)]
+		}
+		return out
+	}
+	I := append(fill(salt), fill(password)...)
+	D := bytes.Repeat([]byte{id}, v)
+
+	var out []byte
+	for len(out) < size {
+		hh := h()
+		hh.Write(D)
+		hh.Write(I)
+		A := hh.Sum(nil)
+		for i := 1; i < iterations; i++ {
+			hh.Reset()
+			hh.Write(A)
+			A = hh.Sum(A[:0])
+		}
+		out = append(out, A...)
+
+		// I_j = (I_j + B + 1) mod 2^(8v), for each block I_j of I,
+		// where B is A repeated.
+		B := new(big.Int).SetBytes(fill(A[:u]))
+		B.Add(B, big.NewInt(1))
+		for j := 0; j < len(I); j += v {
+			Ij := new(big.Int).SetBytes(I[j : j+v])
+			Ij.Add(Ij, B)
+			b := Ij.Bytes()
+			if len(b) > v {
+				b = b[len(b)-v:]
+			}
+			copy(I[j:j+v], make([]byte, v-len(b)))
+			copy(I[j+v-len(b):j+v], b)
+		}
+	}
+	return out[:size]
+}
+
+// pbkdf2 derives a key of size bytes from a password and a salt, as
+// defined in rfc2898, section 5.2.
+func pbkdf2(h func() hash.Hash, password, salt []byte, iterations, size int) []byte {
+	prf := hmac.New(h, password)
+	var out []byte
+	for block := uint32(1); len(out) < size; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write([]byte{byte(block >> 24), byte(block >> 16), byte(block >> 8), byte(block)})
+		U := prf.Sum(nil)
+		T := append([]byte(nil), U...)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(U)
+			U = prf.Sum(U[:0])
+			for j := range T {
+				T[j] ^= U[j]
+			}
+		}
+		out = append(out, T...)
+	}
+	return out[:size]
+}
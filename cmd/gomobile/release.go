@@ -41,6 +41,19 @@ var hosts = []version{
 	{"windows", "x86_64"},
 }
 
+// alToolchains are the NDK toolchains OpenAL is built with, by the
+// directory of its library in APKs. The arm library stays in
+// lib/armeabi, where earlier releases of the tarball hold it.
+var alToolchains = []struct {
+	abi        string // directory of the library in APKs
+	toolPrefix string // prefix of the names of the tools
+}{
+	{"armeabi", "arm-linux-androideabi"},
+	{"arm64-v8a", "aarch64-linux-android"},
+	{"x86", "i686-linux-android"},
+	{"x86_64", "x86_64-linux-android"},
+}
+
 var tmpdir string
 
 func main() {
@@ -83,11 +96,23 @@ func mkALPkg() (err error) {
 	if err := run(alTmpDir, "git", "checkout", "19f79be57b8e768f44710b6d26017bc1f8c8fbda"); err != nil {
 		return err
 	}
-	if err := run(filepath.Join(alTmpDir, "cmake"), "cmake", "..", "-DCMAKE_TOOLCHAIN_FILE=../XCompile-Android.txt", "-DHOST=arm-linux-androideabi"); err != nil {
-		return err
+	files := map[string]string{
+		"include/AL/al.h":  "include/AL/al.h",
+		"include/AL/alc.h": "include/AL/alc.h",
+		"COPYING":          "include/AL/COPYING",
 	}
-	if err := run(filepath.Join(alTmpDir, "cmake"), "make"); err != nil {
-		return err
+	for _, tc := range alToolchains {
+		dir := filepath.Join(alTmpDir, "cmake-"+tc.abi)
+		if err := os.Mkdir(dir, 0755); err != nil {
+			return err
+		}
+		if err := run(dir, "cmake", "..", "-DCMAKE_TOOLCHAIN_FILE=../XCompile-Android.txt", "-DHOST="+tc.toolPrefix); err != nil {
+			return err
+		}
+		if err := run(dir, "make"); err != nil {
+			return err
+		}
+		files["cmake-"+tc.abi+"/libopenal.so"] = "lib/" + tc.abi + "/libopenal.so"
 	}
 
 	// Build the tarball.
@@ -126,12 +151,6 @@ func mkALPkg() (err error) {
 		}
 	}()
 
-	files := map[string]string{
-		"cmake/libopenal.so": "lib/armeabi/libopenal.so",
-		"include/AL/al.h":    "include/AL/al.h",
-		"include/AL/alc.h":   "include/AL/alc.h",
-		"COPYING":            "include/AL/COPYING",
-	}
 	for src, dst := range files {
 		f, err := os.Open(filepath.Join(alTmpDir, src))
 		if err != nil {
@@ -0,0 +1,886 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+// resources.arsc is the table of the resources of an APK, such as its
+// strings and its icons. The binary XML files refer to them by resource
+// ID, 0xPPTTEEEE: the package (0x7f for the app), the type and the entry.
+// A resource has values in one or more configurations, such as a
+// language or a screen density, and Android picks the best match for
+// the device.
+//
+// Like binary XML, the table is made of chunks. They are defined by the
+// ResTable structs of ResourceTypes.h, see binary_xml.go:
+//
+//	Table (ResTable_header, type TABLE)
+//	Chunk: String Pool of the string values, and of the names of the
+//		files of resources, such as res/mipmap-hdpi/ic_launcher.png
+//	Chunk: Package (ResTable_package)
+//		Chunk: String Pool of the type names: mipmap, string...
+//		Chunk: String Pool of the entry names: app_name, ic_launcher...
+//		For each type:
+//		Chunk: Type Spec (ResTable_typeSpec)
+//			The dimensions of the configurations of each entry
+//		Chunk: Type (ResTable_type), for each configuration
+//			ResTable_config
+//			The offsets of the entries, or NO_ENTRY (0xffffffff)
+//			ResTable_entry and Res_value of each entry
+//
+// aapt compiles the res directory of an Android project into this table.
+// readResources does it for the simple values and files of a res
+// directory:
+//
+//	res/values/strings.xml
+//	res/values/colors.xml
+//	res/values-fr/strings.xml
+//	res/mipmap-hdpi/ic_launcher.png
+//	res/mipmap-xhdpi/ic_launcher.png
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+const (
+	headerTable         headerType = 0x0002
+	headerTablePackage             = 0x0200
+	headerTableType                = 0x0201
+	headerTableTypeSpec            = 0x0202
+)
+
+// appPackageID is the package of the resource IDs of the app.
+const appPackageID = 0x7f
+
+// resConfigSize is the size of the ResTable_config written by aapt2.
+const resConfigSize = 64
+
+// The dimensions of configurations, in the flags of type specs.
+const (
+	configLocale  = 0x0004
+	configDensity = 0x0100
+	configVersion = 0x0400
+)
+
+// http://developer.android.com/guide/topics/resources/providing-resources.html#DensityQualifier
+var densities = map[string]uint16{
+	"ldpi":    120,
+	"mdpi":    160,
+	"tvdpi":   213,
+	"hdpi":    240,
+	"xhdpi":   320,
+	"xxhdpi":  480,
+	"xxxhdpi": 640,
+	"anydpi":  0xfffe,
+	"nodpi":   0xffff,
+}
+
+// A resTable is a table of resources.
+type resTable struct {
+	pkg   string     // package of the app
+	types []*resType // by type ID, from 1
+
+	// files maps the names in the APK of the files of resources to
+	// their source files.
+	files map[string]string
+}
+
+// A resType is a type of resources, such as string or mipmap.
+type resType struct {
+	name    string
+	entries []string // names, by entry ID
+	configs []*resConfig
+}
+
+// A resConfig holds the values of a type of resources in a configuration.
+type resConfig struct {
+	resQualifiers
+	values map[string]string // by entry name
+}
+
+// resQualifiers are the qualifiers of a configuration, as in the name of
+// the directory values-fr-rCA or mipmap-hdpi.
+type resQualifiers struct {
+	language string // such as fr
+	region   string // such as CA
+	density  uint16 // in dots per inch
+	sdk      uint16 // minimum SDK version
+}
+
+// parseQualifiers parses the qualifiers of a resource directory, the
+// words after its type.
+func parseQualifiers(words []string) (resQualifiers, error) {
+	var q resQualifiers
+	for i, w := range words {
+		switch {
+		case i == 0 && len(w) == 2 && isLetters(w, 'a', 'z'):
+			q.language = w
+		case i == 1 && q.language != "" && len(w) == 3 && w[0] == 'r' && isLetters(w[1:], 'A', 'Z'):
+			q.region = w[1:]
+		case densities[w] != 0 && q.density == 0 && q.sdk == 0:
+			q.density = densities[w]
+		case len(w) > 1 && w[0] == 'v' && q.sdk == 0:
+			v, err := strconv.ParseUint(w[1:], 10, 16)
+			if err != nil {
+				return q, fmt.Errorf("bad qualifier %q", w)
+			}
+			q.sdk = uint16(v)
+		default:
+			return q, fmt.Errorf("unsupported qualifier %q", w)
+		}
+	}
+	return q, nil
+}
+
+func isLetters(s string, first, last byte) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < first || s[i] > last {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the qualifiers as in the name of a resource directory,
+// such as fr-rCA.
+func (q resQualifiers) String() string {
+	var words []string
+	if q.language != "" {
+		words = append(words, q.language)
+	}
+	if q.region != "" {
+		words = append(words, "r"+q.region)
+	}
+	for w, d := range densities {
+		if q.density == d {
+			words = append(words, w)
+		}
+	}
+	if q.sdk != 0 {
+		words = append(words, "v"+strconv.Itoa(int(q.sdk)))
+	}
+	return strings.Join(words, "-")
+}
+
+// dimensions returns the dimensions of the configurations of q, as in
+// the flags of type specs.
+func (q resQualifiers) dimensions() uint32 {
+	var d uint32
+	if q.language != "" {
+		d |= configLocale
+	}
+	if q.density != 0 {
+		d |= configDensity
+	}
+	if q.sdk != 0 {
+		d |= configVersion
+	}
+	return d
+}
+
+// readResources reads the resources of the res directory dir. It returns
+// nil if there is no such directory.
+//
+// The XML files of the values directories hold strings, colors,
+// dimensions, booleans and integers, see readValues. Each file
+// of the other directories is a resource of the type of the directory,
+// named by the file name without extension. The XML files among them are
+// compiled into binary XML by the build, see binaryXML.
+func readResources(dir string) (*resTable, error) {
+	dirs, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	t := &resTable{files: make(map[string]string)}
+	for _, d := range dirs {
+		if !d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			continue
+		}
+		words := strings.Split(d.Name(), "-")
+		typ := words[0]
+		if typ == "" || !isLetters(typ, 'a', 'z') {
+			return nil, fmt.Errorf("res/%s: bad resource type %q", d.Name(), typ)
+		}
+		q, err := parseQualifiers(words[1:])
+		if err != nil {
+			return nil, fmt.Errorf("res/%s: %v", d.Name(), err)
+		}
+		files, err := ioutil.ReadDir(filepath.Join(dir, d.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, fi := range files {
+			if fi.IsDir() || strings.HasPrefix(fi.Name(), ".") {
+				continue
+			}
+			src := filepath.Join(dir, d.Name(), fi.Name())
+			if typ == "values" {
+				err = t.readValues(src, q)
+			} else {
+				name := "res/" + d.Name() + "/" + fi.Name()
+				t.files[name] = src
+				err = t.add(typ, q, strings.SplitN(fi.Name(), ".", 2)[0], name)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("res/%s/%s: %v", d.Name(), fi.Name(), err)
+			}
+		}
+	}
+
+	sort.Sort(resTypesByName(t.types))
+	for _, rt := range t.types {
+		sort.Strings(rt.entries)
+		sort.Sort(resConfigsByName(rt.configs))
+	}
+	return t, nil
+}
+
+type resTypesByName []*resType
+
+func (s resTypesByName) Len() int           { return len(s) }
+func (s resTypesByName) Less(i, j int) bool { return s[i].name < s[j].name }
+func (s resTypesByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+type resConfigsByName []*resConfig
+
+func (s resConfigsByName) Len() int           { return len(s) }
+func (s resConfigsByName) Less(i, j int) bool { return s[i].String() < s[j].String() }
+func (s resConfigsByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// readValues adds the resources of the XML file src, of the qualifiers q,
+// to t:
+//
+//	<resources>
+//		<string name="app_name">Hello</string>
+//		<color name="ic_launcher_background">#3ddc84</color>
+//		<dimen name="margin">16dp</dimen>
+//		<bool name="debug">false</bool>
+//		<integer name="columns">2</integer>
+//	</resources>
+//
+// The values other than strings are kept as the text of their typed
+// value, such as #ff3ddc84 for #3DDC84, see parseResValue.
+func (t *resTable) readValues(src string, q resQualifiers) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	lr := &lineReader{r: f}
+	d := xml.NewDecoder(lr)
+
+	depth := 0
+	var typ, name string
+	var text []byte
+	for {
+		line := lr.line(d.InputOffset())
+		tok, err := d.Token()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			depth++
+			switch depth {
+			case 1:
+				if tok.Name.Local != "resources" {
+					return fmt.Errorf("%d: root element <%s>, want <resources>", line, tok.Name.Local)
+				}
+			case 2:
+				typ = tok.Name.Local
+				if _, ok := resValueTypes[typ]; !ok && typ != "string" {
+					return fmt.Errorf("%d: unsupported resource <%s>", line, typ)
+				}
+				name, text = "", text[:0]
+				for _, a := range tok.Attr {
+					if a.Name.Space == "" && a.Name.Local == "name" {
+						name = a.Value
+					}
+				}
+				if name == "" {
+					return fmt.Errorf("%d: %s without a name", line, typ)
+				}
+			}
+		case xml.EndElement:
+			if depth == 2 {
+				v := unescapeString(string(text))
+				if typ != "string" {
+					dataType, data, err := parseResValue(typ, v)
+					if err != nil {
+						return fmt.Errorf("%d: %s/%s: %v", line, typ, name, err)
+					}
+					v, _ = formatValue(xml.Name{}, dataType, data, nil, nil)
+				}
+				if err := t.add(typ, q, name, v); err != nil {
+					return fmt.Errorf("%d: %v", line, err)
+				}
+			}
+			depth--
+		case xml.CharData:
+			// The text of the markup of styled strings is kept,
+			// without the markup.
+			if depth >= 2 {
+				text = append(text, tok...)
+			}
+		}
+	}
+	return nil
+}
+
+// resValueTypes are the types of the resources of values directories
+// other than strings, by their element name.
+var resValueTypes = map[string]bool{
+	"bool":    true,
+	"color":   true,
+	"dimen":   true,
+	"integer": true,
+}
+
+// parseResValue returns the type and data of the Res_value of the text s
+// of a resource of type typ, one of resValueTypes, as aapt does.
+func parseResValue(typ, s string) (dataType byte, data uint32, err error) {
+	switch typ {
+	case "bool":
+		switch s {
+		case "true":
+			return 0x12, 0xffffffff, nil // INT_BOOLEAN
+		case "false":
+			return 0x12, 0, nil
+		}
+	case "color":
+		if !strings.HasPrefix(s, "#") {
+			break
+		}
+		v, err := strconv.ParseUint(s[1:], 16, 32)
+		if err != nil {
+			break
+		}
+		// #RGB, #ARGB, #RRGGBB and #AARRGGBB; the data is always
+		// AARRGGBB, opaque without alpha.
+		switch len(s) - 1 {
+		case 3, 4:
+			var argb uint32
+			for i := uint(0); i < 4; i++ {
+				n := uint32(v>>(4*i)) & 0xf
+				argb |= (n<<4 | n) << (8 * i)
+			}
+			if len(s) == 4 {
+				return 0x1f, 0xff000000 | argb, nil // INT_COLOR_RGB4
+			}
+			return 0x1e, argb, nil // INT_COLOR_ARGB4
+		case 6:
+			return 0x1d, 0xff000000 | uint32(v), nil // INT_COLOR_RGB8
+		case 8:
+			return 0x1c, uint32(v), nil // INT_COLOR_ARGB8
+		}
+	case "dimen":
+		units := []struct {
+			suffix string
+			unit   uint32
+		}{{"px", 0}, {"dp", 1}, {"dip", 1}, {"sp", 2}, {"pt", 3}, {"in", 4}, {"mm", 5}}
+		for _, u := range units {
+			if !strings.HasSuffix(s, u.suffix) {
+				continue
+			}
+			f, err := strconv.ParseFloat(strings.TrimSuffix(s, u.suffix), 32)
+			if err != nil {
+				break
+			}
+			data, ok := complexData(f)
+			if !ok {
+				return 0, 0, fmt.Errorf("dimension %s out of range", s)
+			}
+			return 0x05, data | u.unit, nil // DIMENSION
+		}
+	case "integer":
+		if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+			v, err := strconv.ParseUint(s[2:], 16, 32)
+			if err == nil {
+				return 0x11, uint32(v), nil // INT_HEX
+			}
+			break
+		}
+		v, err := strconv.ParseInt(s, 10, 32)
+		if err == nil {
+			return 0x10, uint32(v), nil // INT_DEC
+		}
+	}
+	return 0, 0, fmt.Errorf("bad %s value %q", typ, s)
+}
+
+// complexData returns the mantissa and radix of the dimension or fraction
+// f, the inverse of complexValue, with the unit left as zero. It reports
+// whether f fits in the 24-bit mantissa.
+func complexData(f float64) (uint32, bool) {
+	neg := f < 0
+	if neg {
+		f = -f
+	}
+	if f >= 1<<23 {
+		return 0, false
+	}
+	// As in aapt, the radix keeps as many fraction bits as fit.
+	bits := uint64(f*(1<<23) + 0.5)
+	var radix, shift uint
+	switch {
+	case bits&0x7fffff == 0:
+		radix, shift = 0, 23 // 23p0
+	case bits&^0x7fffff == 0:
+		radix, shift = 3, 0 // 0p23
+	case bits&^0x7fffffff == 0:
+		radix, shift = 2, 8 // 8p15
+	case bits&^0x7fffffffff == 0:
+		radix, shift = 1, 16 // 16p7
+	default:
+		radix, shift = 0, 23
+	}
+	mantissa := uint32(bits>>shift) & 0xffffff
+	if neg {
+		mantissa = -mantissa & 0xffffff
+	}
+	return mantissa<<8 | uint32(radix)<<4, true
+}
+
+// unescapeString returns the value of a string resource of text s, as
+// aapt does. The spaces are collapsed outside of double quotes, which are
+// dropped, and a backslash escapes the next character: \n, \t and \uXXXX
+// are a newline, a tab and a Unicode character.
+func unescapeString(s string) string {
+	buf := new(bytes.Buffer)
+	quoted, space := false, false
+	rs := []rune(s)
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		switch {
+		case r == '\\' && i+1 < len(rs):
+			i++
+			r = rs[i]
+			switch r {
+			case 'n':
+				r = '\n'
+			case 't':
+				r = '\t'
+			case 'u':
+				if i+4 < len(rs) {
+					if v, err := strconv.ParseUint(string(rs[i+1:i+5]), 16, 16); err == nil {
+						r = rune(v)
+						i += 4
+					}
+				}
+			}
+		case r == '"':
+			quoted = !quoted
+			continue
+		case !quoted && unicode.IsSpace(r):
+			space = true
+			continue
+		}
+		if space && buf.Len() > 0 {
+			buf.WriteByte(' ')
+		}
+		space = false
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+// add adds the value of the resource typ/name in the configuration of the
+// qualifiers q.
+func (t *resTable) add(typ string, q resQualifiers, name, value string) error {
+	if name == "" {
+		return errors.New("resource without a name")
+	}
+	var rt *resType
+	for _, x := range t.types {
+		if x.name == typ {
+			rt = x
+		}
+	}
+	if rt == nil {
+		rt = &resType{name: typ}
+		t.types = append(t.types, rt)
+	}
+	var c *resConfig
+	for _, x := range rt.configs {
+		if x.resQualifiers == q {
+			c = x
+		}
+	}
+	if c == nil {
+		c = &resConfig{resQualifiers: q, values: make(map[string]string)}
+		rt.configs = append(rt.configs, c)
+	}
+	if _, dup := c.values[name]; dup {
+		return fmt.Errorf("duplicate resource %s/%s", typ, name)
+	}
+	c.values[name] = value
+	for _, e := range rt.entries {
+		if e == name {
+			return nil
+		}
+	}
+	rt.entries = append(rt.entries, name)
+	return nil
+}
+
+// id returns the resource ID of the resource typ/name of t, which may be
+// nil.
+func (t *resTable) id(typ, name string) (uint32, bool) {
+	if t == nil {
+		return 0, false
+	}
+	for i, rt := range t.types {
+		if rt.name != typ {
+			continue
+		}
+		for j, e := range rt.entries {
+			if e == name {
+				return appPackageID<<24 | uint32(i+1)<<16 | uint32(j), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// name returns the type and name of the resource of ID id of t, which
+// may be nil, as in string/app_name.
+func (t *resTable) name(id uint32) (string, bool) {
+	if t == nil || id>>24 != appPackageID {
+		return "", false
+	}
+	typ, entry := int(id>>16&0xff)-1, int(id&0xffff)
+	if typ < 0 || typ >= len(t.types) || entry >= len(t.types[typ].entries) {
+		return "", false
+	}
+	rt := t.types[typ]
+	if rt.entries[entry] == "" {
+		return "", false
+	}
+	return rt.name + "/" + rt.entries[entry], true
+}
+
+// encode returns the resources.arsc of t. The values of resValueTypes are
+// typed, the others are strings, such as the names of files.
+func (t *resTable) encode() []byte {
+	values := new(binStringPool)
+	typeNames := new(binStringPool)
+	keys := new(binStringPool)
+	for _, rt := range t.types {
+		typeNames.get(rt.name)
+		for _, e := range rt.entries {
+			keys.get(e)
+		}
+	}
+
+	var types []byte
+	for i, rt := range t.types {
+		id := byte(i + 1)
+		types = appendChunkHeader(types, headerTableTypeSpec, 16, 16+4*len(rt.entries))
+		types = append(types, id, 0) // id, reserved
+		types = appendU16(types, 0)  // reserved
+		types = appendU32(types, uint32(len(rt.entries)))
+		for _, e := range rt.entries {
+			var flags uint32
+			for _, c := range rt.configs {
+				if _, ok := c.values[e]; ok {
+					flags |= c.dimensions()
+				}
+			}
+			types = appendU32(types, flags)
+		}
+
+		for _, c := range rt.configs {
+			const headerSize = 20 + resConfigSize
+			entriesStart := headerSize + 4*len(rt.entries)
+			types = appendChunkHeader(types, headerTableType, headerSize, entriesStart+16*len(c.values))
+			types = append(types, id, 0) // id, flags
+			types = appendU16(types, 0)  // reserved
+			types = appendU32(types, uint32(len(rt.entries)))
+			types = appendU32(types, uint32(entriesStart))
+			types = c.appendConfig(types)
+			off := 0
+			for _, e := range rt.entries {
+				if _, ok := c.values[e]; !ok {
+					types = appendU32(types, 0xffffffff) // NO_ENTRY
+					continue
+				}
+				types = appendU32(types, uint32(off))
+				off += 16
+			}
+			for _, e := range rt.entries {
+				v, ok := c.values[e]
+				if !ok {
+					continue
+				}
+				types = appendU16(types, 8) // entry size
+				types = appendU16(types, 0) // flags
+				types = appendU32(types, keys.get(e).ind)
+				types = appendU16(types, 8) // value size
+				dataType, data, err := parseResValue(rt.name, v)
+				if _, file := t.files[v]; file || err != nil {
+					dataType, data = 0x03, values.get(v).ind // STRING
+				}
+				types = append(types, 0, dataType) // unused padding, type
+				types = appendU32(types, data)
+			}
+		}
+	}
+
+	const tableHeaderSize, packageHeaderSize = 12, 288
+	packageSize := packageHeaderSize + typeNames.size() + keys.size() + len(types)
+	size := tableHeaderSize + values.size() + packageSize
+
+	b := make([]byte, 0, size)
+	b = appendChunkHeader(b, headerTable, tableHeaderSize, size)
+	b = appendU32(b, 1) // package count
+	b = values.append(b)
+
+	b = appendChunkHeader(b, headerTablePackage, packageHeaderSize, packageSize)
+	b = appendU32(b, appPackageID)
+	name := utf16.Encode([]rune(t.pkg))
+	for i := 0; i < 128; i++ {
+		var c uint16
+		if i < len(name) && i < 127 {
+			c = name[i]
+		}
+		b = appendU16(b, c)
+	}
+	b = appendU32(b, packageHeaderSize)                          // type strings
+	b = appendU32(b, uint32(len(t.types)))                       // last public type
+	b = appendU32(b, uint32(packageHeaderSize+typeNames.size())) // key strings
+	b = appendU32(b, uint32(len(keys.s)))                        // last public key
+	b = appendU32(b, 0)                                          // type ID offset
+	b = typeNames.append(b)
+	b = keys.append(b)
+	return append(b, types...)
+}
+
+// appendConfig appends the ResTable_config of the qualifiers q.
+func (q resQualifiers) appendConfig(b []byte) []byte {
+	c := make([]byte, resConfigSize)
+	binary.LittleEndian.PutUint32(c[0:], resConfigSize)
+	copy(c[8:10], q.language)
+	copy(c[10:12], q.region)
+	binary.LittleEndian.PutUint16(c[14:], q.density)
+	binary.LittleEndian.PutUint16(c[24:], q.sdk)
+	return append(b, c...)
+}
+
+// decodeResTable decodes the resources.arsc b, as written by encode or
+// aapt. It returns the package of the app, with the values that are not
+// strings as text and without the values of styles.
+func decodeResTable(b []byte) (*resTable, error) {
+	r := &binReader{b: b}
+	typ, headerSize, size := headerType(r.uint16()), int(r.uint16()), int(r.uint32())
+	if r.err != nil || typ != headerTable || headerSize < 12 || size < headerSize || size > len(b) {
+		return nil, errors.New("not a resource table")
+	}
+	chunks, err := splitChunks(b[headerSize:size])
+	if err != nil {
+		return nil, err
+	}
+	var values []string
+	for _, chunk := range chunks {
+		switch headerType(binary.LittleEndian.Uint16(chunk)) {
+		case headerStringPool:
+			values, err = decodeStringPool(chunk, int(binary.LittleEndian.Uint16(chunk[2:])))
+			if err != nil {
+				return nil, err
+			}
+		case headerTablePackage:
+			if len(chunk) >= 12 && binary.LittleEndian.Uint32(chunk[8:]) == appPackageID {
+				t, err := decodeResPackage(chunk, values)
+				if err != nil {
+					return nil, fmt.Errorf("package: %v", err)
+				}
+				return t, nil
+			}
+		}
+	}
+	return nil, errors.New("no package of the app")
+}
+
+// splitChunks returns the chunks of the sequence of chunks b.
+func splitChunks(b []byte) ([][]byte, error) {
+	var chunks [][]byte
+	for len(b) > 0 {
+		r := &binReader{b: b}
+		r.uint16() // type
+		headerSize, size := int(r.uint16()), int(r.uint32())
+		if r.err != nil || headerSize < 8 || size < headerSize || size > len(b) {
+			return nil, errors.New("truncated chunk")
+		}
+		chunks = append(chunks, b[:size])
+		b = b[size:]
+	}
+	return chunks, nil
+}
+
+// decodeResPackage decodes the package chunk of a resource table, of
+// string values values.
+func decodeResPackage(chunk []byte, values []string) (*resTable, error) {
+	headerSize := int(binary.LittleEndian.Uint16(chunk[2:]))
+	r := &binReader{b: chunk[12:]}
+	name := make([]uint16, 0, 128)
+	for i := 0; i < 128; i++ {
+		if c := r.uint16(); c != 0 && len(name) == i {
+			name = append(name, c)
+		}
+	}
+	typeStrings, _, keyStrings := int(r.uint32()), r.uint32(), int(r.uint32())
+	if r.err != nil || headerSize > len(chunk) {
+		return nil, errors.New("truncated package header")
+	}
+	chunks, err := splitChunks(chunk[headerSize:])
+	if err != nil {
+		return nil, err
+	}
+
+	t := &resTable{pkg: string(utf16.Decode(name))}
+	var keys []string
+	off := headerSize
+	for _, c := range chunks {
+		typ, headerSize := headerType(binary.LittleEndian.Uint16(c)), int(binary.LittleEndian.Uint16(c[2:]))
+		switch {
+		case typ == headerStringPool && off == typeStrings:
+			var names []string
+			if names, err = decodeStringPool(c, headerSize); err == nil {
+				for _, n := range names {
+					t.types = append(t.types, &resType{name: n})
+				}
+			}
+		case typ == headerStringPool && off == keyStrings:
+			keys, err = decodeStringPool(c, headerSize)
+		case typ == headerTableType:
+			err = t.decodeType(c, headerSize, keys, values)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("chunk of type %#x: %v", typ, err)
+		}
+		off += len(c)
+	}
+	return t, nil
+}
+
+// decodeType decodes the values of a type chunk of header size headerSize
+// into t, with the entry names keys and the string values values.
+func (t *resTable) decodeType(chunk []byte, headerSize int, keys, values []string) error {
+	r := &binReader{b: chunk[8:]}
+	id, flags := r.next(1), r.next(1)
+	r.uint16() // reserved
+	count, entriesStart := int(r.uint32()), int(r.uint32())
+	if r.err != nil || headerSize < 20 || entriesStart > len(chunk) {
+		return errors.New("truncated type header")
+	}
+	if id[0] == 0 || int(id[0]) > len(t.types) {
+		return fmt.Errorf("type %d out of range", id[0])
+	}
+	rt := t.types[id[0]-1]
+
+	var config [28]byte
+	copy(config[:], chunk[20:headerSize])
+	q := resQualifiers{
+		language: strings.TrimRight(string(config[8:10]), "\x00"),
+		region:   strings.TrimRight(string(config[10:12]), "\x00"),
+		density:  binary.LittleEndian.Uint16(config[14:]),
+		sdk:      binary.LittleEndian.Uint16(config[24:]),
+	}
+	c := &resConfig{resQualifiers: q, values: make(map[string]string)}
+	rt.configs = append(rt.configs, c)
+
+	const (
+		flagSparse   = 0x01
+		flagOffset16 = 0x02
+	)
+	offsets := &binReader{b: chunk[headerSize:]}
+	for i := 0; i < count; i++ {
+		index, off := i, -1
+		switch {
+		case flags[0]&flagSparse != 0:
+			index, off = int(offsets.uint16()), int(offsets.uint16())*4
+		case flags[0]&flagOffset16 != 0:
+			if o := offsets.uint16(); o != 0xffff {
+				off = int(o) * 4
+			}
+		default:
+			if o := offsets.uint32(); o != 0xffffffff {
+				off = int(o)
+			}
+		}
+		if offsets.err != nil {
+			return offsets.err
+		}
+		if off < 0 {
+			continue
+		}
+		if entriesStart+off > len(chunk) {
+			return fmt.Errorf("entry %d out of range", index)
+		}
+
+		const (
+			flagComplex = 0x0001
+			flagCompact = 0x0008
+		)
+		e := &binReader{b: chunk[entriesStart+off:]}
+		var key, data uint32
+		var dataType byte
+		size, entryFlags := e.uint16(), e.uint16()
+		switch {
+		case entryFlags&flagCompact != 0:
+			key, dataType, data = uint32(size), byte(entryFlags>>8), e.uint32()
+		case entryFlags&flagComplex != 0:
+			key = e.uint32()
+		default:
+			key = e.uint32()
+			e.next(3) // size and padding
+			if b := e.next(1); b != nil {
+				dataType = b[0]
+			}
+			data = e.uint32()
+		}
+		if e.err != nil {
+			return fmt.Errorf("entry %d: %v", index, e.err)
+		}
+		if int(key) >= len(keys) {
+			return fmt.Errorf("entry %d: key %d out of range", index, key)
+		}
+		for len(rt.entries) <= index {
+			rt.entries = append(rt.entries, "")
+		}
+		rt.entries[index] = keys[key]
+		if entryFlags&flagComplex != 0 && entryFlags&flagCompact == 0 {
+			continue
+		}
+		str := func(i uint32) (string, error) {
+			if int(i) >= len(values) {
+				return "", fmt.Errorf("string %d out of range", i)
+			}
+			return values[i], nil
+		}
+		v, err := formatValue(xml.Name{}, dataType, data, str, nil)
+		if err != nil {
+			return fmt.Errorf("entry %d: %v", index, err)
+		}
+		c.values[keys[key]] = v
+	}
+	return nil
+}
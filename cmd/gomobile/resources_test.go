@@ -0,0 +1,295 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"encoding/xml"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testResources returns the resources of testdata/res.
+func testResources(t *testing.T) *resTable {
+	res, err := readResources("testdata/res")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.pkg = "org.golang.fakeapp"
+	return res
+}
+
+func TestReadResources(t *testing.T) {
+	res := testResources(t)
+	ids := []struct {
+		typ, name string
+		id        uint32
+	}{
+		{"bool", "debug", 0x7f010000},
+		{"color", "ic_launcher_background", 0x7f020000},
+		{"mipmap", "ic_launcher", 0x7f050000},
+		{"string", "app_name", 0x7f060000},
+		{"string", "greeting", 0x7f060001},
+	}
+	for _, tc := range ids {
+		id, ok := res.id(tc.typ, tc.name)
+		if !ok || id != tc.id {
+			t.Errorf("%s/%s: got ID %#x, want %#x", tc.typ, tc.name, id, tc.id)
+		}
+		if name, _ := res.name(tc.id); name != tc.typ+"/"+tc.name {
+			t.Errorf("%#x: got name %q, want %s/%s", tc.id, name, tc.typ, tc.name)
+		}
+	}
+	if _, ok := res.id("string", "missing"); ok {
+		t.Error("found string/missing")
+	}
+
+	var configs []string
+	for _, rt := range res.types {
+		for _, c := range rt.configs {
+			configs = append(configs, rt.name+"-"+c.String())
+		}
+	}
+	if got, want := strings.Join(configs, " "), "bool- color- dimen- integer- mipmap-hdpi mipmap-xhdpi string- string-fr"; got != want {
+		t.Errorf("configs %q, want %q", got, want)
+	}
+	values := func(typ string) map[string]string {
+		for _, rt := range res.types {
+			if rt.name == typ {
+				return rt.configs[0].values
+			}
+		}
+		return nil
+	}
+	want := map[string]string{
+		"app_name": "Basic",
+		"greeting": "Hello, world   \"Gopher\"\n",
+	}
+	if got := values("string"); !reflect.DeepEqual(got, want) {
+		t.Errorf("strings %q, want %q", got, want)
+	}
+	for typ, want := range map[string]map[string]string{
+		"bool":    {"debug": "false"},
+		"color":   {"ic_launcher_background": "#3ddc84"},
+		"dimen":   {"margin": "16dp"},
+		"integer": {"columns": "2"},
+	} {
+		if got := values(typ); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s values %q, want %q", typ, got, want)
+		}
+	}
+	if got := res.files["res/mipmap-xhdpi/ic_launcher.png"]; got != "testdata/res/mipmap-xhdpi/ic_launcher.png" {
+		t.Errorf("source of res/mipmap-xhdpi/ic_launcher.png %q", got)
+	}
+}
+
+var qualifierTests = []struct {
+	dir string
+	err string
+}{
+	{dir: "fr"},
+	{dir: "fr-rCA"},
+	{dir: "xxhdpi"},
+	{dir: "anydpi-v26"},
+	{dir: "pt-rBR-hdpi-v21"},
+	{dir: "rCA", err: `unsupported qualifier "rCA"`},
+	{dir: "land", err: `unsupported qualifier "land"`},
+	{dir: "v26-hdpi", err: `unsupported qualifier "hdpi"`},
+	{dir: "hdpi-vx", err: `bad qualifier "vx"`},
+}
+
+func TestParseQualifiers(t *testing.T) {
+	for _, tc := range qualifierTests {
+		q, err := parseQualifiers(strings.Split(tc.dir, "-"))
+		if tc.err != "" {
+			if err == nil || err.Error() != tc.err {
+				t.Errorf("%s: got error %v, want %q", tc.dir, err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tc.dir, err)
+			continue
+		}
+		if q.String() != tc.dir {
+			t.Errorf("%s: got qualifiers %s", tc.dir, q)
+		}
+	}
+}
+
+func TestUnescapeString(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"  Hello,\n\tworld ", "Hello, world"},
+		{`"  Hello,  world "`, "  Hello,  world "},
+		{`It\'s \"Go\"`, `It's "Go"`},
+		{`a\nb\tc\\d`, "a\nb\tc\\d"},
+		{`\u4e16\u754c`, "世界"},
+		{`\@string`, "@string"},
+	}
+	for _, tc := range tests {
+		if got := unescapeString(tc.in); got != tc.want {
+			t.Errorf("unescapeString(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestParseResValue(t *testing.T) {
+	tests := []struct {
+		typ, in string
+		want    string // formatted value, or error
+	}{
+		{"bool", "true", "true"},
+		{"bool", "yes", `bad bool value "yes"`},
+		{"color", "#f00", "#ff0000"},
+		{"color", "#8f00", "#88ff0000"},
+		{"color", "#3DDC84", "#3ddc84"},
+		{"color", "#803ddc84", "#803ddc84"},
+		{"color", "#3ddc8", `bad color value "#3ddc8"`},
+		{"color", "red", `bad color value "red"`},
+		{"dimen", "16dp", "16dp"},
+		{"dimen", "8dip", "8dp"},
+		{"dimen", "1.5sp", "1.5sp"},
+		{"dimen", "-2px", "-2px"},
+		{"dimen", "0.25mm", "0.25mm"},
+		{"dimen", "16", `bad dimen value "16"`},
+		{"dimen", "1e9dp", "dimension 1e9dp out of range"},
+		{"integer", "-3", "-3"},
+		{"integer", "0xff", "0xff"},
+		{"integer", "1.5", `bad integer value "1.5"`},
+	}
+	for _, tc := range tests {
+		var got string
+		dataType, data, err := parseResValue(tc.typ, tc.in)
+		if err != nil {
+			got = err.Error()
+		} else if got, err = formatValue(xml.Name{}, dataType, data, nil, nil); err != nil {
+			t.Errorf("%s %q: %v", tc.typ, tc.in, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s %q: got %q, want %q", tc.typ, tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestDecodeResTable(t *testing.T) {
+	res := testResources(t)
+	b := res.encode()
+	got, err := decodeResTable(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.files = nil
+	if !reflect.DeepEqual(got, res) {
+		t.Errorf("decoded %+v, want %+v", got, res)
+	}
+
+	for i := 8; i < len(b); i += 11 {
+		if _, err := decodeResTable(b[:i]); err == nil {
+			t.Errorf("no error decoding %d bytes of %d", i, len(b))
+		}
+	}
+}
+
+const resManifest = `<manifest
+	xmlns:android="http://schemas.android.com/apk/res/android"
+	package="org.golang.fakeapp">
+	<application android:label="@string/app_name" android:icon="@mipmap/ic_launcher" android:theme="@android:style/Theme.NoTitleBar">
+		<activity android:name="org.golang.app.GoNativeActivity" android:screenOrientation="portrait" />
+	</application>
+</manifest>
+`
+
+func TestBinaryXMLResources(t *testing.T) {
+	res := testResources(t)
+	b, err := binaryXML(strings.NewReader(resManifest), res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		res  *resTable
+		want string
+	}{
+		{res, `android:label="@string/app_name" android:icon="@mipmap/ic_launcher"`},
+		{nil, `android:label="@0x7f060000" android:icon="@0x7f050000"`},
+	} {
+		root, err := decodeBinaryXML(b, tc.res)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text := string(root.format())
+		for _, want := range []string{tc.want, `android:theme="@android:style/Theme.NoTitleBar"`, `android:screenOrientation="portrait"`} {
+			if !strings.Contains(text, want) {
+				t.Errorf("decoded manifest does not contain %s:\n%s", want, text)
+			}
+		}
+	}
+
+	for _, ref := range []string{"@string/missing", "@drawable/ic_launcher"} {
+		m := strings.Replace(resManifest, "@string/app_name", ref, 1)
+		if _, err := binaryXML(strings.NewReader(m), res); err == nil || !strings.Contains(err.Error(), "unknown resource "+ref) {
+			t.Errorf("%s: got error %v", ref, err)
+		}
+	}
+	if _, err := binaryXML(strings.NewReader(resManifest), nil); err == nil {
+		t.Error("no error without resources")
+	}
+}
+
+func TestVerifyAPKResources(t *testing.T) {
+	block, _ := pem.Decode([]byte(debugCert))
+	privKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := testResources(t)
+	apk := func(files ...string) []byte {
+		buf := new(bytes.Buffer)
+		apkw := NewWriter(buf, privKey, nil)
+		apkw.res = res
+		for _, name := range files {
+			w, err := apkw.Create(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var data []byte
+			switch name {
+			case "AndroidManifest.xml":
+				data = []byte(resManifest)
+			case "resources.arsc":
+				data = res.encode()
+			default:
+				if data, err = ioutil.ReadFile(res.files[name]); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, err := w.Write(data); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := apkw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	r := verifyAPK("res.apk", apk("AndroidManifest.xml", "resources.arsc", "res/mipmap-hdpi/ic_launcher.png", "res/mipmap-xhdpi/ic_launcher.png"))
+	checkReport(t, "res.apk", r, map[string]string{
+		"resources.arsc":      "ok: 7 resources of types bool, color, dimen, integer, mipmap, string",
+		"AndroidManifest.xml": "ok: package org.golang.fakeapp",
+	})
+	if !strings.Contains(r.Manifest, `android:icon="@mipmap/ic_launcher"`) {
+		t.Errorf("manifest does not name its icon:\n%s", r.Manifest)
+	}
+
+	r = verifyAPK("missing.apk", apk("AndroidManifest.xml", "resources.arsc", "res/mipmap-hdpi/ic_launcher.png"))
+	checkReport(t, "missing.apk", r, map[string]string{
+		"resources.arsc": "FAIL: mipmap/ic_launcher: missing res/mipmap-xhdpi/ic_launcher.png",
+	})
+}
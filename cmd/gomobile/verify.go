@@ -0,0 +1,572 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+var cmdVerify = &command{
+	run:   runVerify,
+	Name:  "verify",
+	Usage: "[-json] [-v] file.apk|file.aar...",
+	Short: "verify android APKs and AARs",
+	Long: `
+Verify checks the APKs and AARs built by the build and bind commands,
+without the Android SDK.
+
+For an APK, it checks that the ZIP entries are intact and that the
+uncompressed ones are 4-byte aligned. It recomputes the digests of the
+JAR signature (v1) in META-INF/MANIFEST.MF and the .SF file and verifies
+its PKCS#7 signature with the embedded certificate. It verifies the APK
+Signature Schemes v2 and v3 if present, decodes the resource table
+resources.arsc, checking that the files of its resources are present,
+and decodes the binary AndroidManifest.xml, naming the resources it
+refers to.
+
+For an AAR, it checks that the ZIP entries are intact and that the
+archive holds an AndroidManifest.xml, a classes.jar and the shared
+libraries of the architectures.
+
+Verify prints a report of the checks of each file, in JSON with the
+-json flag. The -v flag adds the decoded AndroidManifest.xml. Verify
+exits with a non-zero status if a check fails.
+`,
+}
+
+var (
+	verifyJSON bool // -json
+	verifyV    bool // -v
+)
+
+func init() {
+	cmdVerify.flag.BoolVar(&verifyJSON, "json", false, "")
+	cmdVerify.flag.BoolVar(&verifyV, "v", false, "")
+}
+
+func runVerify(cmd *command) error {
+	args := cmd.flag.Args()
+	if len(args) == 0 {
+		cmd.usage()
+		os.Exit(1)
+	}
+
+	failed := false
+	var reports []*verifyReport
+	for _, file := range args {
+		kind := "apk"
+		if strings.HasSuffix(file, ".aar") {
+			kind = "aar"
+		}
+		var r *verifyReport
+		data, err := ioutil.ReadFile(file)
+		switch {
+		case err != nil:
+			// Report the file and go on with the others.
+			r = &verifyReport{File: file, Kind: kind, OK: true}
+			r.check("read", err, "")
+		case kind == "aar":
+			r = verifyAAR(file, data)
+		default:
+			r = verifyAPK(file, data)
+		}
+		if !verifyV {
+			r.Manifest = ""
+		}
+		failed = failed || !r.OK
+		reports = append(reports, r)
+	}
+
+	if verifyJSON {
+		b, err := json.MarshalIndent(reports, "", "\t")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n", b)
+	} else {
+		for _, r := range reports {
+			r.print()
+		}
+	}
+	if failed {
+		return errors.New("verification failed")
+	}
+	return nil
+}
+
+// A verifyReport is the result of the checks of an APK or AAR.
+type verifyReport struct {
+	File     string        `json:"file"`
+	Kind     string        `json:"kind"` // apk or aar
+	OK       bool          `json:"ok"`
+	Checks   []verifyCheck `json:"checks"`
+	Manifest string        `json:"manifest,omitempty"` // decoded AndroidManifest.xml, with -v
+}
+
+type verifyCheck struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail"`
+}
+
+func (r *verifyReport) check(name string, err error, format string, args ...interface{}) {
+	c := verifyCheck{Name: name, OK: err == nil, Detail: fmt.Sprintf(format, args...)}
+	if err != nil {
+		c.Detail = err.Error()
+		r.OK = false
+	}
+	r.Checks = append(r.Checks, c)
+}
+
+func (r *verifyReport) print() {
+	status := "ok"
+	if !r.OK {
+		status = "FAIL"
+	}
+	fmt.Printf("%s\t%s (%s)\n", status, r.File, r.Kind)
+	for _, c := range r.Checks {
+		status := "ok"
+		if !c.OK {
+			status = "FAIL"
+		}
+		fmt.Printf("\t%s\t%s: %s\n", status, c.Name, c.Detail)
+	}
+	if r.Manifest != "" {
+		fmt.Printf("\n%s\n", r.Manifest)
+	}
+}
+
+// zipFiles returns the contents of the entries of the ZIP archive data,
+// by name, checking their CRC-32.
+func zipFiles(data []byte) (*zip.Reader, map[string][]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, err
+	}
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		if _, dup := files[f.Name]; dup {
+			return nil, nil, fmt.Errorf("duplicate entry %s", f.Name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", f.Name, err)
+		}
+		b, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", f.Name, err)
+		}
+		files[f.Name] = b
+	}
+	return zr, files, nil
+}
+
+// verifyAPK checks the APK file of contents data.
+func verifyAPK(file string, data []byte) *verifyReport {
+	r := &verifyReport{File: file, Kind: "apk", OK: true}
+	zr, files, err := zipFiles(data)
+	r.check("zip", err, "%d entries", len(files))
+	if err != nil {
+		return r
+	}
+
+	stored := 0
+	var alignErr error
+	for _, f := range zr.File {
+		if f.Method != zip.Store {
+			continue
+		}
+		stored++
+		off, err := f.DataOffset()
+		if err == nil && off%4 != 0 {
+			err = fmt.Errorf("%s: data at offset %d is not 4-byte aligned", f.Name, off)
+		}
+		if alignErr == nil {
+			alignErr = err
+		}
+	}
+	r.check("alignment", alignErr, "%d uncompressed entries are 4-byte aligned", stored)
+
+	v1, v1Err := verifyJAR(files)
+	switch {
+	case v1Err != nil:
+		r.check("v1 signature", v1Err, "")
+	case v1 == nil:
+		r.check("v1 signature", nil, "none")
+	default:
+		r.check("v1 signature", nil, "%s digests of %d entries, signed by %s", v1.hash, v1.entries, v1.cert.Subject)
+	}
+
+	sigs, err := verifySigBlock(data)
+	if err != nil {
+		r.check("APK Signing Block", err, "")
+	} else {
+		for _, scheme := range []int{2, 3} {
+			var signers []string
+			for _, sig := range sigs {
+				if sig.scheme == scheme {
+					signers = append(signers, sig.cert.Subject.String())
+				}
+			}
+			name := fmt.Sprintf("v%d signature", scheme)
+			switch {
+			case len(signers) > 0:
+				r.check(name, nil, "signed by %s", strings.Join(signers, ", "))
+			case v1 != nil && v1.apkSigned[scheme]:
+				r.check(name, errors.New("stripped, the v1 signature says the APK has one"), "")
+			default:
+				r.check(name, nil, "none")
+			}
+		}
+		if v1 == nil && v1Err == nil && len(sigs) == 0 {
+			r.check("signature", errors.New("the APK is not signed"), "")
+		}
+	}
+
+	var res *resTable
+	if b, ok := files["resources.arsc"]; ok {
+		res, err = decodeResTable(b)
+		if err == nil {
+			err = checkResFiles(res, files)
+		}
+		if err != nil {
+			r.check("resources.arsc", err, "")
+			res = nil
+		} else {
+			r.check("resources.arsc", nil, "%s", describeResources(res))
+		}
+	}
+
+	manifest, ok := files["AndroidManifest.xml"]
+	if !ok {
+		r.check("AndroidManifest.xml", errors.New("missing"), "")
+		return r
+	}
+	root, err := decodeBinaryXML(manifest, res)
+	if err != nil {
+		r.check("AndroidManifest.xml", err, "")
+		return r
+	}
+	r.check("AndroidManifest.xml", nil, "%s", describeManifest(root))
+	r.Manifest = string(root.format())
+	return r
+}
+
+// describeManifest returns the package, versions and minimum SDK of the
+// manifest root.
+func describeManifest(root *xmlElement) string {
+	pkg, _ := root.attrValue("", "package")
+	s := "package " + pkg
+	if v, ok := root.attrValue(androidNS, "versionName"); ok {
+		s += ", version " + v
+	}
+	if v, ok := root.attrValue(androidNS, "versionCode"); ok {
+		s += " (" + v + ")"
+	}
+	for _, c := range root.children {
+		if e, ok := c.(*xmlElement); ok && e.name.Local == "uses-sdk" {
+			if v, ok := e.attrValue(androidNS, "minSdkVersion"); ok {
+				s += ", minSdkVersion " + v
+			}
+		}
+	}
+	return s
+}
+
+// checkResFiles checks that the files of the resources of res are among
+// the files of an APK.
+func checkResFiles(res *resTable, files map[string][]byte) error {
+	for _, rt := range res.types {
+		if rt.name == "string" {
+			continue
+		}
+		for _, c := range rt.configs {
+			for e, v := range c.values {
+				if _, ok := files[v]; strings.HasPrefix(v, "res/") && !ok {
+					return fmt.Errorf("%s/%s: missing %s", rt.name, e, v)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// describeResources returns the number and the types of the resources of
+// res.
+func describeResources(res *resTable) string {
+	n := 0
+	var types []string
+	for _, rt := range res.types {
+		if len(rt.entries) > 0 {
+			n += len(rt.entries)
+			types = append(types, rt.name)
+		}
+	}
+	return fmt.Sprintf("%d resources of types %s", n, strings.Join(types, ", "))
+}
+
+// verifyAAR checks the AAR file of contents data.
+func verifyAAR(file string, data []byte) *verifyReport {
+	r := &verifyReport{File: file, Kind: "aar", OK: true}
+	_, files, err := zipFiles(data)
+	r.check("zip", err, "%d entries", len(files))
+	if err != nil {
+		return r
+	}
+
+	manifest, ok := files["AndroidManifest.xml"]
+	if !ok {
+		r.check("AndroidManifest.xml", errors.New("missing"), "")
+	} else {
+		var m struct {
+			Package string `xml:"package,attr"`
+		}
+		err := xml.Unmarshal(manifest, &m)
+		if err == nil && m.Package == "" {
+			err = errors.New("no package")
+		}
+		r.check("AndroidManifest.xml", err, "package %s", m.Package)
+		r.Manifest = string(manifest)
+	}
+
+	if _, ok := files["classes.jar"]; ok {
+		r.check("classes.jar", nil, "present")
+	} else {
+		r.check("classes.jar", errors.New("missing"), "")
+	}
+
+	var abis []string
+	for name := range files {
+		if dir, base := path.Split(name); strings.HasPrefix(dir, "jni/") && strings.HasSuffix(base, ".so") {
+			abis = append(abis, strings.TrimSuffix(strings.TrimPrefix(dir, "jni/"), "/"))
+		}
+	}
+	sort.Strings(abis)
+	if len(abis) > 0 {
+		r.check("jni", nil, "shared libraries for %s", strings.Join(abis, ", "))
+	} else {
+		r.check("jni", errors.New("no shared libraries"), "")
+	}
+	return r
+}
+
+// A jarSignature is a verified JAR signature.
+type jarSignature struct {
+	hash      string // name of the digest of the entries, such as SHA1
+	entries   int
+	cert      *x509.Certificate
+	apkSigned map[int]bool // other schemes, from X-Android-APK-Signed
+}
+
+// jarDigests are the digests of the JAR manifests, strongest first.
+var jarDigests = []struct {
+	name string
+	hash crypto.Hash
+}{
+	{"SHA-512", crypto.SHA512},
+	{"SHA-384", crypto.SHA384},
+	{"SHA-256", crypto.SHA256},
+	{"SHA-1", crypto.SHA1},
+	{"SHA1", crypto.SHA1},
+}
+
+// verifyJAR verifies the JAR signature of the APK entries files. It
+// returns nil if the APK has none.
+func verifyJAR(files map[string][]byte) (*jarSignature, error) {
+	var sfName string
+	for name := range files {
+		if strings.HasPrefix(name, "META-INF/") && strings.HasSuffix(name, ".SF") && !strings.Contains(name[len("META-INF/"):], "/") {
+			if sfName != "" {
+				return nil, fmt.Errorf("several signatures: %s and %s", sfName, name)
+			}
+			sfName = name
+		}
+	}
+	if sfName == "" {
+		if _, ok := files["META-INF/MANIFEST.MF"]; ok {
+			return nil, errors.New("META-INF/MANIFEST.MF without signature")
+		}
+		return nil, nil
+	}
+	base := strings.TrimSuffix(sfName, ".SF")
+	var block []byte
+	for _, ext := range []string{".RSA", ".EC", ".DSA"} {
+		if b, ok := files[base+ext]; ok {
+			block = b
+		}
+	}
+	if block == nil {
+		return nil, fmt.Errorf("no signature block of %s", sfName)
+	}
+	manifest, ok := files["META-INF/MANIFEST.MF"]
+	if !ok {
+		return nil, errors.New("no META-INF/MANIFEST.MF")
+	}
+
+	sfData := files[sfName]
+	cert, _, err := verifyPKCS7(block, sfData)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", sfName, err)
+	}
+	sig := &jarSignature{cert: cert, apkSigned: make(map[int]bool)}
+
+	// The .SF file holds the digest of the manifest, or of each of its
+	// sections.
+	mf := parseJARManifest(manifest)
+	sf := parseJARManifest(sfData)
+	if len(mf) == 0 || len(sf) == 0 {
+		return nil, errors.New("empty manifest")
+	}
+	for _, s := range strings.Split(sf[0].attrs["X-Android-APK-Signed"], ",") {
+		switch strings.TrimSpace(s) {
+		case "2":
+			sig.apkSigned[2] = true
+		case "3":
+			sig.apkSigned[3] = true
+		}
+	}
+	// sfNames are the names of the manifest sections with a digest in
+	// the .SF file, all of them if it holds the digest of the manifest.
+	var sfNames map[string]bool
+	if !verifyJARDigest(sf[0].attrs, "-Digest-Manifest", manifest) {
+		sfNames = make(map[string]bool)
+		sections := make(map[string][]byte)
+		for _, s := range mf[1:] {
+			sections[s.attrs["Name"]] = s.raw
+		}
+		for _, s := range sf[1:] {
+			name := s.attrs["Name"]
+			raw, ok := sections[name]
+			if !ok || !verifyJARDigest(s.attrs, "-Digest", raw) {
+				return nil, fmt.Errorf("%s: digest of the manifest section of %s does not match", sfName, name)
+			}
+			sfNames[name] = true
+		}
+	}
+
+	// The manifest holds the digests of the entries, all of them but
+	// the signature files.
+	signed := make(map[string]bool)
+	for _, s := range mf[1:] {
+		name := s.attrs["Name"]
+		if sfNames != nil && !sfNames[name] {
+			continue
+		}
+		data, ok := files[name]
+		if !ok {
+			return nil, fmt.Errorf("META-INF/MANIFEST.MF: no entry %s", name)
+		}
+		hash := verifyJARDigestName(s.attrs, "-Digest", data)
+		if hash == "" {
+			return nil, fmt.Errorf("%s: digest does not match META-INF/MANIFEST.MF, the entry was modified", name)
+		}
+		sig.hash = hash
+		signed[name] = true
+	}
+	for name := range files {
+		if !signed[name] && !strings.HasSuffix(name, "/") && !isJARSignatureFile(name) {
+			return nil, fmt.Errorf("%s: entry is not signed", name)
+		}
+	}
+	sig.entries = len(signed)
+	return sig, nil
+}
+
+// verifyJARDigest reports whether the digest in attrs of the attribute
+// named after a digest followed by suffix is the digest of data.
+func verifyJARDigest(attrs map[string]string, suffix string, data []byte) bool {
+	return verifyJARDigestName(attrs, suffix, data) != ""
+}
+
+// verifyJARDigestName is like verifyJARDigest, but returns the name of
+// the matching digest. Of several digests, it checks the strongest.
+func verifyJARDigestName(attrs map[string]string, suffix string, data []byte) string {
+	for _, d := range jarDigests {
+		v, ok := attrs[d.name+suffix]
+		if !ok {
+			continue
+		}
+		h := d.hash.New()
+		h.Write(data)
+		if v != base64.StdEncoding.EncodeToString(h.Sum(nil)) {
+			return ""
+		}
+		return d.name
+	}
+	return ""
+}
+
+// isJARSignatureFile reports whether the entry name is a file of the
+// JAR signature, which the manifest does not list.
+func isJARSignatureFile(name string) bool {
+	if !strings.HasPrefix(name, "META-INF/") {
+		return false
+	}
+	name = name[len("META-INF/"):]
+	if strings.Contains(name, "/") {
+		return false
+	}
+	switch path.Ext(name) {
+	case ".MF", ".SF", ".RSA", ".DSA", ".EC":
+		return true
+	}
+	return strings.HasPrefix(name, "SIG-")
+}
+
+// A jarSection is a section of a JAR manifest.
+type jarSection struct {
+	raw   []byte // with its line breaks and final empty line
+	attrs map[string]string
+}
+
+// parseJARManifest returns the sections of the JAR manifest b. Their
+// lines end with \n or \r\n, and long values continue on lines starting
+// with a space.
+func parseJARManifest(b []byte) []jarSection {
+	var sections []jarSection
+	s := jarSection{attrs: make(map[string]string)}
+	last := ""
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, '\n')
+		if i < 0 {
+			i = len(b) - 1
+		}
+		line := strings.TrimRight(string(b[:i+1]), "\r\n")
+		s.raw = append(s.raw, b[:i+1]...)
+		b = b[i+1:]
+		switch {
+		case line == "":
+			if len(s.attrs) > 0 {
+				sections = append(sections, s)
+			}
+			s = jarSection{attrs: make(map[string]string)}
+		case line[0] == ' ' && last != "":
+			s.attrs[last] += line[1:]
+		default:
+			if i := strings.Index(line, ": "); i > 0 {
+				last = line[:i]
+				s.attrs[last] = line[i+2:]
+			}
+		}
+	}
+	if len(s.attrs) > 0 {
+		sections = append(sections, s)
+	}
+	return sections
+}
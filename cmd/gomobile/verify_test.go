@@ -0,0 +1,327 @@
+// Copyright 2015 The Go Authors.  All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// testAPK returns an APK signed with the debug key by the schemes.
+func testAPK(t *testing.T, schemes string) []byte {
+	block, _ := pem.Decode([]byte(debugCert))
+	if block == nil {
+		t.Fatal("no cert")
+	}
+	privKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	apkw := NewWriter(buf, privKey, nil)
+	if apkw.sign, err = parseSigning(schemes); err != nil {
+		t.Fatal(err)
+	}
+	files := []struct{ name, data string }{
+		{"AndroidManifest.xml", androidManifest},
+		{"assets/hello_world.txt", "Hello, 世界"},
+		{"lib/x86/libbasic.so", "\x7fELF"},
+	}
+	for _, f := range files {
+		w, err := apkw.Create(f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(f.data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := apkw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// rezip copies the entries of the ZIP archive b with a plain ZIP writer,
+// replacing the contents of the entries of edits. The other entries of
+// edits are added at the end.
+func rezip(t *testing.T, b []byte, edits map[string]string) []byte {
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	zw := zip.NewWriter(buf)
+	seen := make(map[string]bool)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rc.Close()
+		if s, ok := edits[f.Name]; ok {
+			data = []byte(s)
+		}
+		seen[f.Name] = true
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.Name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+	}
+	for name, s := range edits {
+		if seen[name] {
+			continue
+		}
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(s))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// checkReport checks that the checks of r have the results of want,
+// giving the start of their detail or failure.
+func checkReport(t *testing.T, name string, r *verifyReport, want map[string]string) {
+	got := make(map[string]string)
+	for _, c := range r.Checks {
+		status := "ok: "
+		if !c.OK {
+			status = "FAIL: "
+		}
+		got[c.Name] = status + c.Detail
+	}
+	wantOK := true
+	for check, w := range want {
+		if !strings.HasPrefix(got[check], w) {
+			t.Errorf("%s: %s: got %q, want %q...", name, check, got[check], w)
+		}
+		wantOK = wantOK && strings.HasPrefix(w, "ok")
+	}
+	if r.OK != wantOK {
+		t.Errorf("%s: report ok %v, want %v", name, r.OK, wantOK)
+	}
+}
+
+func TestVerifyAPK(t *testing.T) {
+	apk := testAPK(t, "v1,v2")
+	checkReport(t, "v1,v2", verifyAPK("test.apk", apk), map[string]string{
+		"zip":                 "ok: 6 entries",
+		"alignment":           "ok: 6 uncompressed entries are 4-byte aligned",
+		"v1 signature":        "ok: SHA1 digests of 3 entries, signed by CN=gomobile",
+		"v2 signature":        "ok: signed by CN=gomobile",
+		"v3 signature":        "ok: none",
+		"AndroidManifest.xml": "ok: package org.golang.fakeapp, version 1.0 (1), minSdkVersion 9",
+	})
+
+	checkReport(t, "v1-sha256,v3", verifyAPK("test.apk", testAPK(t, "v1-sha256,v3")), map[string]string{
+		"v1 signature": "ok: SHA-256 digests of 3 entries",
+		"v2 signature": "ok: none",
+		"v3 signature": "ok: signed by CN=gomobile",
+	})
+
+	checkReport(t, "v2", verifyAPK("test.apk", testAPK(t, "v2")), map[string]string{
+		"zip":          "ok: 3 entries",
+		"v1 signature": "ok: none",
+		"v2 signature": "ok: signed by CN=gomobile",
+	})
+
+	// A plain ZIP writer drops the APK Signing Block and the alignment.
+	checkReport(t, "rezipped", verifyAPK("test.apk", rezip(t, apk, nil)), map[string]string{
+		"alignment":    "FAIL: AndroidManifest.xml: data at offset 49 is not 4-byte aligned",
+		"v1 signature": "ok: SHA1 digests of 3 entries",
+		"v2 signature": "FAIL: stripped",
+	})
+
+	modified := rezip(t, apk, map[string]string{"assets/hello_world.txt": "Hello, world"})
+	checkReport(t, "modified", verifyAPK("test.apk", modified), map[string]string{
+		"v1 signature": "FAIL: assets/hello_world.txt: digest does not match",
+	})
+
+	// Without the digest of the whole manifest, the .SF file only signs
+	// the manifest sections it holds digests of. An entry added with its
+	// own manifest section is not signed.
+	v1 := testAPK(t, "v1")
+	_, files, err := zipFiles(v1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := string(files["META-INF/MANIFEST.MF"])
+	sf := "Signature-Version: 1.0\n\n"
+	for _, s := range parseJARManifest(files["META-INF/MANIFEST.MF"])[1:] {
+		h := sha1.Sum(s.raw)
+		sf += "Name: " + s.attrs["Name"] + "\nSHA1-Digest: " + base64.StdEncoding.EncodeToString(h[:]) + "\n\n"
+	}
+	cert, _, err := verifyPKCS7(files["META-INF/CERT.RSA"], files["META-INF/CERT.SF"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode([]byte(debugCert))
+	privKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := signPKCS7(rand.Reader, privKey, cert, crypto.SHA1, []byte(sf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sections := map[string]string{"META-INF/CERT.SF": sf, "META-INF/CERT.RSA": string(sig)}
+	checkReport(t, "sections", verifyAPK("test.apk", rezip(t, v1, sections)), map[string]string{
+		"alignment":    "FAIL",
+		"v1 signature": "ok: SHA1 digests of 3 entries",
+	})
+	evil := "evil"
+	h := sha1.Sum([]byte(evil))
+	sections["META-INF/MANIFEST.MF"] = manifest + "Name: assets/evil.txt\nSHA1-Digest: " + base64.StdEncoding.EncodeToString(h[:]) + "\n\n"
+	sections["assets/evil.txt"] = evil
+	checkReport(t, "added", verifyAPK("test.apk", rezip(t, v1, sections)), map[string]string{
+		"v1 signature": "FAIL: assets/evil.txt: entry is not signed",
+	})
+
+	unsigned := rezip(t, apk, map[string]string{"META-INF/CERT.RSA": ""})
+	checkReport(t, "unsigned", verifyAPK("test.apk", unsigned), map[string]string{
+		"v1 signature": "FAIL: META-INF/CERT.SF: bad PKCS#7 signature",
+	})
+
+	// Damage the APK Signing Block.
+	damaged := append([]byte(nil), apk...)
+	damaged[bytes.Index(damaged, []byte("APK Sig Block 42"))-100] ^= 1
+	checkReport(t, "damaged", verifyAPK("test.apk", damaged), map[string]string{
+		"APK Signing Block": "FAIL: v2 signature: bad signature",
+	})
+}
+
+func TestVerifyAAR(t *testing.T) {
+	aar := func(files ...string) []byte {
+		buf := new(bytes.Buffer)
+		zw := zip.NewWriter(buf)
+		for _, name := range files {
+			w, err := zw.Create(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if name == "AndroidManifest.xml" {
+				w.Write([]byte(`<manifest xmlns:android="http://schemas.android.com/apk/res/android" package="go.hello" />`))
+			}
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	r := verifyAAR("hello.aar", aar("AndroidManifest.xml", "classes.jar", "jni/x86/libgojni.so", "jni/armeabi-v7a/libgojni.so"))
+	checkReport(t, "hello.aar", r, map[string]string{
+		"zip":                 "ok: 4 entries",
+		"AndroidManifest.xml": "ok: package go.hello",
+		"classes.jar":         "ok: present",
+		"jni":                 "ok: shared libraries for armeabi-v7a, x86",
+	})
+
+	r = verifyAAR("broken.aar", aar("AndroidManifest.xml"))
+	checkReport(t, "broken.aar", r, map[string]string{
+		"classes.jar": "FAIL: missing",
+		"jni":         "FAIL: no shared libraries",
+	})
+}
+
+func TestVerifyJARDigestName(t *testing.T) {
+	data := []byte("Hello, 世界")
+	sha1Sum := sha1.Sum(data)
+	sha256Sum := sha256.Sum256(data)
+	good1 := base64.StdEncoding.EncodeToString(sha1Sum[:])
+	good256 := base64.StdEncoding.EncodeToString(sha256Sum[:])
+	bad := base64.StdEncoding.EncodeToString(make([]byte, 20))
+	tests := []struct {
+		attrs map[string]string
+		want  string
+	}{
+		{map[string]string{"SHA1-Digest": good1}, "SHA1"},
+		{map[string]string{"SHA1-Digest": bad}, ""},
+		{map[string]string{"SHA1-Digest": bad, "SHA-256-Digest": good256}, "SHA-256"},
+		{map[string]string{"SHA1-Digest": good1, "SHA-256-Digest": bad}, ""},
+		{map[string]string{"MD5-Digest": bad}, ""},
+	}
+	for _, tc := range tests {
+		// The digests are checked in the same order every time.
+		for i := 0; i < 10; i++ {
+			if got := verifyJARDigestName(tc.attrs, "-Digest", data); got != tc.want {
+				t.Errorf("%v: got %q, want %q", tc.attrs, got, tc.want)
+				break
+			}
+		}
+	}
+}
+
+func TestVerifyPKCS7(t *testing.T) {
+	msg := []byte("Signature-Version: 1.0\n")
+	for _, hash := range []crypto.Hash{crypto.SHA1, crypto.SHA256} {
+		block, _ := pem.Decode([]byte(debugCert))
+		privKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, err := signPKCS7(rand.Reader, privKey, nil, hash, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, got, err := verifyPKCS7(sig, msg)
+		if err != nil {
+			t.Errorf("%v: %v", hash, err)
+			continue
+		}
+		if got != hash || cert.Subject.CommonName != "gomobile" {
+			t.Errorf("%v: got %v digest of %s", hash, got, cert.Subject)
+		}
+		if _, _, err := verifyPKCS7(sig, []byte("Signature-Version: 2.0\n")); err == nil {
+			t.Errorf("%v: verified another message", hash)
+		}
+	}
+
+	// openssl.p7s was made by
+	//
+	//	openssl cms -sign -binary -in openssl.sf -signer release.pem \
+	//		-inkey release.pem -outform DER -md sha256 -out openssl.p7s
+	//
+	// and has signed attributes.
+	sig, err := ioutil.ReadFile("testdata/openssl.p7s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err = ioutil.ReadFile("testdata/openssl.sf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, hash, err := verifyPKCS7(sig, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != crypto.SHA256 || cert.Subject.CommonName != "Example Release" {
+		t.Errorf("got %v digest of %s", hash, cert.Subject)
+	}
+	msg[0] = 's'
+	if _, _, err := verifyPKCS7(sig, msg); err == nil {
+		t.Error("verified another message")
+	}
+}
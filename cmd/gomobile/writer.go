@@ -44,32 +44,36 @@ package main
 //
 //	openssl smime -verify -in CERT.RSA -inform DER -content CERT.SF cert.pem
 //
+// Android 4.3 and later also accept SHA-256 digests, named SHA-256-Digest
+// and SHA-256-Digest-Manifest, with a CERT.RSA made of a SHA-256 digest.
+//
+// This JAR signature (v1) is checked by all the Android versions. Android 7
+// and later check the APK Signature Scheme v2 and v3 instead, stored in an
+// APK Signing Block before the central directory, see apksig.go. CERT.SF
+// then names them with an X-Android-APK-Signed header, so that they are
+// not stripped to downgrade the APK to its v1 signature.
+//
 // The APK format imposes two extra restrictions on the ZIP format. First,
 // it is uncompressed. Second, each contained file is 4-byte aligned. This
 // allows the Android OS to mmap contents without unpacking the archive.
 
 // Note: to make life a little harder, Android Studio stores the RSA key used
 // for signing in an Oracle Java proprietary keystore format, JKS. For example,
-// the generated debug key is in ~/.android/debug.keystore, and can be
-// extracted using the JDK's keytool utility:
-//
-//	keytool -importkeystore -srckeystore ~/.android/debug.keystore -destkeystore ~/.android/debug.p12 -deststoretype PKCS12
-//
-// Once in standard PKCS12, the key can be converted to PEM for use in the
-// Go crypto packages:
-//
-//	openssl pkcs12 -in ~/.android/debug.p12 -nocerts -nodes -out ~/.android/debug.pem
+// the generated debug key is in ~/.android/debug.keystore. The build command
+// reads the JKS, PKCS#12 and PEM formats directly, see readKeystore, so the
+// key need not be converted with keytool and openssl.
 //
 // Fortunately for debug builds, all that matters is that the APK is signed.
-// The choice of key is unimportant, so we can generate one for normal builds.
-// For production builds, we can ask users to provide a PEM file.
+// The choice of key is unimportant, so we use a fixed key for normal builds.
+// For production builds, users provide their keystore with -keystore.
 
 import (
 	"archive/zip"
 	"bytes"
+	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
-	"crypto/sha1"
+	"crypto/x509"
 	"encoding/base64"
 	"fmt"
 	"hash"
@@ -77,10 +81,14 @@ import (
 )
 
 // NewWriter returns a new Writer writing an APK file to w.
-// The APK will be signed with key.
-func NewWriter(w io.Writer, priv *rsa.PrivateKey) *Writer {
-	apkw := &Writer{priv: priv}
-	apkw.w = zip.NewWriter(&countWriter{apkw: apkw, w: w})
+// The APK will be signed with priv. The signature carries cert, the
+// certificate of priv, or a self-signed certificate if cert is nil.
+// It is signed with the v1 and v2 schemes, unless sign is set before
+// the first call to Create.
+func NewWriter(w io.Writer, priv *rsa.PrivateKey, cert *x509.Certificate) *Writer {
+	apkw := &Writer{priv: priv, cert: cert, sign: defaultSigning}
+	apkw.cw = &countWriter{apkw: apkw, w: w, digests: &chunkDigester{hash: crypto.SHA256}}
+	apkw.w = zip.NewWriter(apkw.cw)
 	return apkw
 }
 
@@ -89,8 +97,12 @@ type Writer struct {
 	offset   int
 	w        *zip.Writer
 	priv     *rsa.PrivateKey
+	cert     *x509.Certificate
+	sign     apkSigning
+	res      *resTable // resources referred to by AndroidManifest.xml
 	manifest []manifestEntry
 	cur      *fileWriter
+	cw       *countWriter
 }
 
 // Create adds a file to the APK archive using the provided name.
@@ -103,9 +115,9 @@ func (w *Writer) Create(name string) (io.Writer, error) {
 	}
 	if name == "AndroidManifest.xml" {
 		w.cur = &fileWriter{
-			name: name,
-			w:    new(bytes.Buffer),
-			sha1: sha1.New(),
+			name:   name,
+			w:      new(bytes.Buffer),
+			digest: w.v1Hash().New(),
 		}
 		return w.cur, nil
 	}
@@ -123,7 +135,7 @@ func (w *Writer) create(name string) (io.Writer, error) {
 	}
 	const fileHeaderLen = 30 // + filename + extra
 	start := w.offset + fileHeaderLen + len(name)
-	extra := start % 4
+	extra := (4 - start%4) % 4 // bytes up to the next multiple of 4
 
 	zipfw, err := w.w.CreateHeader(&zip.FileHeader{
 		Name:  name,
@@ -133,9 +145,9 @@ func (w *Writer) create(name string) (io.Writer, error) {
 		return nil, err
 	}
 	w.cur = &fileWriter{
-		name: name,
-		w:    zipfw,
-		sha1: sha1.New(),
+		name:   name,
+		w:      zipfw,
+		digest: w.v1Hash().New(),
 	}
 	return w.cur, nil
 }
@@ -148,6 +160,67 @@ func (w *Writer) Close() error {
 	if err := w.clearCur(); err != nil {
 		return fmt.Errorf("apk: %v", err)
 	}
+	if !w.sign.v1 && !w.sign.v2 && !w.sign.v3 {
+		return fmt.Errorf("apk: no signature scheme")
+	}
+	cert := w.cert
+	if cert == nil {
+		var err error
+		if cert, err = selfSignedCert(rand.Reader, w.priv); err != nil {
+			return fmt.Errorf("apk: %v", err)
+		}
+	}
+	if w.sign.v1 {
+		if err := w.signV1(cert); err != nil {
+			return err
+		}
+	}
+	if !w.sign.v2 && !w.sign.v3 {
+		return w.w.Close()
+	}
+
+	// Write the end of the archive aside, to insert the APK Signing
+	// Block before its central directory.
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+	out, digests, start := w.cw.w, w.cw.digests, w.offset
+	tail := new(bytes.Buffer)
+	w.cw.w, w.cw.digests = tail, nil
+	if err := w.w.Close(); err != nil {
+		return err
+	}
+	entries, cd, eocd, err := splitZIPTail(tail.Bytes(), start)
+	if err != nil {
+		return fmt.Errorf("apk: %v", err)
+	}
+
+	// The end of central directory is digested as written, its offset
+	// of the central directory being the offset of the block.
+	digests.Write(entries)
+	digests.endSection()
+	digests.Write(cd)
+	digests.endSection()
+	digests.Write(eocd)
+	block, err := apkSigBlock(w.sign, w.priv, cert, digests.sum())
+	if err != nil {
+		return fmt.Errorf("apk: %v", err)
+	}
+	moveCD(eocd, len(block))
+	for _, b := range [][]byte{entries, block, cd, eocd} {
+		if _, err := out.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// signV1 writes the JAR signature of the entries, carrying cert.
+func (w *Writer) signV1(cert *x509.Certificate) error {
+	hashName := "SHA1"
+	if w.sign.v1SHA256 {
+		hashName = "SHA-256"
+	}
 
 	hasDex := false
 	for _, entry := range w.manifest {
@@ -167,20 +240,23 @@ func (w *Writer) Close() error {
 
 	for _, entry := range w.manifest {
 		n := entry.name
-		h := base64.StdEncoding.EncodeToString(entry.sha1.Sum(nil))
-		fmt.Fprintf(manifest, "Name: %s\nSHA1-Digest: %s\n\n", n, h)
-		cHash := sha1.New()
-		fmt.Fprintf(cHash, "Name: %s\r\nSHA1-Digest: %s\r\n\r\n", n, h)
+		h := base64.StdEncoding.EncodeToString(entry.digest.Sum(nil))
+		fmt.Fprintf(manifest, "Name: %s\n%s-Digest: %s\n\n", n, hashName, h)
+		cHash := w.v1Hash().New()
+		fmt.Fprintf(cHash, "Name: %s\r\n%s-Digest: %s\r\n\r\n", n, hashName, h)
 		ch := base64.StdEncoding.EncodeToString(cHash.Sum(nil))
-		fmt.Fprintf(certBody, "Name: %s\nSHA1-Digest: %s\n\n", n, ch)
+		fmt.Fprintf(certBody, "Name: %s\n%s-Digest: %s\n\n", n, hashName, ch)
 	}
 
-	mHash := sha1.New()
+	mHash := w.v1Hash().New()
 	mHash.Write(manifest.Bytes())
-	cert := new(bytes.Buffer)
-	fmt.Fprint(cert, certHeader)
-	fmt.Fprintf(cert, "SHA1-Digest-Manifest: %s\n\n", base64.StdEncoding.EncodeToString(mHash.Sum(nil)))
-	cert.Write(certBody.Bytes())
+	sf := new(bytes.Buffer)
+	fmt.Fprint(sf, certHeader)
+	if signed := w.sign.String(); signed != "" {
+		fmt.Fprintf(sf, "X-Android-APK-Signed: %s\n", signed)
+	}
+	fmt.Fprintf(sf, "%s-Digest-Manifest: %s\n\n", hashName, base64.StdEncoding.EncodeToString(mHash.Sum(nil)))
+	sf.Write(certBody.Bytes())
 
 	mw, err := w.Create("META-INF/MANIFEST.MF")
 	if err != nil {
@@ -194,11 +270,11 @@ func (w *Writer) Close() error {
 	if err != nil {
 		return err
 	}
-	if _, err := cw.Write(cert.Bytes()); err != nil {
+	if _, err := cw.Write(sf.Bytes()); err != nil {
 		return err
 	}
 
-	rsa, err := signPKCS7(rand.Reader, w.priv, cert.Bytes())
+	rsa, err := signPKCS7(rand.Reader, w.priv, cert, w.v1Hash(), sf.Bytes())
 	if err != nil {
 		return fmt.Errorf("apk: %v", err)
 	}
@@ -206,11 +282,16 @@ func (w *Writer) Close() error {
 	if err != nil {
 		return err
 	}
-	if _, err := rw.Write(rsa); err != nil {
-		return err
-	}
+	_, err = rw.Write(rsa)
+	return err
+}
 
-	return w.w.Close()
+// v1Hash returns the digest of the JAR signature.
+func (w *Writer) v1Hash() crypto.Hash {
+	if w.sign.v1SHA256 {
+		return crypto.SHA256
+	}
+	return crypto.SHA1
 }
 
 const manifestHeader = `Manifest-Version: 1.0
@@ -234,7 +315,7 @@ func (w *Writer) clearCur() error {
 	}
 	if w.cur.name == "AndroidManifest.xml" {
 		buf := w.cur.w.(*bytes.Buffer)
-		b, err := binaryXML(buf)
+		b, err := binaryXML(buf, w.res)
 		if err != nil {
 			return err
 		}
@@ -247,8 +328,8 @@ func (w *Writer) clearCur() error {
 		}
 	}
 	w.manifest = append(w.manifest, manifestEntry{
-		name: w.cur.name,
-		sha1: w.cur.sha1,
+		name:   w.cur.name,
+		digest: w.cur.digest,
 	})
 	w.cur.closed = true
 	w.cur = nil
@@ -256,25 +337,29 @@ func (w *Writer) clearCur() error {
 }
 
 type manifestEntry struct {
-	name string
-	sha1 hash.Hash
+	name   string
+	digest hash.Hash
 }
 
 type countWriter struct {
-	apkw *Writer
-	w    io.Writer
+	apkw    *Writer
+	w       io.Writer
+	digests *chunkDigester // of the entries, for the v2 and v3 schemes
 }
 
 func (c *countWriter) Write(p []byte) (n int, err error) {
 	n, err = c.w.Write(p)
 	c.apkw.offset += n
+	if c.digests != nil {
+		c.digests.Write(p[:n])
+	}
 	return n, err
 }
 
 type fileWriter struct {
 	name   string
 	w      io.Writer
-	sha1   hash.Hash
+	digest hash.Hash
 	closed bool
 }
 
@@ -282,7 +367,7 @@ func (w *fileWriter) Write(p []byte) (n int, err error) {
 	if w.closed {
 		return 0, fmt.Errorf("apk: write to closed file %q", w.name)
 	}
-	w.sha1.Write(p)
+	w.digest.Write(p)
 	n, err = w.w.Write(p)
 	if err != nil {
 		err = fmt.Errorf("apk: %v", err)
@@ -5,8 +5,15 @@
 package main
 
 import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/binary"
 	"encoding/pem"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
@@ -38,7 +45,7 @@ func TestWriter(t *testing.T) {
 	}
 	defer os.Remove(apkPath)
 
-	apkw := NewWriter(f, privKey)
+	apkw := NewWriter(f, privKey, nil)
 
 	w, err := apkw.Create("AndroidManifest.xml")
 	if err != nil {
@@ -82,6 +89,260 @@ func TestWriter(t *testing.T) {
 	}
 }
 
+func TestWriterAlignment(t *testing.T) {
+	block, _ := pem.Decode([]byte(debugCert))
+	if block == nil {
+		t.Fatal("no cert")
+	}
+	privKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := new(bytes.Buffer)
+	apkw := NewWriter(buf, privKey, nil)
+	// Names of all lengths modulo 4, after contents of all lengths.
+	for i, name := range []string{"a", "ab", "abc", "abcd", "lib/x86/libbasic.so"} {
+		w, err := apkw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(bytes.Repeat([]byte{'x'}, i))
+	}
+	if err := apkw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	apk := buf.Bytes()
+	r, err := zip.NewReader(bytes.NewReader(apk), int64(len(apk)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range r.File {
+		off, err := f.DataOffset()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if off%4 != 0 {
+			t.Errorf("%s: contents at offset %d, not aligned on 4 bytes", f.Name, off)
+		}
+	}
+}
+
+var signSchemesTests = []struct {
+	schemes string
+	sfWant  []string // lines of CERT.SF
+}{
+	{"v1", []string{"SHA1-Digest-Manifest: "}},
+	{"v1,v2", []string{"X-Android-APK-Signed: 2", "SHA1-Digest-Manifest: "}},
+	{"v1-sha256,v2,v3", []string{"X-Android-APK-Signed: 2, 3", "SHA-256-Digest-Manifest: "}},
+	{"v2", nil},
+	{"v3", nil},
+}
+
+func TestWriterSignSchemes(t *testing.T) {
+	block, _ := pem.Decode([]byte(debugCert))
+	if block == nil {
+		t.Fatal("no cert")
+	}
+	privKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Spans several chunks of the v2 digest.
+	lib := bytes.Repeat([]byte("libbasic"), 300000)
+
+	for _, tc := range signSchemesTests {
+		sign, err := parseSigning(tc.schemes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := new(bytes.Buffer)
+		apkw := NewWriter(buf, privKey, nil)
+		apkw.sign = sign
+		w, err := apkw.Create("lib/x86/libbasic.so")
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(lib)
+		if err := apkw.Close(); err != nil {
+			t.Errorf("%s: %v", tc.schemes, err)
+			continue
+		}
+		apk := buf.Bytes()
+
+		// The APK Signing Block does not disturb the ZIP readers.
+		r, err := zip.NewReader(bytes.NewReader(apk), int64(len(apk)))
+		if err != nil {
+			t.Errorf("%s: %v", tc.schemes, err)
+			continue
+		}
+		files := make(map[string][]byte)
+		for _, f := range r.File {
+			rc, err := f.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			files[f.Name], err = ioutil.ReadAll(rc)
+			if err != nil {
+				t.Errorf("%s: %s: %v", tc.schemes, f.Name, err)
+			}
+			rc.Close()
+		}
+		if !bytes.Equal(files["lib/x86/libbasic.so"], lib) {
+			t.Errorf("%s: lib/x86/libbasic.so was corrupted", tc.schemes)
+		}
+		sf, ok := files["META-INF/CERT.SF"]
+		if ok != sign.v1 {
+			t.Errorf("%s: has CERT.SF %v, want %v", tc.schemes, ok, sign.v1)
+		}
+		for _, line := range tc.sfWant {
+			if !bytes.Contains(sf, []byte("\n"+line)) {
+				t.Errorf("%s: CERT.SF has no line %q:\n%s", tc.schemes, line, sf)
+			}
+		}
+		if sign.v1 && bytes.Contains(sf, []byte("X-Android-APK-Signed")) != (sign.v2 || sign.v3) {
+			t.Errorf("%s: CERT.SF:\n%s", tc.schemes, sf)
+		}
+
+		pairs, err := checkSigBlock(apk, &privKey.PublicKey)
+		if err != nil {
+			t.Errorf("%s: %v", tc.schemes, err)
+			continue
+		}
+		var ids []uint32
+		if sign.v2 {
+			ids = append(ids, apkSigV2ID)
+		}
+		if sign.v3 {
+			ids = append(ids, apkSigV3ID)
+		}
+		for _, id := range ids {
+			if _, ok := pairs[id]; !ok {
+				t.Errorf("%s: no signature %#x in the signing block", tc.schemes, id)
+			}
+		}
+		if len(pairs) != len(ids) {
+			t.Errorf("%s: %d signatures in the signing block, want %d", tc.schemes, len(pairs), len(ids))
+		}
+	}
+}
+
+// checkSigBlock verifies the signatures of the APK Signing Block of apk,
+// made by pub, and returns its ID-value pairs.
+func checkSigBlock(apk []byte, pub *rsa.PublicKey) (map[uint32][]byte, error) {
+	pairs := make(map[uint32][]byte)
+	eocd := apk[len(apk)-22:]
+	cdOff := int(binary.LittleEndian.Uint32(eocd[16:]))
+	if cdOff < 32 || string(apk[cdOff-16:cdOff]) != "APK Sig Block 42" {
+		return pairs, nil // no block
+	}
+	size := int(binary.LittleEndian.Uint64(apk[cdOff-24:]))
+	start := cdOff - size - 8
+	if start < 0 || binary.LittleEndian.Uint64(apk[start:]) != uint64(size) {
+		return nil, fmt.Errorf("bad signing block size %d", size)
+	}
+
+	// The digest of the APK without its block, whose end of central
+	// directory points at the block.
+	eocd = append([]byte(nil), eocd...)
+	binary.LittleEndian.PutUint32(eocd[16:], uint32(start))
+	var chunks []byte
+	for _, section := range [][]byte{apk[:start], apk[cdOff : len(apk)-22], eocd} {
+		for len(section) > 0 {
+			n := len(section)
+			if n > 1<<20 {
+				n = 1 << 20
+			}
+			h := sha256.New()
+			h.Write([]byte{0xa5, byte(n), byte(n >> 8), byte(n >> 16), byte(n >> 24)})
+			h.Write(section[:n])
+			chunks = h.Sum(chunks)
+			section = section[n:]
+		}
+	}
+	h := sha256.New()
+	n := len(chunks) / sha256.Size
+	h.Write([]byte{0x5a, byte(n), byte(n >> 8), byte(n >> 16), byte(n >> 24)})
+	h.Write(chunks)
+	digest := h.Sum(nil)
+
+	b := apk[start+8 : cdOff-24]
+	for len(b) > 0 {
+		n := int(binary.LittleEndian.Uint64(b))
+		id := binary.LittleEndian.Uint32(b[8:])
+		pairs[id] = b[12 : 8+n]
+		b = b[8+n:]
+	}
+	for id, value := range pairs {
+		signers := lpValues(lpValue(&value))
+		if len(signers) != 1 {
+			return nil, fmt.Errorf("%#x: %d signers", id, len(signers))
+		}
+		signer := signers[0]
+		signedData := lpValue(&signer)
+		signed := signedData
+		digests := lpValues(lpValue(&signed))
+		certs := lpValues(lpValue(&signed))
+		if id == apkSigV3ID {
+			signer = signer[8:] // SDK versions
+		}
+		sigs := lpValues(lpValue(&signer))
+		pubDER := lpValue(&signer)
+
+		if len(digests) != 1 || binary.LittleEndian.Uint32(digests[0]) != rsaPKCS1SHA256 {
+			return nil, fmt.Errorf("%#x: bad digests", id)
+		}
+		d := digests[0][4:]
+		if got := lpValue(&d); !bytes.Equal(got, digest) {
+			return nil, fmt.Errorf("%#x: digest %x, want %x", id, got, digest)
+		}
+		if len(certs) != 1 {
+			return nil, fmt.Errorf("%#x: %d certificates", id, len(certs))
+		}
+		cert, err := x509.ParseCertificate(certs[0])
+		if err != nil {
+			return nil, fmt.Errorf("%#x: %v", id, err)
+		}
+		if k, ok := cert.PublicKey.(*rsa.PublicKey); !ok || k.N.Cmp(pub.N) != 0 {
+			return nil, fmt.Errorf("%#x: certificate of another key", id)
+		}
+		k, err := x509.ParsePKIXPublicKey(pubDER)
+		if err != nil {
+			return nil, fmt.Errorf("%#x: %v", id, err)
+		}
+		if k, ok := k.(*rsa.PublicKey); !ok || k.N.Cmp(pub.N) != 0 {
+			return nil, fmt.Errorf("%#x: public key of another key", id)
+		}
+		if len(sigs) != 1 || binary.LittleEndian.Uint32(sigs[0]) != rsaPKCS1SHA256 {
+			return nil, fmt.Errorf("%#x: bad signatures", id)
+		}
+		sig := sigs[0][4:]
+		hashed := sha256.Sum256(signedData)
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, hashed[:], lpValue(&sig)); err != nil {
+			return nil, fmt.Errorf("%#x: %v", id, err)
+		}
+	}
+	return pairs, nil
+}
+
+// lpValue returns the next length-prefixed value of b.
+func lpValue(b *[]byte) []byte {
+	n := binary.LittleEndian.Uint32(*b)
+	v := (*b)[4 : 4+n]
+	*b = (*b)[4+n:]
+	return v
+}
+
+// lpValues returns the length-prefixed values of b.
+func lpValues(b []byte) [][]byte {
+	var values [][]byte
+	for len(b) > 0 {
+		values = append(values, lpValue(&b))
+	}
+	return values
+}
+
 const aaptWant = `AndroidManifest.xml
 assets/hello_world.txt
 META-INF/MANIFEST.MF